@@ -0,0 +1,141 @@
+package analysis
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+	"unicode"
+
+	"main/transformer"
+)
+
+const (
+	DefaultCandidates    = 5
+	DefaultPreviewLength = 60
+)
+
+// CrackOptions select the languages to try, all known ones if empty, and
+// how much of the result to return.
+type CrackOptions struct {
+	Languages     []string
+	Candidates    int
+	PreviewLength int
+}
+
+// Candidate is a possible key of the ciphertext with the start of the text
+// it decrypts to.
+type Candidate struct {
+	Shift    int     `json:"shift"`
+	Language string  `json:"language"`
+	Score    float64 `json:"score"`
+	Preview  string  `json:"preview"`
+}
+
+// CrackCaesar tries every shift of the alphabet of every language and ranks
+// the shifts by the chi-squared score of the decrypted letters, best first.
+// The shift is the one the text was encrypted with.
+func CrackCaesar(in io.Reader, opts CrackOptions) ([]Candidate, error) {
+	langs, err := selectLanguages(opts.Languages)
+	if err != nil {
+		return nil, err
+	}
+	if opts.Candidates <= 0 {
+		opts.Candidates = DefaultCandidates
+	}
+	if opts.PreviewLength <= 0 {
+		opts.PreviewLength = DefaultPreviewLength
+	}
+
+	counts, head, err := countLanguages(in, langs, opts.PreviewLength)
+	if err != nil {
+		return nil, err
+	}
+
+	var candidates []Candidate
+	for i, lang := range langs {
+		if counts[i].Total() == 0 {
+			continue
+		}
+		for shift := 0; shift < len(lang.Alphabet); shift++ {
+			candidates = append(candidates, Candidate{
+				Shift:    shift,
+				Language: lang.Name,
+				Score:    ChiSquared(counts[i].Rotate(shift), lang),
+			})
+		}
+	}
+	if len(candidates) == 0 {
+		return nil, ErrNoLetters
+	}
+	sort.SliceStable(candidates, func(i, j int) bool {
+		return candidates[i].Score < candidates[j].Score
+	})
+	if len(candidates) > opts.Candidates {
+		candidates = candidates[:opts.Candidates]
+	}
+	for i := range candidates {
+		lang := Languages[candidates[i].Language]
+		preview, err := decryptCaesar(head, candidates[i].Shift, lang.Alphabet)
+		if err != nil {
+			return nil, err
+		}
+		candidates[i].Preview = preview
+	}
+	return candidates, nil
+}
+
+func selectLanguages(names []string) ([]Language, error) {
+	if len(names) == 0 {
+		names = LanguageNames()
+	}
+	langs := make([]Language, 0, len(names))
+	for _, name := range names {
+		lang, ok := Languages[name]
+		if !ok {
+			return nil, fmt.Errorf("unknown language %q, expected one of %s", name, strings.Join(LanguageNames(), "/"))
+		}
+		langs = append(langs, lang)
+	}
+	return langs, nil
+}
+
+func decryptCaesar(text string, shift int, alphabet []rune) (string, error) {
+	tr := &transformer.CaesarTransformer{Shift: -shift, Alphabet: alphabet}
+	var sb strings.Builder
+	err := tr.TransformStream(strings.NewReader(text), &sb)
+	return sb.String(), err
+}
+
+// countLanguages counts the letters of every language in a single pass and
+// returns the first runes of the text as well.
+func countLanguages(in io.Reader, langs []Language, headLength int) ([]Counts, string, error) {
+	indexes := make([]map[rune]int, len(langs))
+	counts := make([]Counts, len(langs))
+	for i, lang := range langs {
+		indexes[i] = alphabetIndex(lang.Alphabet)
+		counts[i] = make(Counts, len(lang.Alphabet))
+	}
+	var head strings.Builder
+	br := bufio.NewReader(in)
+	for {
+		r, _, err := br.ReadRune()
+		if err == io.EOF {
+			return counts, head.String(), nil
+		}
+		if err != nil {
+			return nil, "", err
+		}
+		if headLength > 0 {
+			head.WriteRune(r)
+			headLength--
+		}
+		lower := unicode.ToLower(r)
+		for i, index := range indexes {
+			if j, ok := index[lower]; ok {
+				counts[i][j]++
+			}
+		}
+	}
+}
@@ -0,0 +1,98 @@
+package analysis
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	"main/transformer"
+)
+
+type TestCrack struct {
+	plain    string
+	alphabet string
+	shift    int
+	language string
+}
+
+var TestArrayCrack = []TestCrack{
+	TestCrack{"The quick brown fox jumps over the lazy dog while the farmer sleeps in the barn.", "latin", 3, "english"},
+	TestCrack{"Defend the east wall of the castle at dawn, reinforcements are coming.", "latin", 13, "english"},
+	TestCrack{"Attack at dawn, the enemy is weak near the old river crossing.", "latin", 25, "english"},
+	TestCrack{"Україна має багату історію, культуру та мову, якою розмовляють мільйони людей.", "ukrainian", 5, "ukrainian"},
+	TestCrack{"Слово не горобець, вилетить то не впіймаєш.", "ukrainian", 20, "ukrainian"},
+}
+
+func TestTableCrack(t *testing.T) {
+
+	for _, test := range TestArrayCrack {
+
+		letters, err := transformer.CaesarAlphabet(test.alphabet)
+		if err != nil {
+			t.Fatalf("Error in alphabet: %s", err)
+		}
+		cipher := new(strings.Builder)
+		tr := &transformer.CaesarTransformer{Shift: test.shift, Alphabet: letters}
+		err = tr.TransformStream(strings.NewReader(test.plain), cipher)
+		if err != nil {
+			t.Fatalf("Error encrypting: %s", err)
+		}
+
+		candidates, err := CrackCaesar(strings.NewReader(cipher.String()), CrackOptions{PreviewLength: 10})
+		if err != nil {
+			t.Fatalf("Error cracking: %s", err)
+		}
+		if len(candidates) != DefaultCandidates {
+			t.Errorf("Error: got %d candidates, expected %d", len(candidates), DefaultCandidates)
+		}
+		best := candidates[0]
+		if best.Shift != test.shift || best.Language != test.language {
+			t.Errorf("Error: best candidate = %+v, expected shift %d in %s", best, test.shift, test.language)
+		}
+		if best.Preview != string([]rune(test.plain)[:10]) {
+			t.Errorf("Error: preview = %q", best.Preview)
+		}
+
+	}
+}
+
+func TestCrackOptions(t *testing.T) {
+	candidates, err := CrackCaesar(strings.NewReader("Wkh txlfn eurzq ira"), CrackOptions{Languages: []string{"english"}, Candidates: 30})
+	if err != nil {
+		t.Fatalf("Error cracking: %s", err)
+	}
+	if len(candidates) != 26 {
+		t.Errorf("Error: got %d candidates, expected one per shift", len(candidates))
+	}
+	for i := 1; i < len(candidates); i++ {
+		if candidates[i].Score < candidates[i-1].Score {
+			t.Errorf("Error: candidates not ranked by score")
+		}
+	}
+
+	_, err = CrackCaesar(strings.NewReader("12345 !"), CrackOptions{})
+	if !errors.Is(err, ErrNoLetters) {
+		t.Errorf("Error: expected ErrNoLetters, got %v", err)
+	}
+	_, err = CrackCaesar(strings.NewReader("abc"), CrackOptions{Languages: []string{"klingon"}})
+	if err == nil {
+		t.Errorf("Error: expected error for an unknown language")
+	}
+}
+
+func TestChiSquared(t *testing.T) {
+	english, err := CountLetters(strings.NewReader("It was the best of times, it was the worst of times."), English.Alphabet)
+	if err != nil {
+		t.Fatal(err)
+	}
+	noise, err := CountLetters(strings.NewReader("Qzx jvq kzzx wq xqj zzq vkj qxz jqx."), English.Alphabet)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if ChiSquared(english, English) >= ChiSquared(noise, English) {
+		t.Errorf("Error: English text scored worse than noise")
+	}
+	if english.Rotate(1).Rotate(-1).Total() != english.Total() {
+		t.Errorf("Error: rotation changed the total")
+	}
+}
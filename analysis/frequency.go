@@ -0,0 +1,129 @@
+// Package analysis scores text against the letter frequencies of natural
+// languages, to break classical ciphers.
+package analysis
+
+import (
+	"bufio"
+	"errors"
+	"io"
+	"sort"
+	"unicode"
+
+	"main/transformer"
+)
+
+// ErrNoLetters is returned for text without a single letter of the
+// alphabets being analysed.
+var ErrNoLetters = errors.New("no letters to analyse")
+
+// Language holds the relative frequency of every letter of an alphabet in
+// ordinary text, in the order of the alphabet.
+type Language struct {
+	Name        string
+	Alphabet    []rune
+	Frequencies []float64
+}
+
+var English = newLanguage("english", transformer.CaesarAlphabets["latin"], []float64{
+	8.167, 1.492, 2.782, 4.253, 12.702, 2.228, 2.015, 6.094, 6.966, 0.153, 0.772, 4.025, 2.406,
+	6.749, 7.507, 1.929, 0.095, 5.987, 6.327, 9.056, 2.758, 0.978, 2.360, 0.150, 1.974, 0.074,
+})
+
+var Ukrainian = newLanguage("ukrainian", transformer.CaesarAlphabets["ukrainian"], []float64{
+	7.2, 1.7, 5.2, 1.6, 0.01, 3.5, 4.8, 0.8, 0.9, 2.3, 6.1, 5.7, 0.6, 1.1, 4.1, 3.8, 3.0,
+	6.8, 9.4, 2.9, 4.7, 4.1, 5.5, 4.0, 0.3, 1.2, 1.0, 1.2, 0.9, 0.5, 2.9, 0.8, 2.9,
+})
+
+// Languages are the languages known to the analysis by name.
+var Languages = map[string]Language{
+	English.Name:   English,
+	Ukrainian.Name: Ukrainian,
+}
+
+// LanguageNames returns the names of Languages sorted.
+func LanguageNames() []string {
+	names := make([]string, 0, len(Languages))
+	for name := range Languages {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+func newLanguage(name, alphabet string, percent []float64) Language {
+	letters := []rune(alphabet)
+	if len(letters) != len(percent) {
+		panic("analysis: " + name + " frequencies do not match the alphabet")
+	}
+	total := 0.0
+	for _, p := range percent {
+		total += p
+	}
+	frequencies := make([]float64, len(percent))
+	for i, p := range percent {
+		frequencies[i] = p / total
+	}
+	return Language{Name: name, Alphabet: letters, Frequencies: frequencies}
+}
+
+// Counts counts the letters of the alphabet in text, ignoring case. The
+// counts are in the order of the alphabet.
+type Counts []int
+
+func CountLetters(in io.Reader, alphabet []rune) (Counts, error) {
+	index := alphabetIndex(alphabet)
+	counts := make(Counts, len(alphabet))
+	br := bufio.NewReader(in)
+	for {
+		r, _, err := br.ReadRune()
+		if err == io.EOF {
+			return counts, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+		if i, ok := index[unicode.ToLower(r)]; ok {
+			counts[i]++
+		}
+	}
+}
+
+func (c Counts) Total() int {
+	total := 0
+	for _, n := range c {
+		total += n
+	}
+	return total
+}
+
+// Rotate returns the counts the text would have after every letter was
+// shifted back by shift places of the alphabet.
+func (c Counts) Rotate(shift int) Counts {
+	n := len(c)
+	rotated := make(Counts, n)
+	for i := range c {
+		rotated[i] = c[((i+shift)%n+n)%n]
+	}
+	return rotated
+}
+
+// ChiSquared measures how far the counts are from the frequencies of the
+// language, lower is closer. Counts must follow the language alphabet.
+func ChiSquared(counts Counts, lang Language) float64 {
+	total := float64(counts.Total())
+	score := 0.0
+	for i, n := range counts {
+		expected := lang.Frequencies[i] * total
+		diff := float64(n) - expected
+		score += diff * diff / expected
+	}
+	return score
+}
+
+func alphabetIndex(alphabet []rune) map[rune]int {
+	index := make(map[rune]int, len(alphabet))
+	for i, r := range alphabet {
+		index[r] = i
+	}
+	return index
+}
@@ -0,0 +1,270 @@
+// Package batch transforms many files at once, each into an output file of
+// its own.
+package batch
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"main/cli"
+	"main/transformer"
+	"os"
+	"path/filepath"
+	"strings"
+	"text/tabwriter"
+)
+
+// Config says which files batch mode transforms and where it writes
+// their output: below OutputDir in the same tree, next to the input with
+// Suffix appended, or over the input.
+type Config struct {
+	Recursive bool
+	OutputDir string
+	Suffix    string
+	InPlace   bool
+}
+
+func (c Config) Validate() error {
+	if c.InPlace && (c.OutputDir != "" || c.Suffix != "") {
+		return errors.New("-in-place cannot be combined with -output-dir or -suffix")
+	}
+	if !c.InPlace && c.OutputDir == "" && c.Suffix == "" {
+		return errors.New("several inputs need -output-dir, -suffix or -in-place")
+	}
+	return nil
+}
+
+type File struct {
+	Path string
+	// Root is the absolute directory whose tree is mirrored below the output
+	// directory: a directory given as input, or the deepest directory all
+	// the files given as inputs are in
+	Root string
+}
+
+type Summary struct {
+	Processed []string
+	Skipped   []string
+	Failed    []string
+}
+
+// Err is cli.Failure when some files failed, so that the command exits with
+// cli.ExitFailure after printing the summary.
+func (s Summary) Err() error {
+	if len(s.Failed) > 0 {
+		return cli.Failure
+	}
+	return nil
+}
+
+// Run transforms every file the patterns name, each one on its own, and
+// reports what happened to them.
+func Run(patterns []string, cfg Config, tr transformer.StreamTransformer, parallel transformer.ParallelOptions) Summary {
+	var summary Summary
+	files := cfg.Expand(patterns, &summary)
+	cfg.TransformFiles(files, tr, parallel, &summary)
+	return summary
+}
+
+// TransformFiles transforms the files in order. A file whose output path
+// is also the output of an earlier one fails instead of overwriting it.
+func (c Config) TransformFiles(files []File, tr transformer.StreamTransformer, parallel transformer.ParallelOptions, summary *Summary) {
+	written := map[string]string{}
+	for _, f := range files {
+		out := c.OutputPath(f)
+		abs, _ := filepath.Abs(out)
+		if first, ok := written[abs]; ok {
+			summary.Failed = append(summary.Failed, fmt.Sprintf("%s: output %s is already written from %s", f.Path, out, first))
+			continue
+		}
+		written[abs] = f.Path
+		err := TransformFile(f.Path, out, tr, parallel)
+		if err != nil {
+			summary.Failed = append(summary.Failed, fmt.Sprintf("%s: %s", f.Path, err))
+			continue
+		}
+		summary.Processed = append(summary.Processed, f.Path+" -> "+out)
+	}
+}
+
+// Expand lists the regular files that the glob patterns name, with the files
+// below directories if Recursive, leaving out the outputs of c.
+func (c Config) Expand(patterns []string, summary *Summary) []File {
+	var files []File
+	seen := map[string]bool{}
+	outputDir, _ := filepath.Abs(c.OutputDir)
+	add := func(path, root string) {
+		abs, _ := filepath.Abs(path)
+		switch {
+		case seen[abs]:
+			return
+		case c.OutputDir != "" && strings.HasPrefix(abs, outputDir+string(filepath.Separator)):
+			summary.Skipped = append(summary.Skipped, path+" (in the output directory)")
+		case c.Suffix != "" && strings.HasSuffix(path, c.Suffix):
+			summary.Skipped = append(summary.Skipped, path+" (already has the output suffix)")
+		default:
+			files = append(files, File{Path: path, Root: root})
+		}
+		seen[abs] = true
+	}
+
+	// explicit files share one root, so that a/x.txt and b/x.txt keep
+	// their directories below the output directory
+	var fileRoot string
+	for _, pattern := range patterns {
+		matches, _ := filepath.Glob(pattern)
+		for _, match := range matches {
+			if info, err := os.Stat(match); err == nil && info.Mode().IsRegular() {
+				dir, _ := filepath.Abs(filepath.Dir(match))
+				fileRoot = commonDir(fileRoot, dir)
+			}
+		}
+	}
+
+	for _, pattern := range patterns {
+		matches, err := filepath.Glob(pattern)
+		if err != nil {
+			summary.Failed = append(summary.Failed, fmt.Sprintf("%s: %s", pattern, err))
+			continue
+		}
+		if len(matches) == 0 {
+			summary.Failed = append(summary.Failed, pattern+": no such file")
+			continue
+		}
+		for _, match := range matches {
+			info, err := os.Stat(match)
+			switch {
+			case err != nil:
+				summary.Failed = append(summary.Failed, fmt.Sprintf("%s: %s", match, err))
+			case info.Mode().IsRegular():
+				add(match, fileRoot)
+			case !info.IsDir():
+				summary.Skipped = append(summary.Skipped, match+" (not a regular file)")
+			case !c.Recursive:
+				summary.Skipped = append(summary.Skipped, match+" (directory, use -recursive)")
+			default:
+				root, _ := filepath.Abs(match)
+				err = filepath.WalkDir(match, func(path string, d fs.DirEntry, err error) error {
+					if err != nil {
+						summary.Failed = append(summary.Failed, fmt.Sprintf("%s: %s", path, err))
+						return nil
+					}
+					if d.Type().IsRegular() {
+						add(path, root)
+					} else if !d.IsDir() {
+						summary.Skipped = append(summary.Skipped, path+" (not a regular file)")
+					}
+					return nil
+				})
+				if err != nil {
+					summary.Failed = append(summary.Failed, fmt.Sprintf("%s: %s", match, err))
+				}
+			}
+		}
+	}
+	return files
+}
+
+// IsOutput tells whether batch mode writes path, as an output or the
+// temporary file of one.
+func (c Config) IsOutput(path string) bool {
+	base := filepath.Base(path)
+	if strings.HasPrefix(base, ".") && strings.HasSuffix(base, ".tmp") {
+		return true
+	}
+	if c.Suffix != "" && strings.HasSuffix(path, c.Suffix) {
+		return true
+	}
+	if c.OutputDir == "" {
+		return false
+	}
+	abs, _ := filepath.Abs(path)
+	outputDir, _ := filepath.Abs(c.OutputDir)
+	return abs == outputDir || strings.HasPrefix(abs, outputDir+string(filepath.Separator))
+}
+
+// OutputPath is where the output of f is written.
+func (c Config) OutputPath(f File) string {
+	if c.InPlace {
+		return f.Path
+	}
+	if c.OutputDir == "" {
+		return f.Path + c.Suffix
+	}
+	abs, _ := filepath.Abs(f.Path)
+	rel, err := filepath.Rel(f.Root, abs)
+	if err != nil {
+		rel = filepath.Base(f.Path)
+	}
+	return filepath.Join(c.OutputDir, rel) + c.Suffix
+}
+
+// commonDir returns the deepest directory containing both absolute
+// directories, b when a is empty.
+func commonDir(a, b string) string {
+	if a == "" {
+		return b
+	}
+	for a != b {
+		if len(a) > len(b) {
+			a, b = b, a
+		}
+		if strings.HasPrefix(b, a) && (strings.HasSuffix(a, string(filepath.Separator)) || b[len(a)] == filepath.Separator) {
+			return a
+		}
+		a = filepath.Dir(a)
+	}
+	return a
+}
+
+// TransformFile writes the output through a temporary file next to it, so
+// the output, or the input transformed in place, is replaced only once the
+// transformation succeeded.
+func TransformFile(inPath, outPath string, tr transformer.StreamTransformer, parallel transformer.ParallelOptions) error {
+	in, err := os.Open(inPath)
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+	info, err := in.Stat()
+	if err != nil {
+		return err
+	}
+	err = os.MkdirAll(filepath.Dir(outPath), 0o755)
+	if err != nil {
+		return err
+	}
+	tmp, err := os.CreateTemp(filepath.Dir(outPath), "."+filepath.Base(outPath)+".*.tmp")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+
+	err = transformer.RunTransformSize(in, tmp, tr, info.Size(), false, parallel)
+	if err == nil {
+		err = tmp.Chmod(info.Mode().Perm())
+	}
+	if closeErr := tmp.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), outPath)
+}
+
+func PrintSummary(w io.Writer, summary Summary) {
+	tw := tabwriter.NewWriter(w, 0, 4, 2, ' ', 0)
+	for _, line := range summary.Processed {
+		fmt.Fprintf(tw, "processed\t%s\n", line)
+	}
+	for _, line := range summary.Skipped {
+		fmt.Fprintf(tw, "skipped\t%s\n", line)
+	}
+	for _, line := range summary.Failed {
+		fmt.Fprintf(tw, "failed\t%s\n", line)
+	}
+	tw.Flush()
+	fmt.Fprintf(w, "%d processed, %d skipped, %d failed\n", len(summary.Processed), len(summary.Skipped), len(summary.Failed))
+}
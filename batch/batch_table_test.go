@@ -0,0 +1,139 @@
+package batch
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"main/cli"
+	"main/transformer"
+)
+
+// chdirTree changes into a temporary directory with a tree of small files
+// until the test ends.
+func chdirTree(t *testing.T) {
+	dir := t.TempDir()
+	for _, name := range []string{"a/x.txt", "a/z.txt", "a/sub/w.txt", "b/x.txt", "d1/y", "d2/y"} {
+		path := filepath.Join(dir, filepath.FromSlash(name))
+		if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+			t.Fatalf("Error creating the tree: %s", err)
+		}
+		if err := os.WriteFile(path, []byte(name), 0o644); err != nil {
+			t.Fatalf("Error creating the tree: %s", err)
+		}
+	}
+	cwd, err := os.Getwd()
+	if err != nil {
+		t.Fatalf("Error: %s", err)
+	}
+	if err := os.Chdir(dir); err != nil {
+		t.Fatalf("Error: %s", err)
+	}
+	t.Cleanup(func() { os.Chdir(cwd) })
+}
+
+type TestBatch struct {
+	patterns  []string
+	cfg       Config
+	processed []string
+	skipped   int
+	failed    int
+}
+
+var TestArrayBatch = []TestBatch{
+	TestBatch{[]string{"a/x.txt", "b/x.txt"}, Config{OutputDir: "out"}, []string{"a/x.txt -> out/a/x.txt", "b/x.txt -> out/b/x.txt"}, 0, 0},
+	TestBatch{[]string{"a/x.txt"}, Config{OutputDir: "out"}, []string{"a/x.txt -> out/x.txt"}, 0, 0},
+	TestBatch{[]string{"a/*.txt"}, Config{OutputDir: "out"}, []string{"a/x.txt -> out/x.txt", "a/z.txt -> out/z.txt"}, 0, 0},
+	TestBatch{[]string{"a/sub/w.txt", "a/x.txt"}, Config{OutputDir: "out"}, []string{"a/sub/w.txt -> out/sub/w.txt", "a/x.txt -> out/x.txt"}, 0, 0},
+	TestBatch{[]string{"a"}, Config{Recursive: true, OutputDir: "out"}, []string{"a/sub/w.txt -> out/sub/w.txt", "a/x.txt -> out/x.txt", "a/z.txt -> out/z.txt"}, 0, 0},
+	TestBatch{[]string{"a"}, Config{OutputDir: "out"}, nil, 1, 0},
+	TestBatch{[]string{"a/x.txt", "a/x.txt"}, Config{Suffix: ".enc"}, []string{"a/x.txt -> a/x.txt.enc"}, 0, 0},
+	TestBatch{[]string{"b/x.txt"}, Config{InPlace: true}, []string{"b/x.txt -> b/x.txt"}, 0, 0},
+	TestBatch{[]string{"d1", "d2"}, Config{Recursive: true, OutputDir: "out"}, []string{"d1/y -> out/y"}, 0, 1},
+	TestBatch{[]string{"missing.txt", "a/x.txt"}, Config{OutputDir: "out"}, []string{"a/x.txt -> out/x.txt"}, 0, 1},
+}
+
+func TestTableBatch(t *testing.T) {
+
+	for _, test := range TestArrayBatch {
+
+		chdirTree(t)
+		summary := Run(test.patterns, test.cfg, transformer.NewBase64Transformer(), transformer.ParallelOptions{})
+		var processed []string
+		for _, line := range summary.Processed {
+			processed = append(processed, filepath.ToSlash(line))
+		}
+		if strings.Join(processed, "; ") != strings.Join(test.processed, "; ") {
+			t.Errorf("Error: %q processed %q, expected %q", test.patterns, processed, test.processed)
+		}
+		if len(summary.Skipped) != test.skipped || len(summary.Failed) != test.failed {
+			t.Errorf("Error: %q skipped %q and failed %q, expected %d and %d", test.patterns, summary.Skipped, summary.Failed, test.skipped, test.failed)
+		}
+		code := cli.ExitOK
+		var exitErr *cli.Error
+		if errors.As(summary.Err(), &exitErr) {
+			code = exitErr.Code
+		}
+		expectedCode := cli.ExitOK
+		if test.failed > 0 {
+			expectedCode = cli.ExitFailure
+		}
+		if code != expectedCode {
+			t.Errorf("Error: %q would exit with %d, expected %d", test.patterns, code, expectedCode)
+		}
+		for _, line := range summary.Processed {
+			in, out, _ := strings.Cut(line, " -> ")
+			data, err := os.ReadFile(out)
+			if err != nil {
+				t.Errorf("Error: %s", err)
+				continue
+			}
+			name := filepath.ToSlash(in)
+			if encoded := transformer.NewBase64Transformer().Encoding.EncodeToString([]byte(name)); string(data) != encoded {
+				t.Errorf("Error: %s holds %q, expected %q", out, data, encoded)
+			}
+		}
+	}
+}
+
+func TestPrintSummary(t *testing.T) {
+	chdirTree(t)
+	summary := Run([]string{"a", "a/x.txt", "missing.txt"}, Config{Suffix: ".enc"}, transformer.NewBase64Transformer(), transformer.ParallelOptions{})
+	out := new(strings.Builder)
+	PrintSummary(out, summary)
+	expected := []string{
+		"processed  a/x.txt -> a/x.txt.enc",
+		"skipped    a (directory, use -recursive)",
+		"failed     missing.txt: no such file",
+		"1 processed, 1 skipped, 1 failed",
+	}
+	if strings.TrimSpace(filepath.ToSlash(out.String())) != strings.Join(expected, "\n") {
+		t.Errorf("Error: summary %q, expected %q", out, expected)
+	}
+}
+
+type TestValidate struct {
+	cfg   Config
+	valid bool
+}
+
+var TestArrayValidate = []TestValidate{
+	TestValidate{Config{OutputDir: "out"}, true},
+	TestValidate{Config{OutputDir: "out", Suffix: ".enc"}, true},
+	TestValidate{Config{InPlace: true}, true},
+	TestValidate{Config{InPlace: true, Suffix: ".enc"}, false},
+	TestValidate{Config{Recursive: true}, false},
+}
+
+func TestTableValidate(t *testing.T) {
+
+	for _, test := range TestArrayValidate {
+
+		err := test.cfg.Validate()
+		if (err == nil) != test.valid {
+			t.Errorf("Error: %+v validated with %v, expected valid %t", test.cfg, err, test.valid)
+		}
+	}
+}
@@ -0,0 +1,273 @@
+// Package cli runs a tree of commands whose options are standard flag sets,
+// and generates their help from those flags.
+package cli
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+	"text/tabwriter"
+)
+
+// Exit codes of Execute.
+const (
+	ExitOK = 0
+	// ExitFailure is a negative result the command reports, such as a digest
+	// mismatch or some files of a batch that failed.
+	ExitFailure = 1
+	// ExitUsage is an unknown command, invalid flags or conflicting options.
+	ExitUsage = 2
+	// ExitIO is a file, network or database that could not be used.
+	ExitIO = 3
+	// ExitTransform is input that could not be transformed.
+	ExitTransform = 4
+	// ExitNotFound is a record or other resource that does not exist.
+	ExitNotFound = 5
+)
+
+// Error makes a command exit with Code.
+type Error struct {
+	Code int
+	Err  error
+}
+
+func (e *Error) Error() string {
+	return e.Err.Error()
+}
+
+func (e *Error) Unwrap() error {
+	return e.Err
+}
+
+func Usagef(format string, args ...any) error {
+	return &Error{Code: ExitUsage, Err: fmt.Errorf(format, args...)}
+}
+
+// IOError, TransformError and NotFoundError set the exit code of err unless
+// it has one.
+func IOError(err error) error {
+	return withCode(ExitIO, err)
+}
+
+func TransformError(err error) error {
+	return withCode(ExitTransform, err)
+}
+
+func NotFoundError(err error) error {
+	return withCode(ExitNotFound, err)
+}
+
+// Failure reports a negative result without a message.
+var Failure = &Error{Code: ExitFailure, Err: errors.New("failed")}
+
+func withCode(code int, err error) error {
+	var e *Error
+	if err == nil || errors.As(err, &e) {
+		return err
+	}
+	return &Error{Code: code, Err: err}
+}
+
+type Command struct {
+	Name    string
+	Aliases []string
+	// Args is the synopsis of the arguments after the options.
+	Args  string
+	Short string
+	Long  string
+	// Exclusive lists groups of flags of which at most one may be given.
+	Exclusive [][]string
+	// Flags are set up by the command before Run, see FlagSet.
+	Flags    func(fs *flag.FlagSet)
+	Run      func(args []string) error
+	Commands []*Command
+
+	parent *Command
+	fs     *flag.FlagSet
+}
+
+// FlagSet returns the options of the command.
+func (c *Command) FlagSet() *flag.FlagSet {
+	if c.fs == nil {
+		c.fs = flag.NewFlagSet(c.Path(), flag.ContinueOnError)
+		c.fs.SetOutput(io.Discard)
+		if c.Flags != nil {
+			c.Flags(c.fs)
+		}
+	}
+	return c.fs
+}
+
+// Path is the command line that runs c, such as "main records list".
+func (c *Command) Path() string {
+	if c.parent == nil {
+		return c.Name
+	}
+	return c.parent.Path() + " " + c.Name
+}
+
+func (c *Command) find(name string) *Command {
+	for _, sub := range c.Commands {
+		if sub.Name == name {
+			return sub
+		}
+		for _, alias := range sub.Aliases {
+			if alias == name {
+				return sub
+			}
+		}
+	}
+	return nil
+}
+
+// Execute runs the command that args select below c and returns the exit
+// code. Errors go to stderr, help to stdout.
+func (c *Command) Execute(args []string, stdout, stderr io.Writer) int {
+	c.setParents()
+	cmd := c
+	for len(args) > 0 && len(cmd.Commands) > 0 {
+		if args[0] == "help" {
+			target := cmd
+			for _, name := range args[1:] {
+				if target = target.find(name); target == nil {
+					return report(stderr, cmd, Usagef("unknown command %q", name))
+				}
+			}
+			target.PrintHelp(stdout)
+			return ExitOK
+		}
+		sub := cmd.find(args[0])
+		if sub == nil {
+			break
+		}
+		cmd, args = sub, args[1:]
+	}
+	fs := cmd.FlagSet()
+	err := fs.Parse(args)
+	if errors.Is(err, flag.ErrHelp) {
+		cmd.PrintHelp(stdout)
+		return ExitOK
+	}
+	if err != nil {
+		return report(stderr, cmd, Usagef("%s", err))
+	}
+	if cmd.Run == nil {
+		if fs.NArg() > 0 {
+			return report(stderr, cmd, Usagef("unknown command %q", fs.Arg(0)))
+		}
+		cmd.PrintHelp(stdout)
+		return ExitUsage
+	}
+	if err := cmd.checkExclusive(); err != nil {
+		return report(stderr, cmd, err)
+	}
+	return report(stderr, cmd, cmd.Run(fs.Args()))
+}
+
+func (c *Command) setParents() {
+	for _, sub := range c.Commands {
+		sub.parent = c
+		sub.setParents()
+	}
+}
+
+func (c *Command) checkExclusive() error {
+	set := map[string]bool{}
+	c.FlagSet().Visit(func(f *flag.Flag) { set[f.Name] = true })
+	for _, group := range c.Exclusive {
+		var given []string
+		for _, name := range group {
+			if set[name] {
+				given = append(given, "-"+name)
+			}
+		}
+		if len(given) > 1 {
+			return Usagef("%s cannot be used together", strings.Join(given, " and "))
+		}
+	}
+	return nil
+}
+
+func report(stderr io.Writer, cmd *Command, err error) int {
+	if err == nil {
+		return ExitOK
+	}
+	code := ExitFailure
+	var e *Error
+	if errors.As(err, &e) {
+		code = e.Code
+	}
+	if err != Failure {
+		fmt.Fprintf(stderr, "%s: %s\n", cmd.Path(), err)
+	}
+	if code == ExitUsage {
+		fmt.Fprintf(stderr, "Run '%s -help' for usage.\n", cmd.Path())
+	}
+	return code
+}
+
+// PrintHelp writes the usage of c, generated from its subcommands and flags.
+func (c *Command) PrintHelp(w io.Writer) {
+	usage := c.Path()
+	if len(c.Commands) > 0 {
+		usage += " COMMAND"
+	}
+	hasFlags := false
+	c.FlagSet().VisitAll(func(*flag.Flag) { hasFlags = true })
+	if hasFlags {
+		usage += " [OPTIONS]"
+	}
+	if c.Args != "" {
+		usage += " " + c.Args
+	}
+	fmt.Fprintf(w, "Usage:  %s\n\n", usage)
+	if c.Long != "" {
+		fmt.Fprintf(w, "%s\n\n", strings.TrimSpace(c.Long))
+	} else if c.Short != "" {
+		fmt.Fprintf(w, "%s\n\n", c.Short)
+	}
+	tw := tabwriter.NewWriter(w, 0, 8, 2, ' ', 0)
+	if len(c.Commands) > 0 {
+		fmt.Fprintln(tw, "Commands:")
+		for _, sub := range c.Commands {
+			name := sub.Name
+			if len(sub.Aliases) > 0 {
+				name += " (" + strings.Join(sub.Aliases, ", ") + ")"
+			}
+			fmt.Fprintf(tw, "\t%s\t%s\n", name, sub.Short)
+		}
+		fmt.Fprintf(tw, "\thelp\tShow the usage of a command: %s help COMMAND\n", c.Path())
+		fmt.Fprintln(tw)
+	}
+	if hasFlags {
+		fmt.Fprintln(tw, "Options:")
+		c.FlagSet().VisitAll(func(f *flag.Flag) {
+			name, usage := flag.UnquoteUsage(f)
+			line := "-" + f.Name
+			if name != "" {
+				line += " " + name
+			}
+			if f.DefValue != "" && f.DefValue != "false" && f.DefValue != "0" {
+				usage += fmt.Sprintf(" (default %q)", f.DefValue)
+			}
+			fmt.Fprintf(tw, "\t%s\t%s\n", line, usage)
+		})
+		fmt.Fprintln(tw)
+	}
+	if len(c.Exclusive) > 0 {
+		groups := make([]string, 0, len(c.Exclusive))
+		for _, group := range c.Exclusive {
+			groups = append(groups, "-"+strings.Join(group, ", -"))
+		}
+		sort.Strings(groups)
+		fmt.Fprintln(tw, "Only one of each of these can be given:")
+		for _, g := range groups {
+			fmt.Fprintf(tw, "\t%s\n", g)
+		}
+		fmt.Fprintln(tw)
+	}
+	tw.Flush()
+}
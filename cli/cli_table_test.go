@@ -0,0 +1,84 @@
+package cli
+
+import (
+	"errors"
+	"flag"
+	"strings"
+	"testing"
+)
+
+type TestExecute struct {
+	args   []string
+	code   int
+	stdout string
+	stderr string
+}
+
+var TestArrayExecute = []TestExecute{
+	TestExecute{[]string{"echo", "-upper", "hi"}, ExitOK, "HI", ""},
+	TestExecute{[]string{"say", "hi"}, ExitOK, "hi", ""},
+	TestExecute{[]string{"echo", "-upper", "-lower", "hi"}, ExitUsage, "", "-upper and -lower cannot be used together"},
+	TestExecute{[]string{"echo", "-loud"}, ExitUsage, "", "Run 'main echo -help' for usage."},
+	TestExecute{[]string{"shout"}, ExitUsage, "", `unknown command "shout"`},
+	TestExecute{[]string{"echo"}, ExitFailure, "", ""},
+	TestExecute{[]string{"open"}, ExitIO, "", "main open: no such file"},
+	TestExecute{[]string{"help", "echo"}, ExitOK, "Usage:  main echo [OPTIONS] WORDS...", ""},
+	TestExecute{[]string{"echo", "-h"}, ExitOK, "-upper", ""},
+	TestExecute{[]string{}, ExitUsage, "echo (say)", ""},
+}
+
+func newTestCommand(out *strings.Builder) *Command {
+	var upper, lower bool
+	return &Command{
+		Name: "main",
+		Commands: []*Command{
+			{
+				Name:      "echo",
+				Aliases:   []string{"say"},
+				Args:      "WORDS...",
+				Short:     "Print the words",
+				Exclusive: [][]string{{"upper", "lower"}},
+				Flags: func(fs *flag.FlagSet) {
+					fs.BoolVar(&upper, "upper", false, "Print in upper case")
+					fs.BoolVar(&lower, "lower", false, "Print in lower case")
+				},
+				Run: func(args []string) error {
+					if len(args) == 0 {
+						return Failure
+					}
+					s := strings.Join(args, " ")
+					if upper {
+						s = strings.ToUpper(s)
+					}
+					out.WriteString(s)
+					return nil
+				},
+			},
+			{
+				Name: "open",
+				Run: func(args []string) error {
+					return TransformError(IOError(errors.New("no such file")))
+				},
+			},
+		},
+	}
+}
+
+func TestTableExecute(t *testing.T) {
+
+	for _, test := range TestArrayExecute {
+
+		stdout := new(strings.Builder)
+		stderr := new(strings.Builder)
+		code := newTestCommand(stdout).Execute(test.args, stdout, stderr)
+		if code != test.code {
+			t.Errorf("Error: %q exited with %d, expected %d (%s)", test.args, code, test.code, stderr)
+		}
+		if !strings.Contains(stdout.String(), test.stdout) {
+			t.Errorf("Error: %q printed %q, expected %q", test.args, stdout, test.stdout)
+		}
+		if !strings.Contains(stderr.String(), test.stderr) {
+			t.Errorf("Error: %q reported %q, expected %q", test.args, stderr, test.stderr)
+		}
+	}
+}
@@ -0,0 +1,350 @@
+// Package command is the command line of the program: it transforms files
+// and std.in, serves the records API and talks to it.
+package command
+
+import (
+	"crypto/subtle"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"log"
+	"main/analysis"
+	"main/cli"
+	"main/records"
+	"main/transformer"
+	"os"
+	"runtime/debug"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+	"time"
+)
+
+// Main loads the plugins and keys, runs the command of args and returns its
+// exit code.
+func Main(version string, args []string, stdout, stderr io.Writer) int {
+	loadPlugins()
+	loadKeys()
+
+	return newRootCommand(version).Execute(args, stdout, stderr)
+}
+
+func newRootCommand(version string) *cli.Command {
+	return &cli.Command{
+		Name: "./bin/main",
+		Long: `
+Transform text with ciphers, codecs, compression, encryption and hashes, and
+serve the transformations over HTTP.
+
+Plugins:
+	Executables in $TRANSFORMER_PLUGIN_DIR (default ./plugins) are loaded as transformers at startup.
+	$TRANSFORMER_PLUGIN_TIMEOUT (e.g. 30s) and $TRANSFORMER_PLUGIN_MAX_OUTPUT (bytes) limit every run.
+
+Keys:
+	Files <id>.key in $TRANSFORMER_KEY_DIR (default ./keys) hold 32 raw bytes or 64 hex digits and are used by aes:<id>.
+	HTTP requests only name the key id, the transform command can also take a key with -key-file or -passphrase.
+
+Exit codes:
+	0  success
+	1  negative result, such as a digest mismatch or files of a batch that failed
+	2  usage error: unknown command, invalid or conflicting options
+	3  I/O error: a file, the server or the database could not be used, or the server failed (HTTP 5xx)
+	4  transform error: the input could not be transformed, such as malformed encoded input, or the server rejected it (HTTP 400, 422)
+	5  not found: the record does not exist (HTTP 404)`,
+		Commands: []*cli.Command{
+			transformCommand("transform", false),
+			transformCommand("decode", true),
+			crackCommand(),
+			verifyCommand(),
+			serveCommand(),
+			migrateCommand(),
+			records.Command(),
+			replCommand(),
+			versionCommand(version),
+		},
+	}
+}
+
+func crackCommand() *cli.Command {
+	var fileIn, langs string
+	var opts analysis.CrackOptions
+	return &cli.Command{
+		Name:  "crack",
+		Short: "Find the shift of Caesar ciphertext by letter frequencies",
+		Flags: func(fs *flag.FlagSet) {
+			fs.StringVar(&fileIn, "input", "", "Path to file input, std.in if not set")
+			fs.StringVar(&langs, "lang", "", "Comma separated languages to try: "+strings.Join(analysis.LanguageNames(), ", ")+" (default all)")
+			fs.IntVar(&opts.Candidates, "top", analysis.DefaultCandidates, "Number of candidate shifts to show")
+			fs.IntVar(&opts.PreviewLength, "preview", analysis.DefaultPreviewLength, "Characters of plain text to show per candidate")
+		},
+		Run: func(args []string) error {
+			if len(args) > 0 {
+				return cli.Usagef("unexpected argument %q", args[0])
+			}
+			if langs != "" {
+				opts.Languages = strings.Split(langs, ",")
+				for _, lang := range opts.Languages {
+					if _, ok := analysis.Languages[lang]; !ok {
+						return cli.Usagef("unknown language %q", lang)
+					}
+				}
+			}
+			in, _, err := IoConfig{FileIn: fileIn}.openInput()
+			if err != nil {
+				return cli.IOError(err)
+			}
+			defer in.Close()
+			candidates, err := analysis.CrackCaesar(in, opts)
+			if err != nil {
+				return cli.TransformError(err)
+			}
+			tw := tabwriter.NewWriter(os.Stdout, 0, 8, 2, ' ', 0)
+			fmt.Fprintln(tw, "RANK\tSHIFT\tLANGUAGE\tSCORE\tPREVIEW")
+			for i, c := range candidates {
+				fmt.Fprintf(tw, "%d\t%d\t%s\t%.2f\t%q\n", i+1, c.Shift, c.Language, c.Score, c.Preview)
+			}
+			return tw.Flush()
+		},
+	}
+}
+
+func verifyCommand() *cli.Command {
+	var fileIn, hashName, digest, keyFile, passphrase string
+	return &cli.Command{
+		Name:      "verify",
+		Short:     "Check that the input hashes to the digest given with -digest, exits with 1 on mismatch",
+		Exclusive: [][]string{{"key-file", "passphrase"}},
+		Flags: func(fs *flag.FlagSet) {
+			fs.StringVar(&fileIn, "input", "", "Path to file input, std.in if not set")
+			fs.StringVar(&hashName, "hash", "sha256", "Hash function, e.g. sha256 or hmac-sha256")
+			fs.StringVar(&digest, "digest", "", "Expected digest, hex or Base64")
+			fs.StringVar(&keyFile, "key-file", "", "Key file for hmac-sha256, or $TRANSFORMER_KEY_FILE")
+			fs.StringVar(&passphrase, "passphrase", "", "Key for hmac-sha256, or $TRANSFORMER_PASSPHRASE")
+		},
+		Run: func(args []string) error {
+			if len(args) > 0 {
+				return cli.Usagef("unexpected argument %q", args[0])
+			}
+			if digest == "" {
+				return cli.Usagef("expected -digest")
+			}
+			err := registerCLIKey(keyFile, passphrase)
+			if err != nil {
+				return err
+			}
+			ok, err := verify(fileIn, hashName, digest)
+			if err != nil {
+				return err
+			}
+			if !ok {
+				fmt.Println("MISMATCH")
+				return cli.Failure
+			}
+			fmt.Println("OK")
+			return nil
+		},
+	}
+}
+
+func versionCommand(version string) *cli.Command {
+	return &cli.Command{
+		Name:  "version",
+		Short: "Show the version of the program",
+		Run: func(args []string) error {
+			line := "./bin/main " + version
+			if info, ok := debug.ReadBuildInfo(); ok {
+				line += " " + info.GoVersion
+				for _, s := range info.Settings {
+					if s.Key == "vcs.revision" {
+						line += " " + s.Value
+					}
+				}
+			}
+			fmt.Println(line)
+			return nil
+		},
+	}
+}
+
+// IoConfig names the input and output files, std.in and std.out when a name
+// is empty or "-".
+type IoConfig struct {
+	FileIn, FileOut string
+}
+
+func (c IoConfig) Stdin() bool {
+	return c.FileIn == "" || c.FileIn == "-"
+}
+
+// openInput returns the input and its size, 0 for std.in.
+func (c IoConfig) openInput() (io.ReadCloser, int64, error) {
+	if c.Stdin() {
+		return io.NopCloser(os.Stdin), 0, nil
+	}
+	f, err := os.Open(c.FileIn)
+	if err != nil {
+		return nil, 0, err
+	}
+	info, err := f.Stat()
+	if err != nil {
+		f.Close()
+		return nil, 0, err
+	}
+	return f, info.Size(), nil
+}
+
+func (c IoConfig) createOutput() (io.WriteCloser, error) {
+	if c.FileOut == "" || c.FileOut == "-" {
+		return nopWriteCloser{os.Stdout}, nil
+	}
+	return os.Create(c.FileOut)
+}
+
+type nopWriteCloser struct {
+	io.Writer
+}
+
+func (nopWriteCloser) Close() error {
+	return nil
+}
+
+func printTransformers(w io.Writer) {
+	fmt.Fprintln(w, "Transformers (pipeline steps and the type field of POST /records):")
+	tw := tabwriter.NewWriter(w, 0, 8, 2, ' ', 0)
+	for _, spec := range transformer.Specs() {
+		description := spec.Description
+		if spec.Legacy {
+			description += " (legacy)"
+		}
+		fmt.Fprintf(tw, "\t%s\t%s\n", spec.Name, description)
+		for _, p := range spec.Params {
+			line := fmt.Sprintf("%s (%s)", p.Name, p.Type)
+			if p.Name == spec.MainParam {
+				line += ", main"
+			}
+			if p.Required {
+				line += ", required"
+			}
+			if p.Default != "" {
+				line += ", default " + p.Default
+			}
+			fmt.Fprintf(tw, "\t  %s\t%s\n", line, p.Description)
+		}
+	}
+	tw.Flush()
+}
+
+// verify hashes the file, or std.in, and compares the digest with the
+// expected one given in hex or Base64.
+func verify(fileIn, hashName, digest string) (bool, error) {
+	h, err := transformer.NewHash(hashName)
+	if err != nil {
+		return false, cli.Usagef("%s", err)
+	}
+	in, _, err := IoConfig{FileIn: fileIn}.openInput()
+	if err != nil {
+		return false, cli.IOError(err)
+	}
+	defer in.Close()
+	sum, err := h.Sum(in)
+	if err != nil {
+		return false, cli.IOError(err)
+	}
+	expected, err := hex.DecodeString(digest)
+	if err != nil || len(expected) != len(sum) {
+		expected, err = base64.StdEncoding.DecodeString(digest)
+		if err != nil || len(expected) != len(sum) {
+			return false, cli.Usagef("digest is not a %d-byte value in hex or Base64", len(sum))
+		}
+	}
+	return subtle.ConstantTimeCompare(sum, expected) == 1, nil
+}
+
+// registerCLIKey makes the key given on the command line the default key of
+// aes steps. Without -key-file and -passphrase it falls back to
+// $TRANSFORMER_KEY_FILE and $TRANSFORMER_PASSPHRASE, which are read only here
+// so that help does not show a secret as a default.
+func registerCLIKey(keyFile, passphrase string) error {
+	if keyFile == "" && passphrase == "" {
+		keyFile, passphrase = os.Getenv("TRANSFORMER_KEY_FILE"), os.Getenv("TRANSFORMER_PASSPHRASE")
+	}
+	var key transformer.Key
+	var err error
+	switch {
+	case keyFile != "" && passphrase != "":
+		return cli.Usagef("use either a key file or a passphrase")
+	case keyFile != "":
+		key, err = transformer.LoadKeyFile(keyFile)
+	case passphrase != "":
+		key, err = transformer.PassphraseKey(passphrase)
+	default:
+		return nil
+	}
+	if err != nil {
+		return cli.IOError(fmt.Errorf("encryption key: %w", err))
+	}
+	transformer.RegisterKey(transformer.DefaultKeyID, key)
+	return nil
+}
+
+func isTerminal(f *os.File) bool {
+	info, err := f.Stat()
+	return err == nil && info.Mode()&os.ModeCharDevice != 0
+}
+
+func loadKeys() {
+	dir, ok := os.LookupEnv("TRANSFORMER_KEY_DIR")
+	if !ok {
+		dir = "keys"
+		if _, err := os.Stat(dir); err != nil {
+			return
+		}
+	}
+	_, errs := transformer.LoadKeys(dir)
+	for _, err := range errs {
+		log.Print(fmt.Errorf("failed to load key: %w", err))
+	}
+}
+
+func loadPlugins() {
+	dir, ok := os.LookupEnv("TRANSFORMER_PLUGIN_DIR")
+	if !ok {
+		dir = "plugins"
+		if _, err := os.Stat(dir); err != nil {
+			return
+		}
+	}
+	var limits transformer.PluginLimits
+	if v := os.Getenv("TRANSFORMER_PLUGIN_TIMEOUT"); v != "" {
+		d, err := time.ParseDuration(v)
+		if err != nil {
+			log.Print(fmt.Errorf("invalid TRANSFORMER_PLUGIN_TIMEOUT: %w", err))
+		}
+		limits.Timeout = d
+	}
+	if v := os.Getenv("TRANSFORMER_PLUGIN_MAX_OUTPUT"); v != "" {
+		n, err := strconv.ParseInt(v, 10, 64)
+		if err != nil {
+			log.Print(fmt.Errorf("invalid TRANSFORMER_PLUGIN_MAX_OUTPUT: %w", err))
+		}
+		limits.MaxOutput = n
+	}
+	_, errs := transformer.LoadPlugins(dir, limits)
+	for _, err := range errs {
+		log.Print(fmt.Errorf("failed to load plugin: %w", err))
+	}
+}
+
+// errorCode picks the exit code of an error of a transformation, I/O for
+// files and connections, transform for the rest.
+func errorCode(err error) error {
+	var pathErr *os.PathError
+	var syscallErr *os.SyscallError
+	if errors.As(err, &pathErr) || errors.As(err, &syscallErr) {
+		return cli.IOError(err)
+	}
+	return cli.TransformError(err)
+}
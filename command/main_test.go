@@ -0,0 +1,130 @@
+package command
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// execute runs the root command with stdin as std.in and returns the exit
+// code and what the command printed to std.out and stderr.
+func execute(t *testing.T, stdin string, args ...string) (int, string, string) {
+	t.Helper()
+	dir := t.TempDir()
+	in, err := os.Create(filepath.Join(dir, "stdin"))
+	if err == nil {
+		_, err = in.WriteString(stdin)
+	}
+	if err == nil {
+		_, err = in.Seek(0, 0)
+	}
+	out, outErr := os.Create(filepath.Join(dir, "stdout"))
+	if err == nil {
+		err = outErr
+	}
+	if err != nil {
+		t.Fatalf("Error: %s", err)
+	}
+	defer in.Close()
+	defer out.Close()
+
+	oldStdin, oldStdout := os.Stdin, os.Stdout
+	os.Stdin, os.Stdout = in, out
+	defer func() { os.Stdin, os.Stdout = oldStdin, oldStdout }()
+
+	var help, stderr strings.Builder
+	code := newRootCommand("test").Execute(args, &help, &stderr)
+	printed, _ := os.ReadFile(out.Name())
+	return code, help.String() + string(printed), stderr.String()
+}
+
+type TestExitCode struct {
+	args  []string
+	stdin string
+	code  int
+}
+
+var TestArrayExitCode = []TestExitCode{
+	TestExitCode{[]string{"transform", "-caesar", "3", "-input", "input.txt"}, "", 0},
+	TestExitCode{[]string{"transform", "-input", "missing.txt"}, "", 3},
+	TestExitCode{[]string{"transform", "-caesar", "3", "-input", "missing.txt"}, "", 3},
+	TestExitCode{[]string{"transform", "-caesar", "3", "-atbash"}, "hello", 2},
+	TestExitCode{[]string{"transform", "-lines", "-field", "2"}, "a,b", 2},
+	TestExitCode{[]string{"transform", "-key-file", "key", "-passphrase", "secret"}, "hello", 2},
+	TestExitCode{[]string{"transform", "-unknown"}, "hello", 2},
+	TestExitCode{[]string{"transform", "-pipeline", "nope"}, "hello", 2},
+	TestExitCode{[]string{"decode", "-base64"}, "aGVsbG8=", 0},
+	TestExitCode{[]string{"decode", "-base64"}, "not base64!", 4},
+	TestExitCode{[]string{"decode", "-codec", "hex"}, "zz", 4},
+	TestExitCode{[]string{"verify", "-digest", "2cf24dba5fb0a30e26e83b2ac5b9e29e1b161e5c1fa7425e73043362938b9824"}, "hello", 0},
+	TestExitCode{[]string{"verify", "-digest", "0000000000000000000000000000000000000000000000000000000000000000"}, "hello", 1},
+	TestExitCode{[]string{"verify", "-digest", "abc"}, "hello", 2},
+	TestExitCode{[]string{"verify", "-digest", "00", "-input", "missing.txt"}, "", 3},
+	TestExitCode{[]string{"records", "get", "-print", "xml", "id"}, "", 2},
+	TestExitCode{[]string{"records", "list", "-output", "json"}, "", 2},
+	TestExitCode{[]string{"nope"}, "", 2},
+	TestExitCode{[]string{"help", "transform"}, "", 0},
+	TestExitCode{[]string{"transform", "-help"}, "", 0},
+}
+
+func TestTableExitCode(t *testing.T) {
+
+	dir := t.TempDir()
+	err := os.WriteFile(filepath.Join(dir, "input.txt"), []byte("hello"), 0o644)
+	if err != nil {
+		t.Fatalf("Error: %s", err)
+	}
+
+	for _, test := range TestArrayExitCode {
+
+		args := make([]string, len(test.args))
+		for i, arg := range test.args {
+			if strings.HasSuffix(arg, ".txt") {
+				arg = filepath.Join(dir, arg)
+			}
+			args[i] = arg
+		}
+		code, _, stderr := execute(t, test.stdin, args...)
+		if code != test.code {
+			t.Errorf("Error: %q exits with %d, expected %d, stderr %q", test.args, code, test.code, stderr)
+		}
+	}
+}
+
+func TestKeyFromEnvironment(t *testing.T) {
+	t.Setenv("TRANSFORMER_PASSPHRASE", "env secret")
+	t.Setenv("TRANSFORMER_KEY_FILE", "/env/key/file")
+	keyFile := filepath.Join(t.TempDir(), "key")
+	err := os.WriteFile(keyFile, []byte(strings.Repeat("ab", 32)), 0o600)
+	if err != nil {
+		t.Fatalf("Error: %s", err)
+	}
+
+	for _, args := range [][]string{{"transform", "-help"}, {"decode", "-help"}, {"verify", "-help"}, {"repl", "-help"}} {
+		code, stdout, _ := execute(t, "", args...)
+		if code != 0 || strings.Contains(stdout, "env secret") || strings.Contains(stdout, "/env/key/file") {
+			t.Errorf("Error: %q exits with %d and shows the environment: %q", args, code, stdout)
+		}
+	}
+
+	// a flag replaces both variables
+	code, _, stderr := execute(t, "hello", "transform", "-encrypt", "-key-file", keyFile)
+	if code != 0 {
+		t.Errorf("Error: -key-file with $TRANSFORMER_PASSPHRASE exits with %d: %s", code, stderr)
+	}
+	code, _, stderr = execute(t, "hello", "transform", "-encrypt", "-passphrase", "flag secret")
+	if code != 0 {
+		t.Errorf("Error: -passphrase with $TRANSFORMER_KEY_FILE exits with %d: %s", code, stderr)
+	}
+	// without flags both variables are used, which conflict
+	code, _, _ = execute(t, "hello", "transform", "-encrypt")
+	if code != 2 {
+		t.Errorf("Error: both variables exit with %d, expected 2", code)
+	}
+	t.Setenv("TRANSFORMER_KEY_FILE", "")
+	code, _, stderr = execute(t, "hello", "transform", "-encrypt")
+	if code != 0 {
+		t.Errorf("Error: $TRANSFORMER_PASSPHRASE exits with %d: %s", code, stderr)
+	}
+}
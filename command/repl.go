@@ -0,0 +1,57 @@
+package command
+
+import (
+	"flag"
+	"fmt"
+	"main/cli"
+	"main/repl"
+	"os"
+	"path/filepath"
+)
+
+func replCommand() *cli.Command {
+	var pipeline, historyPath, keyFile, passphrase string
+	return &cli.Command{
+		Name:      "repl",
+		Short:     "Try transformers interactively on the lines you type",
+		Long:      "Try transformers interactively on the lines you type.\n" + repl.Help,
+		Exclusive: [][]string{{"key-file", "passphrase"}},
+		Flags: func(fs *flag.FlagSet) {
+			fs.StringVar(&pipeline, "pipeline", "reverse", "Transformer or pipeline to start with")
+			fs.StringVar(&historyPath, "history", defaultHistoryPath(), "History file, none if empty, or $TRANSFORMER_HISTORY")
+			fs.StringVar(&keyFile, "key-file", "", "Key file for aes and hmac-sha256, or $TRANSFORMER_KEY_FILE")
+			fs.StringVar(&passphrase, "passphrase", "", "Passphrase to derive the key from with scrypt, or $TRANSFORMER_PASSPHRASE")
+		},
+		Run: func(args []string) error {
+			if len(args) > 0 {
+				return cli.Usagef("unexpected argument %q", args[0])
+			}
+			err := registerCLIKey(keyFile, passphrase)
+			if err != nil {
+				return err
+			}
+			session, err := repl.New(os.Stdout, pipeline)
+			if err != nil {
+				return cli.Usagef("%s", err)
+			}
+			if historyPath != "" {
+				if err := session.OpenHistory(historyPath); err != nil {
+					fmt.Fprintf(os.Stderr, "history is not kept: %s\n", err)
+				}
+				defer session.Close()
+			}
+			return session.Run(os.Stdin, isTerminal(os.Stdin))
+		},
+	}
+}
+
+func defaultHistoryPath() string {
+	if path, ok := os.LookupEnv("TRANSFORMER_HISTORY"); ok {
+		return path
+	}
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return ""
+	}
+	return filepath.Join(home, ".transformer_history")
+}
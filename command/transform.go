@@ -0,0 +1,189 @@
+package command
+
+import (
+	"flag"
+	"fmt"
+	"main/batch"
+	"main/cli"
+	"main/transformer"
+	"main/watch"
+	"os"
+	"strings"
+)
+
+// selectionFlags choose the transformation, reverse when none is given.
+var selectionFlags = []string{"pipeline", "codec", "compress", "encrypt", "hash", "caesar", "vigenere", "atbash", "substitution",
+	"rail-fence", "columnar", "playfair", "xor", "case", "whitespace", "normalize", "base64"}
+
+const transformHelp = `
+Transform the input, std.in by default, and write the output, std.out by default.
+Without a transformation option the input is reversed.
+
+Files, glob patterns or directories after the options are transformed one by one,
+each written to -output-dir, next to it with -suffix, or over it with -in-place:
+	./bin/main transform -caesar 3 -suffix .enc *.txt
+
+With -watch the -input file or directory is transformed again whenever it changes:
+	./bin/main transform -watch -input fixtures -output-dir build/fixtures -base64
+
+Pipeline steps take their arguments after a colon:
+	caesar:3,alphabet=ukrainian  base64:url,decode=true  vigenere:lemon  hex:upper  gzip:9  sha256:base64
+`
+
+// transformCommand builds the transform command, or the decode command that
+// applies the inverse of the transformation.
+func transformCommand(name string, decode bool) *cli.Command {
+	var config IoConfig
+	var opts transformer.Options
+	var keyFile, passphrase string
+	var parallel transformer.ParallelOptions
+	var progress bool
+	var batchConfig batch.Config
+	var watching bool
+	var watchOpts watch.Options
+
+	short := "Transform the input, reversing it if no other option is given"
+	var help strings.Builder
+	if decode {
+		short = "Apply the inverse of a transformation to recover the original input"
+		help.WriteString(short + ", the same as transform -decode.\n")
+	}
+	help.WriteString(transformHelp)
+	help.WriteString("\n")
+	printTransformers(&help)
+
+	return &cli.Command{
+		Name:  name,
+		Args:  "[FILES...]",
+		Short: short,
+		Long:  help.String(),
+		Exclusive: [][]string{
+			selectionFlags,
+			{"lines", "field", "select"},
+			{"key-file", "passphrase"},
+			{"in-place", "output-dir"},
+			{"in-place", "suffix"},
+			{"in-place", "watch"},
+		},
+		Flags: func(fs *flag.FlagSet) {
+			fs.StringVar(&config.FileIn, "input", "", "Path to file input, std.in if not set")
+			fs.StringVar(&config.FileOut, "output", "", "Path to file output, std.out if not set")
+			fs.StringVar(&opts.Pipeline, "pipeline", "", "Run several transformations in order, e.g. \"reverse | caesar:3 | base64\"")
+			fs.StringVar(&opts.Codec, "codec", "", "Encode with hex, base32, base58, ascii85, z85, percent or base64, the variant follows a colon: hex:upper")
+			fs.StringVar(&opts.Compress, "compress", "", "Compress with gzip or zlib, the level follows a colon: gzip:9")
+			fs.BoolVar(&opts.Encrypt, "encrypt", false, "Encrypt with AES-256-GCM, the output is binary")
+			fs.StringVar(&keyFile, "key-file", "", "Key file with 32 raw bytes or 64 hex digits for -encrypt and hmac-sha256, or $TRANSFORMER_KEY_FILE")
+			fs.StringVar(&passphrase, "passphrase", "", "Passphrase to derive the key from with scrypt, or $TRANSFORMER_PASSPHRASE")
+			fs.StringVar(&opts.Hash, "hash", "", "Replace the input with its digest: sha256, sha512, blake2b, hmac-sha256, sha1 or md5 (legacy), e.g. sha256:base64")
+			fs.IntVar(&opts.CaesarShift, "caesar", 0, "Run Caesar cipher with provided shift (not 0)")
+			fs.StringVar(&opts.CaesarOptions.Alphabet, "alphabet", transformer.DefaultCaesarAlphabet, "Alphabet of the letter ciphers: latin, ukrainian or the letters of a custom alphabet")
+			fs.BoolVar(&opts.CaesarOptions.RotateDigits, "caesar-digits", false, "Rotate digits 0-9 along with the letters")
+			fs.StringVar(&opts.Vigenere, "vigenere", "", "Run Vigenere cipher with provided keyword")
+			fs.BoolVar(&opts.Atbash, "atbash", false, "Run Atbash cipher")
+			fs.StringVar(&opts.Substitution, "substitution", "", "Run substitution cipher with provided keyword or keyed alphabet")
+			fs.IntVar(&opts.RailFence, "rail-fence", 0, "Run rail fence transposition with provided number of rails")
+			fs.StringVar(&opts.Columnar, "columnar", "", "Run columnar transposition with provided keyword")
+			fs.StringVar(&opts.Playfair, "playfair", "", "Run Playfair cipher with provided keyword or 25-letter key square")
+			fs.StringVar(&opts.Xor, "xor", "", "Run repeating-key XOR with hex output with provided key")
+			fs.StringVar(&opts.Case, "case", "", "Change case: upper, lower, title, snake, camel or kebab")
+			fs.StringVar(&opts.Whitespace, "whitespace", "", "Collapse whitespace into single spaces (collapse), trim the text (trim) or every line (trim_lines)")
+			fs.StringVar(&opts.Normalize, "normalize", "", "Unicode normalization: nfc, nfd, nfkc or nfkd")
+			fs.BoolVar(&opts.Base64, "base64", false, "Run Base64")
+			fs.BoolVar(&opts.Base64Decode, "base64-decode", false, "Decode Base64 input instead of encoding it")
+			fs.StringVar(&opts.Base64Variant, "base64-variant", "std", "Base64 alphabet: std, url, rawstd or rawurl (unpadded)")
+			fs.StringVar(&opts.ReverseUnit, "reverse-unit", transformer.ReverseRunes, "What the default reverse transformation reverses: runes, graphemes, words or lines")
+			fs.StringVar(&opts.Mode, "mode", transformer.ModeUTF8, "Input mode of the text ciphers: utf8 (rejects invalid UTF-8) or bytes (binary safe)")
+			fs.BoolVar(&opts.Lines, "lines", false, "Transform every line on its own, keeping the LF or CRLF line endings")
+			fs.IntVar(&opts.Field, "field", 0, "Transform only the Nth field (from 1) of every line of CSV or TSV input")
+			fs.StringVar(&opts.Delimiter, "delimiter", ",", "Field delimiter for -field: a character or tab")
+			fs.StringVar(&opts.Select, "select", "", "Transform only the string values a JSONPath selector picks: $.user.name, $.items[*].id, $..password")
+			fs.StringVar(&opts.Format, "format", transformer.FormatJSON, "Document format for -select: json or yaml")
+			fs.IntVar(&parallel.Workers, "workers", 0, "Workers for input files over 1 MiB that can be split, such as caesar, hex or -lines (default one per CPU)")
+			fs.BoolVar(&progress, "progress", isTerminal(os.Stderr), "Show the progress of those files on stderr (default when stderr is a terminal)")
+			fs.BoolVar(&batchConfig.Recursive, "recursive", false, "Transform every file below the directories given as inputs")
+			fs.StringVar(&batchConfig.OutputDir, "output-dir", "", "Write the outputs of several inputs to a mirrored tree in this directory")
+			fs.StringVar(&batchConfig.Suffix, "suffix", "", "Write the outputs of several inputs next to them with this suffix, e.g. .enc")
+			fs.BoolVar(&batchConfig.InPlace, "in-place", false, "Replace every input with its output, through a temporary file")
+			fs.BoolVar(&watching, "watch", false, "Keep running and transform -input again whenever it changes, a directory needs -output-dir or -suffix")
+			fs.DurationVar(&watchOpts.Debounce, "debounce", watch.DefaultDebounce, "How long -watch waits for the input to stay unchanged before transforming it")
+			if !decode {
+				fs.BoolVar(&decode, "decode", false, "Apply the inverse of the selected transformation, the same as the decode command")
+			}
+		},
+		Run: func(args []string) error {
+			err := registerCLIKey(keyFile, passphrase)
+			if err != nil {
+				return err
+			}
+			if opts.Base64Decode || opts.Base64Variant != "std" {
+				opts.Base64 = true
+			}
+			tr, err := transformer.NewTransformer(opts)
+			if err != nil {
+				return cli.Usagef("%s", err)
+			}
+			if decode {
+				tr, err = transformer.Inverse(tr)
+				if err != nil {
+					return cli.Usagef("%s", err)
+				}
+			}
+
+			if watching {
+				if len(args) > 0 {
+					return cli.Usagef("-watch transforms -input, not the files after the options")
+				}
+				return runWatch(config, batchConfig, tr, parallel, watchOpts)
+			}
+			if len(args) > 0 {
+				if config.FileIn != "" || config.FileOut != "" {
+					return cli.Usagef("-input and -output cannot be combined with several inputs, use -output-dir, -suffix or -in-place")
+				}
+				if err := batchConfig.Validate(); err != nil {
+					return cli.Usagef("%s", err)
+				}
+				summary := batch.Run(args, batchConfig, tr, parallel)
+				batch.PrintSummary(os.Stdout, summary)
+				return summary.Err()
+			}
+			if batchConfig != (batch.Config{}) {
+				return cli.Usagef("-recursive, -output-dir, -suffix and -in-place need files after the options")
+			}
+
+			in, size, err := config.openInput()
+			if err != nil {
+				return cli.IOError(err)
+			}
+			defer in.Close()
+			out, err := config.createOutput()
+			if err != nil {
+				return cli.IOError(err)
+			}
+			// the newline ending typed input is not part of it, binary input is kept intact
+			ioinput := config.Stdin() && opts.Mode != transformer.ModeBytes
+			if progress && size > transformer.DefaultChunkSize {
+				parallel.Progress = printProgress(size)
+			}
+			err = transformer.RunTransformSize(in, out, tr, size, ioinput, parallel)
+			if closeErr := out.Close(); err == nil && closeErr != nil {
+				return cli.IOError(closeErr)
+			}
+			if err != nil {
+				return errorCode(err)
+			}
+			return nil
+		},
+	}
+}
+
+// printProgress returns a progress callback that keeps one line on stderr
+// up to date.
+func printProgress(total int64) func(done int64) {
+	const mib = 1 << 20
+	return func(done int64) {
+		fmt.Fprintf(os.Stderr, "\r%.1f / %.1f MiB (%d%%)", float64(done)/mib, float64(total)/mib, done*100/total)
+		if done >= total {
+			fmt.Fprintln(os.Stderr)
+		}
+	}
+}
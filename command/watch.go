@@ -0,0 +1,139 @@
+package command
+
+import (
+	"context"
+	"log"
+	"main/batch"
+	"main/cli"
+	"main/transformer"
+	"main/watch"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"strings"
+	"syscall"
+	"time"
+)
+
+// runWatch transforms the input, and again whenever it changes, until it is
+// interrupted. A directory, or a file with -output-dir or -suffix, is
+// transformed like a batch, the files that changed only; a single file is
+// written to the output.
+func runWatch(config IoConfig, cfg batch.Config, tr transformer.StreamTransformer, parallel transformer.ParallelOptions, opts watch.Options) error {
+	if config.Stdin() {
+		return cli.Usagef("-watch needs -input, a file or a directory")
+	}
+	info, err := os.Stat(config.FileIn)
+	if err != nil {
+		return cli.IOError(err)
+	}
+	batchMode := info.IsDir() || cfg.OutputDir != "" || cfg.Suffix != ""
+	switch {
+	case batchMode && config.FileOut != "":
+		return cli.Usagef("-output takes a single file, use -output-dir or -suffix to watch a directory")
+	case batchMode && cfg.OutputDir == "" && cfg.Suffix == "":
+		return cli.Usagef("-watch of a directory needs -output-dir or -suffix")
+	case !batchMode && samePath(config.FileIn, config.FileOut):
+		return cli.Usagef("-output cannot be the watched input")
+	}
+
+	var run func(changed []string)
+	if batchMode {
+		cfg.Recursive = true
+		opts.Ignore = cfg.IsOutput
+		run = func(changed []string) {
+			var summary batch.Summary
+			files := cfg.Expand([]string{config.FileIn}, &summary)
+			if changed != nil {
+				files = changedFiles(files, changed)
+			}
+			if len(files) == 0 && len(summary.Failed) == 0 {
+				return
+			}
+			start := time.Now()
+			cfg.TransformFiles(files, tr, parallel, &summary)
+			for _, line := range summary.Processed {
+				log.Printf("processed %s", line)
+			}
+			for _, line := range summary.Failed {
+				log.Printf("failed %s", line)
+			}
+			log.Printf("%d processed, %d failed in %s", len(summary.Processed), len(summary.Failed), time.Since(start).Round(time.Millisecond))
+		}
+	} else {
+		run = func([]string) {
+			start := time.Now()
+			err := transformToOutput(config, tr, parallel)
+			if err != nil {
+				log.Printf("failed %s: %s", config.FileIn, err)
+				return
+			}
+			out := config.FileOut
+			if out == "" {
+				out = "std.out"
+			}
+			log.Printf("processed %s -> %s in %s", config.FileIn, out, time.Since(start).Round(time.Millisecond))
+		}
+	}
+
+	w, err := watch.New(config.FileIn, opts)
+	if err != nil {
+		return cli.IOError(err)
+	}
+	defer w.Close()
+	log.Printf("watching %s with %s, stop with Ctrl+C", config.FileIn, w.Backend)
+	run(nil)
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case changed := <-w.Changes():
+			run(changed)
+		case err := <-w.Errors():
+			log.Print(err)
+		}
+	}
+}
+
+// transformToOutput transforms a single input file, through a temporary file
+// unless the output is std.out.
+func transformToOutput(config IoConfig, tr transformer.StreamTransformer, parallel transformer.ParallelOptions) error {
+	if config.FileOut != "" && config.FileOut != "-" {
+		return batch.TransformFile(config.FileIn, config.FileOut, tr, parallel)
+	}
+	in, size, err := config.openInput()
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+	return transformer.RunTransformSize(in, os.Stdout, tr, size, false, parallel)
+}
+
+// changedFiles keeps the files that changed or are below a directory that
+// changed.
+func changedFiles(files []batch.File, changed []string) []batch.File {
+	var kept []batch.File
+	for _, f := range files {
+		path, _ := filepath.Abs(f.Path)
+		for _, c := range changed {
+			c, _ = filepath.Abs(c)
+			if path == c || strings.HasPrefix(path, c+string(filepath.Separator)) {
+				kept = append(kept, f)
+				break
+			}
+		}
+	}
+	return kept
+}
+
+func samePath(a, b string) bool {
+	if a == "" || b == "" {
+		return false
+	}
+	a, _ = filepath.Abs(a)
+	b, _ = filepath.Abs(b)
+	return a == b
+}
@@ -1,14 +1,19 @@
 package crud_handler
 
 import (
+	"database/sql"
 	"encoding/json"
-	"log"
+	"errors"
+	"fmt"
+	"main/analysis"
 	"main/repo"
 	"main/transformer"
 	"net/http"
 	"sort"
+	"strconv"
 	"strings"
 	"time"
+	"unicode/utf8"
 
 	"github.com/go-chi/chi/v5"
 	"github.com/go-chi/chi/v5/middleware"
@@ -34,30 +39,113 @@ func NewHandler(db DBLayer) *Handler {
 }
 
 type TransformRequest struct {
-	Type        string `json:"type"`
-	CaesarShift int    `json:"shift,omitempty"`
-	Input       string `json:"input,omitempty"`
+	Type         string `json:"type"`
+	CaesarShift  int    `json:"shift,omitempty"`
+	Alphabet     string `json:"alphabet,omitempty"`
+	RotateDigits bool   `json:"rotate_digits,omitempty"`
+	Input        string `json:"input,omitempty"`
+	Decode       bool   `json:"decode,omitempty"`
+	Variant      string `json:"variant,omitempty"`
+	Key          string `json:"key,omitempty"`
+	Rails        int    `json:"rails,omitempty"`
+	// KeyID names a key loaded by the server for aes, keys themselves are
+	// never part of a request.
+	KeyID string `json:"key_id,omitempty"`
+	// Level is the compression level of gzip and zlib, the default level
+	// is filled in so that records show the level used.
+	Level *int `json:"level,omitempty"`
+	// Mode is the input mode of text transformers, utf8 or bytes. Pipeline
+	// steps without their own mode inherit it.
+	Mode string `json:"mode,omitempty"`
+	// Lines transforms every line of the input on its own, Field only the
+	// given field, counted from 1, of every CSV or TSV line.
+	Lines     bool   `json:"lines,omitempty"`
+	Field     int    `json:"field,omitempty"`
+	Delimiter string `json:"delimiter,omitempty"`
+	// Select transforms only the values of the JSON or YAML input, see
+	// Format, picked by a selector such as $.user.name.
+	Select string `json:"select,omitempty"`
+	Format string `json:"format,omitempty"`
+	// Params holds transformer parameters that have no dedicated field,
+	// see GET /transformers.
+	Params map[string]string `json:"params,omitempty"`
+	// Steps are the transformations of a "pipeline" request, applied in order.
+	Steps []TransformRequest `json:"steps,omitempty"`
 }
 
-func (h *Handler) RunServer() {
+// params merges the dedicated request fields into the generic parameters of
+// the transformer.
+func (request *TransformRequest) params() transformer.Params {
+	p := transformer.Params{}
+	for k, v := range request.Params {
+		p[k] = v
+	}
+	if request.CaesarShift != 0 {
+		p["shift"] = strconv.Itoa(request.CaesarShift)
+	}
+	if request.Alphabet != "" {
+		p["alphabet"] = request.Alphabet
+	}
+	if request.RotateDigits {
+		p["rotate_digits"] = "true"
+	}
+	if request.Variant != "" {
+		p["variant"] = request.Variant
+	}
+	if request.Key != "" {
+		p["key"] = request.Key
+	}
+	if request.Decode {
+		p["decode"] = "true"
+	}
+	if request.Rails != 0 {
+		p["rails"] = strconv.Itoa(request.Rails)
+	}
+	if request.KeyID != "" {
+		p["key_id"] = request.KeyID
+	}
+	if request.Level != nil {
+		p["level"] = strconv.Itoa(*request.Level)
+	}
+	if spec, ok := transformer.Lookup(request.Type); ok && spec.Text && request.Mode != "" {
+		p["mode"] = request.Mode
+	}
+	return p
+}
+
+// step returns the i-th pipeline step, inheriting the mode of the pipeline.
+func (request *TransformRequest) step(i int) *TransformRequest {
+	step := &request.Steps[i]
+	if step.Mode == "" {
+		step.Mode = request.Mode
+	}
+	return step
+}
+
+// RunServer serves the API on addr, such as ":8080", until it fails.
+func (h *Handler) RunServer(addr string) error {
 	router := chi.NewRouter()
 	router.Use(SetJSONContentType)
 	router.Use(middleware.Logger)
 	router.Post("/records", h.NewRecord)
 	router.Get("/records", h.GetAllRecords)
 	router.Get("/records/{id}", h.GetRecord)
+	router.Get("/records/{id}/original", h.GetOriginal)
 	router.Delete("/records/{id}", h.DeleteRecord)
 	router.Put("/records/{id}", h.UpdateRecord)
+	router.Get("/transformers", h.GetTransformers)
+	router.Post("/crack", h.Crack)
 
 	server := &http.Server{
-		Addr:              ":8080",
+		Addr:              addr,
 		Handler:           router,
 		ReadHeaderTimeout: 3 * time.Second,
 	}
 	err := server.ListenAndServe()
 	if err != nil {
-		log.Fatal("server listenig error")
+		return fmt.Errorf("server listening error: %w", err)
 	}
+	return nil
 }
 
 func SetJSONContentType(h http.Handler) http.Handler {
@@ -68,18 +156,242 @@ func SetJSONContentType(h http.Handler) http.Handler {
 }
 
 func CheckValidRequest(request *TransformRequest) string {
-	if request.Type != "reverse" && request.Type != "caesar" && request.Type != "base64" {
-		return "expected tranformation type field: reverse/caesar/base64"
-	}
-	if request.Type == "caesar" && request.CaesarShift == 0 {
-		return "expected shift field (not 0)"
+	invalid := checkValidStep(request)
+	if invalid != "" {
+		return invalid
 	}
 	if request.Input == "" {
 		return "expected input field"
 	}
+	if _, err := newTransformer(request); err != nil {
+		return err.Error()
+	}
+	if name := binaryStep(request); name != "" {
+		return fmt.Sprintf("the output of %s is binary and cannot be stored, chain it into base64 or hex", name)
+	}
+	return ""
+}
+
+// binaryStep returns the transformation whose binary output would be the
+// result of the request: the last step, or the first one of a decoding
+// pipeline, which runs backwards.
+func binaryStep(request *TransformRequest) string {
+	last, decode := request, false
+	if request.Type == "pipeline" {
+		if len(request.Steps) == 0 {
+			return ""
+		}
+		last = &request.Steps[len(request.Steps)-1]
+		if request.Decode {
+			last, decode = &request.Steps[0], true
+		}
+	}
+	spec, ok := transformer.Lookup(last.Type)
+	stepDecode, _ := last.params().Bool("decode")
+	if ok && spec.BinaryOutput && stepDecode == decode {
+		return last.Type
+	}
+	return ""
+}
+
+func checkValidStep(request *TransformRequest) string {
+	if request.Type == "pipeline" {
+		if len(request.Steps) == 0 {
+			return "expected steps field"
+		}
+		params := request.params()
+		delete(params, "decode")
+		if len(params) > 0 {
+			keys := make([]string, 0, len(params))
+			for k := range params {
+				keys = append(keys, k)
+			}
+			sort.Strings(keys)
+			return "parameters of a pipeline belong to its steps, got " + strings.Join(keys, ", ")
+		}
+		for i := range request.Steps {
+			step := request.step(i)
+			if step.Type == "pipeline" {
+				return fmt.Sprintf("step %d: pipelines cannot be nested", i+1)
+			}
+			if invalid := checkValidStep(step); invalid != "" {
+				return fmt.Sprintf("step %d: %s", i+1, invalid)
+			}
+		}
+		return ""
+	}
+	spec, ok := transformer.Lookup(request.Type)
+	if !ok {
+		return "expected tranformation type field: " + strings.Join(append(transformer.Names(), "pipeline"), "/")
+	}
+	if isCompression(request.Type) && request.params()["level"] == "" {
+		level := transformer.DefaultCompressionLevel
+		request.Level = &level
+	}
+	err := spec.Validate(request.params())
+	if err != nil {
+		return err.Error()
+	}
 	return ""
 }
 
+func newTransformer(request *TransformRequest) (transformer.StreamTransformer, error) {
+	tr, err := newStepTransformer(request)
+	if err != nil {
+		return nil, err
+	}
+	if request.Select != "" {
+		if request.Lines || request.Field != 0 {
+			return nil, errors.New("select cannot be combined with lines or field")
+		}
+		return transformer.Select(tr, request.Select, request.Format)
+	}
+	if request.Field != 0 {
+		delimiter, err := transformer.ParseDelimiter(request.Delimiter)
+		if err != nil {
+			return nil, err
+		}
+		return transformer.Field(tr, request.Field, delimiter)
+	}
+	if request.Lines {
+		return transformer.Lines(tr), nil
+	}
+	return tr, nil
+}
+
+func newStepTransformer(request *TransformRequest) (transformer.StreamTransformer, error) {
+	if request.Type != "pipeline" {
+		return transformer.New(request.Type, request.params())
+	}
+	p := make(transformer.Pipeline, 0, len(request.Steps))
+	for i := range request.Steps {
+		step, err := newTransformer(request.step(i))
+		if err != nil {
+			return nil, fmt.Errorf("step %d: %w", i+1, err)
+		}
+		p = append(p, step)
+	}
+	if len(p) == 0 {
+		return nil, fmt.Errorf("pipeline has no steps")
+	}
+	if request.Decode {
+		return p.Inverse()
+	}
+	return p, nil
+}
+
+// recordParams collects the request parameters that are stored on the record
+// next to the Caesar shift.
+func recordParams(request *TransformRequest) repo.Params {
+	params := repo.Params(request.params())
+	delete(params, "shift")
+	if len(request.Steps) > 0 {
+		steps, _ := json.Marshal(request.Steps)
+		params["steps"] = string(steps)
+	}
+	if request.Lines {
+		params["lines"] = "true"
+	}
+	if request.Field != 0 {
+		params["field"] = strconv.Itoa(request.Field)
+	}
+	if request.Delimiter != "" {
+		params["delimiter"] = request.Delimiter
+	}
+	if request.Select != "" {
+		params["select"] = request.Select
+	}
+	if request.Format != "" {
+		params["format"] = request.Format
+	}
+	if len(params) == 0 {
+		return nil
+	}
+	return params
+}
+
+// requestFromRecord rebuilds the request that produced the record, without
+// its input.
+func requestFromRecord(record repo.Record) *TransformRequest {
+	request := &TransformRequest{
+		Type:        record.Type,
+		CaesarShift: record.CaesarShift,
+	}
+	for k, v := range record.Params {
+		switch k {
+		case "steps":
+			_ = json.Unmarshal([]byte(v), &request.Steps)
+			continue
+		case "decode":
+			request.Decode = v == "true"
+			continue
+		case "lines":
+			request.Lines = v == "true"
+			continue
+		case "field":
+			request.Field, _ = strconv.Atoi(v)
+			continue
+		case "delimiter":
+			request.Delimiter = v
+			continue
+		case "select":
+			request.Select = v
+			continue
+		case "format":
+			request.Format = v
+			continue
+		}
+		if request.Params == nil {
+			request.Params = map[string]string{}
+		}
+		request.Params[k] = v
+	}
+	return request
+}
+
+func isCompression(name string) bool {
+	for _, c := range transformer.Compressions {
+		if c == name {
+			return true
+		}
+	}
+	return false
+}
+
+// transformRecord runs the request and stores the result on the record,
+// along with the sizes before and after compression if the request
+// compresses.
+func transformRecord(record *repo.Record, request *TransformRequest) error {
+	tr, err := newTransformer(request)
+	if err != nil {
+		return err
+	}
+	var sb strings.Builder
+	stats, _, err := transformer.TransformStreamStats(tr, strings.NewReader(request.Input), &sb)
+	if err != nil {
+		return err
+	}
+	// records are stored as text, other binary results such as decoded
+	// Base64 are caught here
+	if !utf8.ValidString(sb.String()) || strings.ContainsRune(sb.String(), 0) {
+		return errBinaryResult
+	}
+	record.Result = sb.String()
+	record.OriginalSize, record.CompressedSize = stats.Original, stats.Compressed
+	return nil
+}
+
+var errBinaryResult = errors.New("the result is binary and cannot be stored, chain the transformation into base64 or hex")
+
+func writeTransformError(w http.ResponseWriter, err error) {
+	var malformed *transformer.MalformedInputError
+	if errors.As(err, &malformed) || errors.Is(err, errBinaryResult) {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	http.Error(w, "Server Transformer error", http.StatusInternalServerError)
+}
+
 func (h *Handler) NewRecord(w http.ResponseWriter, r *http.Request) {
 	request := new(TransformRequest)
 	dec := json.NewDecoder(r.Body)
@@ -93,24 +405,14 @@ func (h *Handler) NewRecord(w http.ResponseWriter, r *http.Request) {
 		http.Error(w, invalid, http.StatusBadRequest)
 		return
 	}
-	w.WriteHeader(http.StatusCreated)
-
 	result := new(repo.Record)
 	result.ID = uuid.NewString()
 	result.Type = request.Type
-	result.CaesarShift = request.CaesarShift
-	var tr transformer.Transformer
-	switch {
-	case request.Type == "reverse":
-		tr = transformer.NewReverseTransformer()
-	case request.Type == "caesar":
-		tr = transformer.NewCaesarTransformer(request.CaesarShift)
-	case request.Type == "base64":
-		tr = transformer.NewBase64Transformer()
-	}
-	result.Result, err = tr.Transform(strings.NewReader(request.Input), false)
+	result.CaesarShift, _ = request.params().Int("shift")
+	result.Params = recordParams(request)
+	err = transformRecord(result, request)
 	if err != nil {
-		http.Error(w, "Server Transformer error", http.StatusInternalServerError)
+		writeTransformError(w, err)
 		return
 	}
 	result.CreatedAt = time.Now().Unix()
@@ -120,6 +422,7 @@ func (h *Handler) NewRecord(w http.ResponseWriter, r *http.Request) {
 		http.Error(w, err.Error(), http.StatusInternalServerError)
 		return
 	}
+	w.WriteHeader(http.StatusCreated)
 
 	enc := json.NewEncoder(w)
 	err = enc.Encode(result)
@@ -141,14 +444,23 @@ func (h *Handler) DeleteRecord(w http.ResponseWriter, r *http.Request) {
 }
 
 func (h *Handler) GetAllRecords(w http.ResponseWriter, r *http.Request) {
+	filter, err := ParseRecordFilter(r.URL.Query())
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
 	values, err := h.db.GetRecords()
 	if err != nil {
 		http.Error(w, err.Error(), http.StatusInternalServerError)
 		return
 	}
-	sort.Slice(values, func(i, j int) bool {
+	// stable, so that records of the same second keep the database order
+	// from page to page
+	sort.SliceStable(values, func(i, j int) bool {
 		return values[i].CreatedAt > values[j].CreatedAt
 	})
+	values, total := filter.Apply(values)
+	w.Header().Set(TotalCountHeader, strconv.Itoa(total))
 	enc := json.NewEncoder(w)
 	err = enc.Encode(values)
 	if err != nil {
@@ -162,7 +474,7 @@ func (h *Handler) GetRecord(w http.ResponseWriter, r *http.Request) {
 	// fmt.Printf("id = %s \n", id)
 	result, err := h.db.GetRecord(id)
 	if err != nil {
-		http.Error(w, err.Error(), http.StatusBadRequest)
+		http.Error(w, err.Error(), recordErrorStatus(err))
 		return
 	}
 
@@ -174,6 +486,92 @@ func (h *Handler) GetRecord(w http.ResponseWriter, r *http.Request) {
 	}
 }
 
+// recordErrorStatus is not found for a missing record, bad request for
+// other errors such as an id that is not a UUID.
+func recordErrorStatus(err error) int {
+	if errors.Is(err, sql.ErrNoRows) {
+		return http.StatusNotFound
+	}
+	return http.StatusBadRequest
+}
+
+func (h *Handler) GetOriginal(w http.ResponseWriter, r *http.Request) {
+	id := chi.URLParam(r, "id")
+	record, err := h.db.GetRecord(id)
+	if err != nil {
+		http.Error(w, err.Error(), recordErrorStatus(err))
+		return
+	}
+
+	original := requestFromRecord(record)
+	tr, err := newTransformer(original)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	tr, err = transformer.Inverse(tr)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusUnprocessableEntity)
+		return
+	}
+	var sb strings.Builder
+	err = tr.TransformStream(strings.NewReader(record.Result), &sb)
+	if err != nil {
+		writeTransformError(w, err)
+		return
+	}
+	original.Input = sb.String()
+
+	enc := json.NewEncoder(w)
+	err = enc.Encode(original)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+}
+
+func (h *Handler) GetTransformers(w http.ResponseWriter, r *http.Request) {
+	enc := json.NewEncoder(w)
+	err := enc.Encode(transformer.Specs())
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+}
+
+// CrackRequest asks for the likely shifts of Caesar ciphertext.
+type CrackRequest struct {
+	Input         string   `json:"input"`
+	Languages     []string `json:"languages,omitempty"`
+	Candidates    int      `json:"candidates,omitempty"`
+	PreviewLength int      `json:"preview_length,omitempty"`
+}
+
+func (h *Handler) Crack(w http.ResponseWriter, r *http.Request) {
+	request := new(CrackRequest)
+	err := json.NewDecoder(r.Body).Decode(request)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	candidates, err := analysis.CrackCaesar(strings.NewReader(request.Input), analysis.CrackOptions{
+		Languages:     request.Languages,
+		Candidates:    request.Candidates,
+		PreviewLength: request.PreviewLength,
+	})
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	enc := json.NewEncoder(w)
+	err = enc.Encode(candidates)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+}
+
 func (h *Handler) UpdateRecord(w http.ResponseWriter, r *http.Request) {
 	id := chi.URLParam(r, "id")
 	request := new(TransformRequest)
@@ -189,25 +587,19 @@ func (h *Handler) UpdateRecord(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	var tr transformer.Transformer
-	switch {
-	case request.Type == "reverse":
-		tr = transformer.NewReverseTransformer()
-	case request.Type == "caesar":
-		tr = transformer.NewCaesarTransformer(request.CaesarShift)
-	case request.Type == "base64":
-		tr = transformer.NewBase64Transformer()
-	}
-	transform_result, err := tr.Transform(strings.NewReader(request.Input), false)
+	transformed := new(repo.Record)
+	err = transformRecord(transformed, request)
 	if err != nil {
-		http.Error(w, "Server Transformer error", http.StatusInternalServerError)
+		writeTransformError(w, err)
 		return
 	}
 
 	result, err := h.db.GetRecord(id)
 	result.Type = request.Type
-	result.CaesarShift = request.CaesarShift
-	result.Result = transform_result
+	result.CaesarShift, _ = request.params().Int("shift")
+	result.Params = recordParams(request)
+	result.Result = transformed.Result
+	result.OriginalSize, result.CompressedSize = transformed.OriginalSize, transformed.CompressedSize
 	result.UpdatedAt = time.Now().Unix()
 	if err != nil {
 		result.ID = id
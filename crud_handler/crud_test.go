@@ -6,7 +6,9 @@ import (
 	"strings"
 	"time"
 
+	"main/analysis"
 	"main/repo"
+	"main/transformer"
 	"net/http"
 	"net/http/httptest"
 	"testing"
@@ -217,3 +219,758 @@ func Test_DeleteRecord(t *testing.T) {
 	}
 
 }
+
+func Test_NewRecordBase64Decode(t *testing.T) {
+	db := new(MockDB)
+	h := NewHandler(db)
+
+	req, err := http.NewRequest("POST", "/records", strings.NewReader(`{"type":"base64", "input":"TWE", "decode":true, "variant":"rawurl"}`))
+	if err != nil {
+		t.Fatalf("failed to create request: %s", err)
+	}
+	rr := httptest.NewRecorder()
+	http.HandlerFunc(h.NewRecord).ServeHTTP(rr, req)
+	if rr.Code != http.StatusCreated {
+		t.Errorf("handler returned wrong status code: got %v want %v", rr.Code, http.StatusCreated)
+	}
+	res := new(repo.Record)
+	err = json.NewDecoder(rr.Body).Decode(&res)
+	if err != nil {
+		t.Errorf("decoding error")
+	}
+	assert.Equal(t, "Ma", res.Result)
+
+	req, err = http.NewRequest("POST", "/records", strings.NewReader(`{"type":"base64", "input":"TW*u", "decode":true}`))
+	if err != nil {
+		t.Fatalf("failed to create request: %s", err)
+	}
+	rr = httptest.NewRecorder()
+	http.HandlerFunc(h.NewRecord).ServeHTTP(rr, req)
+	if rr.Code != http.StatusBadRequest {
+		t.Errorf("handler returned wrong status code: got %v want %v", rr.Code, http.StatusBadRequest)
+	}
+	assert.Contains(t, rr.Body.String(), "byte offset 2")
+}
+
+func Test_GetOriginal(t *testing.T) {
+	db := new(MockDB)
+	h := NewHandler(db)
+
+	req, err := http.NewRequest("GET", "/records/1111/original", nil)
+	if err != nil {
+		t.Fatalf("failed to create request: %s", err)
+	}
+	rr := httptest.NewRecorder()
+	http.HandlerFunc(h.GetOriginal).ServeHTTP(rr, req)
+	if rr.Code != http.StatusOK {
+		t.Errorf("handler returned wrong status code: got %v want %v", rr.Code, http.StatusOK)
+	}
+
+	result := TransformRequest{}
+	err = json.NewDecoder(rr.Body).Decode(&result)
+	if err != nil {
+		t.Errorf("decoding error")
+	}
+	assert.Equal(t, TransformRequest{Type: "reverse", Input: "12345"}, result)
+}
+
+func Test_NewRecordCipherParams(t *testing.T) {
+	db := new(MockDB)
+	h := NewHandler(db)
+
+	req, err := http.NewRequest("POST", "/records", strings.NewReader(`{"type":"vigenere", "input":"attackatdawn", "key":"lemon"}`))
+	if err != nil {
+		t.Fatalf("failed to create request: %s", err)
+	}
+	rr := httptest.NewRecorder()
+	http.HandlerFunc(h.NewRecord).ServeHTTP(rr, req)
+	if rr.Code != http.StatusCreated {
+		t.Errorf("handler returned wrong status code: got %v want %v", rr.Code, http.StatusCreated)
+	}
+	res := new(repo.Record)
+	err = json.NewDecoder(rr.Body).Decode(&res)
+	if err != nil {
+		t.Errorf("decoding error")
+	}
+	assert.Equal(t, "lxfopvefrnhr", res.Result)
+	assert.Equal(t, repo.Params{"key": "lemon"}, res.Params)
+
+	req, err = http.NewRequest("POST", "/records", strings.NewReader(`{"type":"substitution", "input":"abc"}`))
+	if err != nil {
+		t.Fatalf("failed to create request: %s", err)
+	}
+	rr = httptest.NewRecorder()
+	http.HandlerFunc(h.NewRecord).ServeHTTP(rr, req)
+	if rr.Code != http.StatusBadRequest {
+		t.Errorf("handler returned wrong status code: got %v want %v", rr.Code, http.StatusBadRequest)
+	}
+}
+
+func Test_NewRecordPipeline(t *testing.T) {
+	db := new(MockDB)
+	h := NewHandler(db)
+
+	body := `{"type":"pipeline", "input":"abc", "steps":[{"type":"reverse"}, {"type":"caesar", "shift":3}, {"type":"base64"}]}`
+	req, err := http.NewRequest("POST", "/records", strings.NewReader(body))
+	if err != nil {
+		t.Fatalf("failed to create request: %s", err)
+	}
+	rr := httptest.NewRecorder()
+	http.HandlerFunc(h.NewRecord).ServeHTTP(rr, req)
+	if rr.Code != http.StatusCreated {
+		t.Errorf("handler returned wrong status code: got %v want %v", rr.Code, http.StatusCreated)
+	}
+	res := new(repo.Record)
+	err = json.NewDecoder(rr.Body).Decode(&res)
+	if err != nil {
+		t.Errorf("decoding error")
+	}
+	assert.Equal(t, "ZmVk", res.Result)
+
+	replay := requestFromRecord(*res)
+	replay.Input = "abc"
+	replayed := new(repo.Record)
+	err = transformRecord(replayed, replay)
+	assert.Nil(t, err)
+	assert.Equal(t, "ZmVk", replayed.Result)
+
+	body = `{"type":"pipeline", "input":"abc", "steps":[{"type":"reverse"}, {"type":"caesar"}]}`
+	req, err = http.NewRequest("POST", "/records", strings.NewReader(body))
+	if err != nil {
+		t.Fatalf("failed to create request: %s", err)
+	}
+	rr = httptest.NewRecorder()
+	http.HandlerFunc(h.NewRecord).ServeHTTP(rr, req)
+	if rr.Code != http.StatusBadRequest {
+		t.Errorf("handler returned wrong status code: got %v want %v", rr.Code, http.StatusBadRequest)
+	}
+	assert.Contains(t, rr.Body.String(), "step 2")
+
+	body = `{"type":"pipeline", "input":"abc", "shift":3, "steps":[{"type":"caesar", "shift":1}]}`
+	req, err = http.NewRequest("POST", "/records", strings.NewReader(body))
+	if err != nil {
+		t.Fatalf("failed to create request: %s", err)
+	}
+	rr = httptest.NewRecorder()
+	http.HandlerFunc(h.NewRecord).ServeHTTP(rr, req)
+	if rr.Code != http.StatusBadRequest {
+		t.Errorf("handler returned wrong status code: got %v want %v", rr.Code, http.StatusBadRequest)
+	}
+	assert.Contains(t, rr.Body.String(), "belong to its steps, got shift")
+}
+
+func Test_NewRecordDecodedPipeline(t *testing.T) {
+	db := new(MockDB)
+	h := NewHandler(db)
+
+	body := `{"type":"pipeline", "input":"Y2Jh", "decode":true, "steps":[{"type":"reverse"}, {"type":"base64"}]}`
+	req, err := http.NewRequest("POST", "/records", strings.NewReader(body))
+	if err != nil {
+		t.Fatalf("failed to create request: %s", err)
+	}
+	rr := httptest.NewRecorder()
+	http.HandlerFunc(h.NewRecord).ServeHTTP(rr, req)
+	if rr.Code != http.StatusCreated {
+		t.Errorf("handler returned wrong status code: got %v want %v", rr.Code, http.StatusCreated)
+	}
+	res := new(repo.Record)
+	err = json.NewDecoder(rr.Body).Decode(&res)
+	if err != nil {
+		t.Errorf("decoding error")
+	}
+	assert.Equal(t, "abc", res.Result)
+
+	// the record replays in the decoding direction, and its inverse, as
+	// used by GET /records/{id}/original, recovers the input
+	replay := requestFromRecord(*res)
+	assert.True(t, replay.Decode)
+	assert.Nil(t, replay.Params)
+	replay.Input = "Y2Jh"
+	replayed := new(repo.Record)
+	err = transformRecord(replayed, replay)
+	assert.Nil(t, err)
+	assert.Equal(t, "abc", replayed.Result)
+
+	tr, err := newTransformer(replay)
+	assert.Nil(t, err)
+	tr, err = transformer.Inverse(tr)
+	assert.Nil(t, err)
+	var original strings.Builder
+	err = tr.TransformStream(strings.NewReader(res.Result), &original)
+	assert.Nil(t, err)
+	assert.Equal(t, "Y2Jh", original.String())
+}
+
+func Test_GetTransformers(t *testing.T) {
+	db := new(MockDB)
+	h := NewHandler(db)
+
+	req, err := http.NewRequest("GET", "/transformers", nil)
+	if err != nil {
+		t.Fatalf("failed to create request: %s", err)
+	}
+	rr := httptest.NewRecorder()
+	http.HandlerFunc(h.GetTransformers).ServeHTTP(rr, req)
+	if rr.Code != http.StatusOK {
+		t.Errorf("handler returned wrong status code: got %v want %v", rr.Code, http.StatusOK)
+	}
+
+	var specs []struct {
+		Name   string `json:"name"`
+		Params []struct {
+			Name string `json:"name"`
+		} `json:"params"`
+	}
+	err = json.NewDecoder(rr.Body).Decode(&specs)
+	if err != nil {
+		t.Errorf("decoding error")
+	}
+	names := []string{}
+	for _, spec := range specs {
+		names = append(names, spec.Name)
+	}
+	assert.Contains(t, names, "caesar")
+	assert.Contains(t, names, "vigenere")
+}
+
+func Test_NewRecordGenericParams(t *testing.T) {
+	db := new(MockDB)
+	h := NewHandler(db)
+
+	req, err := http.NewRequest("POST", "/records", strings.NewReader(`{"type":"caesar", "input":"Zab", "params":{"shift":"1"}}`))
+	if err != nil {
+		t.Fatalf("failed to create request: %s", err)
+	}
+	rr := httptest.NewRecorder()
+	http.HandlerFunc(h.NewRecord).ServeHTTP(rr, req)
+	if rr.Code != http.StatusCreated {
+		t.Errorf("handler returned wrong status code: got %v want %v", rr.Code, http.StatusCreated)
+	}
+	res := new(repo.Record)
+	err = json.NewDecoder(rr.Body).Decode(&res)
+	if err != nil {
+		t.Errorf("decoding error")
+	}
+	assert.Equal(t, "Abc", res.Result)
+	assert.Equal(t, 1, res.CaesarShift)
+
+	req, err = http.NewRequest("POST", "/records", strings.NewReader(`{"type":"caesar", "input":"abc", "shift":1, "params":{"color":"red"}}`))
+	if err != nil {
+		t.Fatalf("failed to create request: %s", err)
+	}
+	rr = httptest.NewRecorder()
+	http.HandlerFunc(h.NewRecord).ServeHTTP(rr, req)
+	if rr.Code != http.StatusBadRequest {
+		t.Errorf("handler returned wrong status code: got %v want %v", rr.Code, http.StatusBadRequest)
+	}
+}
+
+func Test_NewRecordMode(t *testing.T) {
+	db := new(MockDB)
+	h := NewHandler(db)
+
+	body := `{"type":"pipeline", "input":"/2Fi", "mode":"bytes", "steps":[{"type":"base64", "decode":true}, {"type":"caesar", "shift":1}, {"type":"base64"}]}`
+	req, err := http.NewRequest("POST", "/records", strings.NewReader(body))
+	if err != nil {
+		t.Fatalf("failed to create request: %s", err)
+	}
+	rr := httptest.NewRecorder()
+	http.HandlerFunc(h.NewRecord).ServeHTTP(rr, req)
+	if rr.Code != http.StatusCreated {
+		t.Errorf("handler returned wrong status code: got %v want %v", rr.Code, http.StatusCreated)
+	}
+	res := new(repo.Record)
+	err = json.NewDecoder(rr.Body).Decode(&res)
+	if err != nil {
+		t.Errorf("decoding error")
+	}
+	assert.Equal(t, "/2Jj", res.Result)
+
+	body = `{"type":"pipeline", "input":"/2Fi", "steps":[{"type":"base64", "decode":true}, {"type":"caesar", "shift":1}, {"type":"base64"}]}`
+	req, err = http.NewRequest("POST", "/records", strings.NewReader(body))
+	if err != nil {
+		t.Fatalf("failed to create request: %s", err)
+	}
+	rr = httptest.NewRecorder()
+	http.HandlerFunc(h.NewRecord).ServeHTTP(rr, req)
+	if rr.Code != http.StatusBadRequest {
+		t.Errorf("handler returned wrong status code: got %v want %v", rr.Code, http.StatusBadRequest)
+	}
+	assert.Contains(t, rr.Body.String(), "UTF-8")
+}
+
+func Test_NewRecordCodec(t *testing.T) {
+	db := new(MockDB)
+	h := NewHandler(db)
+
+	req, err := http.NewRequest("POST", "/records", strings.NewReader(`{"type":"base32", "input":"foobar", "variant":"hex"}`))
+	if err != nil {
+		t.Fatalf("failed to create request: %s", err)
+	}
+	rr := httptest.NewRecorder()
+	http.HandlerFunc(h.NewRecord).ServeHTTP(rr, req)
+	if rr.Code != http.StatusCreated {
+		t.Errorf("handler returned wrong status code: got %v want %v", rr.Code, http.StatusCreated)
+	}
+	res := new(repo.Record)
+	err = json.NewDecoder(rr.Body).Decode(&res)
+	if err != nil {
+		t.Errorf("decoding error")
+	}
+	assert.Equal(t, "CPNMUOJ1E8======", res.Result)
+	assert.Equal(t, repo.Params{"variant": "hex"}, res.Params)
+
+	req, err = http.NewRequest("POST", "/records", strings.NewReader(`{"type":"percent", "input":"100%", "decode":true}`))
+	if err != nil {
+		t.Fatalf("failed to create request: %s", err)
+	}
+	rr = httptest.NewRecorder()
+	http.HandlerFunc(h.NewRecord).ServeHTTP(rr, req)
+	if rr.Code != http.StatusBadRequest {
+		t.Errorf("handler returned wrong status code: got %v want %v", rr.Code, http.StatusBadRequest)
+	}
+	assert.Contains(t, rr.Body.String(), "byte offset 3")
+
+	req, err = http.NewRequest("POST", "/records", strings.NewReader(`{"type":"base58", "input":"`+strings.Repeat("a", 50000)+`"}`))
+	if err != nil {
+		t.Fatalf("failed to create request: %s", err)
+	}
+	rr = httptest.NewRecorder()
+	http.HandlerFunc(h.NewRecord).ServeHTTP(rr, req)
+	if rr.Code != http.StatusBadRequest {
+		t.Errorf("handler returned wrong status code: got %v want %v", rr.Code, http.StatusBadRequest)
+	}
+	assert.Contains(t, rr.Body.String(), "base58 encodes at most 4096 bytes")
+}
+
+func Test_NewRecordCompression(t *testing.T) {
+	db := new(MockDB)
+	h := NewHandler(db)
+
+	input := strings.Repeat("compress me ", 20)
+	body := fmt.Sprintf(`{"type":"pipeline", "input":"%s", "steps":[{"type":"gzip"}, {"type":"base64"}]}`, input)
+	req, err := http.NewRequest("POST", "/records", strings.NewReader(body))
+	if err != nil {
+		t.Fatalf("failed to create request: %s", err)
+	}
+	rr := httptest.NewRecorder()
+	http.HandlerFunc(h.NewRecord).ServeHTTP(rr, req)
+	if rr.Code != http.StatusCreated {
+		t.Errorf("handler returned wrong status code: got %v want %v", rr.Code, http.StatusCreated)
+	}
+	res := new(repo.Record)
+	err = json.NewDecoder(rr.Body).Decode(&res)
+	if err != nil {
+		t.Errorf("decoding error")
+	}
+	assert.Equal(t, int64(len(input)), res.OriginalSize)
+	assert.Less(t, res.CompressedSize, res.OriginalSize)
+	assert.Contains(t, res.Params["steps"], `"level":6`)
+
+	replay := requestFromRecord(*res)
+	replay.Decode = true
+	replay.Input = res.Result
+	replayed := new(repo.Record)
+	err = transformRecord(replayed, replay)
+	assert.Nil(t, err)
+	assert.Equal(t, input, replayed.Result)
+	assert.Equal(t, res.CompressedSize, replayed.CompressedSize)
+
+	for _, body := range []string{
+		`{"type":"zlib", "input":"abc", "level":9}`,
+		`{"type":"pipeline", "input":"abc", "steps":[{"type":"reverse"}, {"type":"gzip"}]}`,
+		`{"type":"pipeline", "input":"abc", "decode":true, "steps":[{"type":"gzip", "decode":true}, {"type":"base64"}]}`,
+		`{"type":"base64", "input":"AAEC", "decode":true}`,
+	} {
+		req, err = http.NewRequest("POST", "/records", strings.NewReader(body))
+		if err != nil {
+			t.Fatalf("failed to create request: %s", err)
+		}
+		rr = httptest.NewRecorder()
+		http.HandlerFunc(h.NewRecord).ServeHTTP(rr, req)
+		if rr.Code != http.StatusBadRequest {
+			t.Errorf("%s: handler returned wrong status code: got %v want %v", body, rr.Code, http.StatusBadRequest)
+		}
+		assert.Contains(t, rr.Body.String(), "base64 or hex", body)
+	}
+
+	req, err = http.NewRequest("POST", "/records", strings.NewReader(`{"type":"pipeline", "input":"abc", "steps":[{"type":"zlib", "level":9}, {"type":"hex"}]}`))
+	if err != nil {
+		t.Fatalf("failed to create request: %s", err)
+	}
+	rr = httptest.NewRecorder()
+	http.HandlerFunc(h.NewRecord).ServeHTTP(rr, req)
+	if rr.Code != http.StatusCreated {
+		t.Errorf("handler returned wrong status code: got %v want %v", rr.Code, http.StatusCreated)
+	}
+	res = new(repo.Record)
+	err = json.NewDecoder(rr.Body).Decode(&res)
+	if err != nil {
+		t.Errorf("decoding error")
+	}
+	assert.Contains(t, res.Params["steps"], `"level":9`)
+	assert.Equal(t, int64(3), res.OriginalSize)
+
+	req, err = http.NewRequest("POST", "/records", strings.NewReader(`{"type":"pipeline", "input":"ab\ncde", "steps":[{"type":"gzip", "lines":true}, {"type":"base64"}]}`))
+	if err != nil {
+		t.Fatalf("failed to create request: %s", err)
+	}
+	rr = httptest.NewRecorder()
+	http.HandlerFunc(h.NewRecord).ServeHTTP(rr, req)
+	if rr.Code != http.StatusCreated {
+		t.Errorf("handler returned wrong status code: got %v want %v: %s", rr.Code, http.StatusCreated, rr.Body)
+	}
+	res = new(repo.Record)
+	err = json.NewDecoder(rr.Body).Decode(&res)
+	if err != nil {
+		t.Errorf("decoding error")
+	}
+	assert.Equal(t, int64(5), res.OriginalSize)
+	assert.NotZero(t, res.CompressedSize)
+}
+
+func Test_NewRecordEncryption(t *testing.T) {
+	db := new(MockDB)
+	h := NewHandler(db)
+	transformer.RegisterKey("crud-test", transformer.Key{Raw: []byte("0123456789abcdef0123456789abcdef")})
+
+	body := `{"type":"pipeline", "input":"secret", "steps":[{"type":"aes", "key_id":"crud-test"}, {"type":"base64"}]}`
+	req, err := http.NewRequest("POST", "/records", strings.NewReader(body))
+	if err != nil {
+		t.Fatalf("failed to create request: %s", err)
+	}
+	rr := httptest.NewRecorder()
+	http.HandlerFunc(h.NewRecord).ServeHTTP(rr, req)
+	if rr.Code != http.StatusCreated {
+		t.Errorf("handler returned wrong status code: got %v want %v", rr.Code, http.StatusCreated)
+	}
+	res := new(repo.Record)
+	err = json.NewDecoder(rr.Body).Decode(&res)
+	if err != nil {
+		t.Errorf("decoding error")
+	}
+	assert.NotContains(t, res.Result, "secret")
+	assert.NotContains(t, res.Params["steps"], "0123456789abcdef")
+
+	replay := requestFromRecord(*res)
+	replay.Decode = true
+	replay.Input = res.Result
+	replayed := new(repo.Record)
+	err = transformRecord(replayed, replay)
+	assert.Nil(t, err)
+	assert.Equal(t, "secret", replayed.Result)
+
+	req, err = http.NewRequest("POST", "/records", strings.NewReader(`{"type":"aes", "input":"secret", "key_id":"missing"}`))
+	if err != nil {
+		t.Fatalf("failed to create request: %s", err)
+	}
+	rr = httptest.NewRecorder()
+	http.HandlerFunc(h.NewRecord).ServeHTTP(rr, req)
+	if rr.Code != http.StatusBadRequest {
+		t.Errorf("handler returned wrong status code: got %v want %v", rr.Code, http.StatusBadRequest)
+	}
+	assert.Contains(t, rr.Body.String(), "unknown key id")
+
+	req, err = http.NewRequest("POST", "/records", strings.NewReader(`{"type":"aes", "input":"secret", "key_id":"crud-test"}`))
+	if err != nil {
+		t.Fatalf("failed to create request: %s", err)
+	}
+	rr = httptest.NewRecorder()
+	http.HandlerFunc(h.NewRecord).ServeHTTP(rr, req)
+	if rr.Code != http.StatusBadRequest {
+		t.Errorf("handler returned wrong status code: got %v want %v", rr.Code, http.StatusBadRequest)
+	}
+	assert.Contains(t, rr.Body.String(), "the output of aes is binary")
+}
+
+func Test_NewRecordHash(t *testing.T) {
+	db := new(MockDB)
+	h := NewHandler(db)
+
+	req, err := http.NewRequest("POST", "/records", strings.NewReader(`{"type":"sha256", "input":"abc", "params":{"encoding":"base64"}}`))
+	if err != nil {
+		t.Fatalf("failed to create request: %s", err)
+	}
+	rr := httptest.NewRecorder()
+	http.HandlerFunc(h.NewRecord).ServeHTTP(rr, req)
+	if rr.Code != http.StatusCreated {
+		t.Errorf("handler returned wrong status code: got %v want %v", rr.Code, http.StatusCreated)
+	}
+	res := new(repo.Record)
+	err = json.NewDecoder(rr.Body).Decode(&res)
+	if err != nil {
+		t.Errorf("decoding error")
+	}
+	assert.Equal(t, "ungWv48Bz+pBQUDeXa4iI7ADYaOWF3qctBD/YfIAFa0=", res.Result)
+	assert.Equal(t, repo.Params{"encoding": "base64"}, res.Params)
+}
+
+func Test_NewRecordClassicalCiphers(t *testing.T) {
+	db := new(MockDB)
+	h := NewHandler(db)
+
+	req, err := http.NewRequest("POST", "/records", strings.NewReader(`{"type":"railfence", "input":"WEAREDISCOVERED", "rails":3}`))
+	if err != nil {
+		t.Fatalf("failed to create request: %s", err)
+	}
+	rr := httptest.NewRecorder()
+	http.HandlerFunc(h.NewRecord).ServeHTTP(rr, req)
+	if rr.Code != http.StatusCreated {
+		t.Errorf("handler returned wrong status code: got %v want %v", rr.Code, http.StatusCreated)
+	}
+	res := new(repo.Record)
+	err = json.NewDecoder(rr.Body).Decode(&res)
+	if err != nil {
+		t.Errorf("decoding error")
+	}
+	assert.Equal(t, "WECRERDSOEEAIVD", res.Result)
+	assert.Equal(t, repo.Params{"rails": "3"}, res.Params)
+
+	replay := requestFromRecord(*res)
+	replay.Decode = true
+	replay.Input = res.Result
+	replayed := new(repo.Record)
+	err = transformRecord(replayed, replay)
+	assert.Nil(t, err)
+	assert.Equal(t, "WEAREDISCOVERED", replayed.Result)
+
+	req, err = http.NewRequest("POST", "/records", strings.NewReader(`{"type":"railfence", "input":"abc", "rails":1}`))
+	if err != nil {
+		t.Fatalf("failed to create request: %s", err)
+	}
+	rr = httptest.NewRecorder()
+	http.HandlerFunc(h.NewRecord).ServeHTTP(rr, req)
+	if rr.Code != http.StatusBadRequest {
+		t.Errorf("handler returned wrong status code: got %v want %v", rr.Code, http.StatusBadRequest)
+	}
+
+	req, err = http.NewRequest("POST", "/records", strings.NewReader(`{"type":"xor", "input":"hello", "key":"k"}`))
+	if err != nil {
+		t.Fatalf("failed to create request: %s", err)
+	}
+	rr = httptest.NewRecorder()
+	http.HandlerFunc(h.NewRecord).ServeHTTP(rr, req)
+	res = new(repo.Record)
+	err = json.NewDecoder(rr.Body).Decode(&res)
+	if err != nil {
+		t.Errorf("decoding error")
+	}
+	assert.Equal(t, "030e070704", res.Result)
+	assert.Equal(t, repo.Params{"key": "k"}, res.Params)
+}
+
+func Test_Crack(t *testing.T) {
+	db := new(MockDB)
+	h := NewHandler(db)
+
+	body := `{"input":"Wkh txlfn eurzq ira mxpsv ryhu wkh odcb grj", "candidates":3}`
+	req, err := http.NewRequest("POST", "/crack", strings.NewReader(body))
+	if err != nil {
+		t.Fatalf("failed to create request: %s", err)
+	}
+	rr := httptest.NewRecorder()
+	http.HandlerFunc(h.Crack).ServeHTTP(rr, req)
+	if rr.Code != http.StatusOK {
+		t.Errorf("handler returned wrong status code: got %v want %v", rr.Code, http.StatusOK)
+	}
+	var candidates []analysis.Candidate
+	err = json.NewDecoder(rr.Body).Decode(&candidates)
+	if err != nil {
+		t.Errorf("decoding error")
+	}
+	assert.Len(t, candidates, 3)
+	assert.Equal(t, 3, candidates[0].Shift)
+	assert.Equal(t, "english", candidates[0].Language)
+	assert.Equal(t, "The quick brown fox jumps over the lazy dog", candidates[0].Preview)
+
+	req, err = http.NewRequest("POST", "/crack", strings.NewReader(`{"input":"1234"}`))
+	if err != nil {
+		t.Fatalf("failed to create request: %s", err)
+	}
+	rr = httptest.NewRecorder()
+	http.HandlerFunc(h.Crack).ServeHTTP(rr, req)
+	if rr.Code != http.StatusBadRequest {
+		t.Errorf("handler returned wrong status code: got %v want %v", rr.Code, http.StatusBadRequest)
+	}
+}
+
+func Test_NewRecordLines(t *testing.T) {
+	db := new(MockDB)
+	h := NewHandler(db)
+
+	req, err := http.NewRequest("POST", "/records", strings.NewReader(`{"type":"reverse", "input":"abc\r\ndef", "lines":true}`))
+	if err != nil {
+		t.Fatalf("failed to create request: %s", err)
+	}
+	rr := httptest.NewRecorder()
+	http.HandlerFunc(h.NewRecord).ServeHTTP(rr, req)
+	if rr.Code != http.StatusCreated {
+		t.Errorf("handler returned wrong status code: got %v want %v", rr.Code, http.StatusCreated)
+	}
+	res := new(repo.Record)
+	err = json.NewDecoder(rr.Body).Decode(&res)
+	if err != nil {
+		t.Errorf("decoding error")
+	}
+	assert.Equal(t, "cba\r\nfed", res.Result)
+	assert.Equal(t, "true", res.Params["lines"])
+
+	body := `{"type":"caesar", "shift":1, "input":"id\tname\n1\tbob", "field":2, "delimiter":"tab"}`
+	req, err = http.NewRequest("POST", "/records", strings.NewReader(body))
+	if err != nil {
+		t.Fatalf("failed to create request: %s", err)
+	}
+	rr = httptest.NewRecorder()
+	http.HandlerFunc(h.NewRecord).ServeHTTP(rr, req)
+	if rr.Code != http.StatusCreated {
+		t.Errorf("handler returned wrong status code: got %v want %v", rr.Code, http.StatusCreated)
+	}
+	res = new(repo.Record)
+	err = json.NewDecoder(rr.Body).Decode(&res)
+	if err != nil {
+		t.Errorf("decoding error")
+	}
+	assert.Equal(t, "id\tobnf\n1\tcpc", res.Result)
+
+	replay := requestFromRecord(*res)
+	replay.Decode = true
+	replay.Input = res.Result
+	replayed := new(repo.Record)
+	err = transformRecord(replayed, replay)
+	assert.Nil(t, err)
+	assert.Equal(t, "id\tname\n1\tbob", replayed.Result)
+
+	req, err = http.NewRequest("POST", "/records", strings.NewReader(`{"type":"reverse", "input":"a", "field":1, "delimiter":"ab"}`))
+	if err != nil {
+		t.Fatalf("failed to create request: %s", err)
+	}
+	rr = httptest.NewRecorder()
+	http.HandlerFunc(h.NewRecord).ServeHTTP(rr, req)
+	if rr.Code != http.StatusBadRequest {
+		t.Errorf("handler returned wrong status code: got %v want %v", rr.Code, http.StatusBadRequest)
+	}
+}
+
+func Test_NewRecordSelect(t *testing.T) {
+	db := new(MockDB)
+	h := NewHandler(db)
+
+	body := `{"type":"base64", "input":"{\"user\": {\"password\": \"secret\"}}", "select":"$..password"}`
+	req, err := http.NewRequest("POST", "/records", strings.NewReader(body))
+	if err != nil {
+		t.Fatalf("failed to create request: %s", err)
+	}
+	rr := httptest.NewRecorder()
+	http.HandlerFunc(h.NewRecord).ServeHTTP(rr, req)
+	if rr.Code != http.StatusCreated {
+		t.Errorf("handler returned wrong status code: got %v want %v", rr.Code, http.StatusCreated)
+	}
+	res := new(repo.Record)
+	err = json.NewDecoder(rr.Body).Decode(&res)
+	if err != nil {
+		t.Errorf("decoding error")
+	}
+	assert.Equal(t, `{"user": {"password": "c2VjcmV0"}}`, res.Result)
+	assert.Equal(t, "$..password", res.Params["select"])
+
+	replay := requestFromRecord(*res)
+	replay.Decode = true
+	replay.Input = res.Result
+	replayed := new(repo.Record)
+	err = transformRecord(replayed, replay)
+	assert.Nil(t, err)
+	assert.Equal(t, `{"user": {"password": "secret"}}`, replayed.Result)
+
+	body = `{"type":"caesar", "shift":1, "input":"name: ann\n", "select":"$.name", "format":"yaml"}`
+	req, err = http.NewRequest("POST", "/records", strings.NewReader(body))
+	if err != nil {
+		t.Fatalf("failed to create request: %s", err)
+	}
+	rr = httptest.NewRecorder()
+	http.HandlerFunc(h.NewRecord).ServeHTTP(rr, req)
+	if rr.Code != http.StatusCreated {
+		t.Errorf("handler returned wrong status code: got %v want %v", rr.Code, http.StatusCreated)
+	}
+	res = new(repo.Record)
+	err = json.NewDecoder(rr.Body).Decode(&res)
+	if err != nil {
+		t.Errorf("decoding error")
+	}
+	assert.Equal(t, "name: boo\n", res.Result)
+
+	req, err = http.NewRequest("POST", "/records", strings.NewReader(`{"type":"reverse", "input":"{\"a\": ", "select":"a"}`))
+	if err != nil {
+		t.Fatalf("failed to create request: %s", err)
+	}
+	rr = httptest.NewRecorder()
+	http.HandlerFunc(h.NewRecord).ServeHTTP(rr, req)
+	if rr.Code != http.StatusBadRequest {
+		t.Errorf("handler returned wrong status code: got %v want %v", rr.Code, http.StatusBadRequest)
+	}
+
+	req, err = http.NewRequest("POST", "/records", strings.NewReader(`{"type":"reverse", "input":"{}", "select":"$["}`))
+	if err != nil {
+		t.Fatalf("failed to create request: %s", err)
+	}
+	rr = httptest.NewRecorder()
+	http.HandlerFunc(h.NewRecord).ServeHTTP(rr, req)
+	if rr.Code != http.StatusBadRequest {
+		t.Errorf("handler returned wrong status code: got %v want %v", rr.Code, http.StatusBadRequest)
+	}
+}
+
+var GetAllRecordsFilterTable = []struct {
+	query  string
+	code   int
+	types  []string
+	total  string
+	filter RecordFilter
+}{
+	{"", http.StatusOK, []string{"reverse", "caesar"}, "2", RecordFilter{}},
+	{"type=caesar", http.StatusOK, []string{"caesar"}, "1", RecordFilter{Type: "caesar"}},
+	{"limit=1", http.StatusOK, []string{"reverse"}, "2", RecordFilter{Limit: 1}},
+	{"limit=1&offset=1", http.StatusOK, []string{"caesar"}, "2", RecordFilter{Limit: 1, Offset: 1}},
+	{"offset=5", http.StatusOK, []string{}, "2", RecordFilter{Offset: 5}},
+	{"created_before=1000", http.StatusOK, []string{}, "0", RecordFilter{CreatedBefore: 1000}},
+	{"created_after=1000&type=reverse", http.StatusOK, []string{"reverse"}, "1", RecordFilter{Type: "reverse", CreatedAfter: 1000}},
+	{"limit=-1", http.StatusBadRequest, nil, "", RecordFilter{}},
+	{"offset=x", http.StatusBadRequest, nil, "", RecordFilter{}},
+}
+
+func Test_GetAllRecordsFilter(t *testing.T) {
+	db := new(MockDB)
+	h := NewHandler(db)
+
+	for _, test := range GetAllRecordsFilterTable {
+		req, err := http.NewRequest("GET", "/records?"+test.query, nil)
+		if err != nil {
+			t.Fatalf("failed to create request: %s", err)
+		}
+		rr := httptest.NewRecorder()
+		http.HandlerFunc(h.GetAllRecords).ServeHTTP(rr, req)
+		if rr.Code != test.code {
+			t.Errorf("%q: handler returned wrong status code: got %v want %v", test.query, rr.Code, test.code)
+		}
+		if test.code != http.StatusOK {
+			continue
+		}
+		assert.Equal(t, test.total, rr.Header().Get(TotalCountHeader), test.query)
+		res := []repo.Record{}
+		err = json.NewDecoder(rr.Body).Decode(&res)
+		if err != nil {
+			t.Errorf("%q: decoding error", test.query)
+		}
+		types := []string{}
+		for _, record := range res {
+			types = append(types, record.Type)
+		}
+		assert.Equal(t, test.types, types, test.query)
+
+		filter, err := ParseRecordFilter(req.URL.Query())
+		assert.Nil(t, err)
+		assert.Equal(t, test.filter, filter, test.query)
+		parsed, err := ParseRecordFilter(filter.Query())
+		assert.Nil(t, err)
+		assert.Equal(t, filter, parsed, test.query)
+	}
+}
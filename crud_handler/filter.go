@@ -0,0 +1,89 @@
+package crud_handler
+
+import (
+	"fmt"
+	"main/repo"
+	"net/url"
+	"strconv"
+)
+
+// TotalCountHeader is the response header of GET /records with the number of
+// records that match the filter, on all pages.
+const TotalCountHeader = "X-Total-Count"
+
+// RecordFilter selects a page of the records of GET /records, which are
+// sorted newest first. It is read from the query parameters type,
+// created_after, created_before, both in Unix seconds, limit and offset. A
+// zero field does not filter, a zero Limit returns all records.
+type RecordFilter struct {
+	Type          string
+	CreatedAfter  int64
+	CreatedBefore int64
+	Limit         int
+	Offset        int
+}
+
+func ParseRecordFilter(query url.Values) (RecordFilter, error) {
+	filter := RecordFilter{Type: query.Get("type")}
+	var err error
+	parse := func(name string, bitSize int) int64 {
+		v := query.Get(name)
+		if v == "" || err != nil {
+			return 0
+		}
+		n, parseErr := strconv.ParseInt(v, 10, bitSize)
+		if parseErr != nil || n < 0 {
+			err = fmt.Errorf("expected %s to be a non-negative integer, got %q", name, v)
+		}
+		return n
+	}
+	filter.CreatedAfter = parse("created_after", 64)
+	filter.CreatedBefore = parse("created_before", 64)
+	filter.Limit = int(parse("limit", 32))
+	filter.Offset = int(parse("offset", 32))
+	if err != nil {
+		return RecordFilter{}, err
+	}
+	return filter, nil
+}
+
+// Query returns the query parameters of the filter.
+func (f RecordFilter) Query() url.Values {
+	query := url.Values{}
+	if f.Type != "" {
+		query.Set("type", f.Type)
+	}
+	set := func(name string, n int64) {
+		if n != 0 {
+			query.Set(name, strconv.FormatInt(n, 10))
+		}
+	}
+	set("created_after", f.CreatedAfter)
+	set("created_before", f.CreatedBefore)
+	set("limit", int64(f.Limit))
+	set("offset", int64(f.Offset))
+	return query
+}
+
+// Apply returns the page of the matching records and how many records match.
+func (f RecordFilter) Apply(records []repo.Record) ([]repo.Record, int) {
+	matching := make([]repo.Record, 0, len(records))
+	for _, record := range records {
+		switch {
+		case f.Type != "" && record.Type != f.Type:
+		case f.CreatedAfter != 0 && record.CreatedAt < f.CreatedAfter:
+		case f.CreatedBefore != 0 && record.CreatedAt >= f.CreatedBefore:
+		default:
+			matching = append(matching, record)
+		}
+	}
+	total := len(matching)
+	if f.Offset >= total {
+		return []repo.Record{}, total
+	}
+	matching = matching[f.Offset:]
+	if f.Limit > 0 && f.Limit < len(matching) {
+		matching = matching[:f.Limit]
+	}
+	return matching, total
+}
@@ -8,10 +8,10 @@ import (
 )
 
 const (
-	QueryCreate     = `INSERT INTO records VALUES ($1, $2, $3, $4, $5, $6) RETURNING *`
+	QueryCreate     = `INSERT INTO records VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9) RETURNING *`
 	QuerySingleRead = `SELECT * FROM records WHERE id = $1`
 	QueryMultiRead  = `SELECT * FROM records`
-	QueryUpdate     = `UPDATE records SET transform_type = $1, caesar_shift = $2, result = $3, updated_at = $4 WHERE id = $5 RETURNING *`
+	QueryUpdate     = `UPDATE records SET transform_type = $1, caesar_shift = $2, result = $3, updated_at = $4, params = $5, original_size = $6, compressed_size = $7 WHERE id = $8 RETURNING *`
 	QueryDelete     = `DELETE FROM records WHERE id = $1`
 )
 
@@ -38,7 +38,7 @@ func NewRecordDB(db *sqlx.DB) *RecordDB {
 	}
 }
 func (db *RecordDB) NewRecord(r *repo.Record) error {
-	err := db.Get(r, QueryCreate, r.ID, r.Type, r.CaesarShift, r.Result, r.CreatedAt, r.UpdatedAt)
+	err := db.Get(r, QueryCreate, r.ID, r.Type, r.CaesarShift, r.Result, r.CreatedAt, r.UpdatedAt, r.Params, r.OriginalSize, r.CompressedSize)
 	if err != nil {
 		return err
 	}
@@ -64,7 +64,7 @@ func (db *RecordDB) GetRecords() ([]repo.Record, error) {
 }
 
 func (db *RecordDB) UpdateRecord(r *repo.Record) error {
-	err := db.Get(r, QueryUpdate, r.Type, r.CaesarShift, r.Result, r.UpdatedAt, r.ID)
+	err := db.Get(r, QueryUpdate, r.Type, r.CaesarShift, r.Result, r.UpdatedAt, r.Params, r.OriginalSize, r.CompressedSize, r.ID)
 	if err != nil {
 		return err
 	}
@@ -24,11 +24,14 @@ var records = []repo.Record{
 		CreatedAt:   time.Now().Unix(),
 	},
 	{
-		ID:          uuid.NewString(),
-		Type:        "reverse",
-		CaesarShift: 0,
-		Result:      "54321",
-		CreatedAt:   time.Now().Unix(),
+		ID:             uuid.NewString(),
+		Type:           "reverse",
+		CaesarShift:    0,
+		Result:         "54321",
+		CreatedAt:      time.Now().Unix(),
+		Params:         repo.Params{"alphabet": "ukrainian"},
+		OriginalSize:   5,
+		CompressedSize: 25,
 	},
 }
 
@@ -0,0 +1 @@
+ALTER TABLE Records DROP COLUMN IF EXISTS params;
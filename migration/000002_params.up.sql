@@ -0,0 +1 @@
+ALTER TABLE Records ADD COLUMN IF NOT EXISTS params TEXT;
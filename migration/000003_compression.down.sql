@@ -0,0 +1,2 @@
+ALTER TABLE Records DROP COLUMN IF EXISTS compressed_size;
+ALTER TABLE Records DROP COLUMN IF EXISTS original_size;
@@ -0,0 +1,2 @@
+ALTER TABLE Records ADD COLUMN IF NOT EXISTS original_size BIGINT NOT NULL DEFAULT 0;
+ALTER TABLE Records ADD COLUMN IF NOT EXISTS compressed_size BIGINT NOT NULL DEFAULT 0;
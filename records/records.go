@@ -0,0 +1,683 @@
+// Package records is the command line client of the records API.
+package records
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"main/cli"
+	"main/crud_handler"
+	"main/repo"
+	"main/transformer"
+	"net/http"
+	"net/url"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+	"time"
+	"unicode/utf8"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Formats the records commands print records in.
+const (
+	PrintTable = "table"
+	PrintJSON  = "json"
+	PrintYAML  = "yaml"
+)
+
+// exportPageSize is how many records export asks for at once.
+const exportPageSize = 100
+
+// recordsClient talks to the records API of the serve command.
+type recordsClient struct {
+	server string
+	http   *http.Client
+}
+
+func serverFlag(fs *flag.FlagSet, server *string) {
+	def := os.Getenv("TRANSFORMER_SERVER")
+	if def == "" {
+		def = "http://localhost:8080"
+	}
+	fs.StringVar(server, "server", def, "URL of the server, or $TRANSFORMER_SERVER")
+}
+
+func printFlag(fs *flag.FlagSet, format *string, def string) {
+	fs.StringVar(format, "print", def, "Print the records as table, json or yaml")
+}
+
+func newRecordsClient(server string) *recordsClient {
+	return &recordsClient{server: strings.TrimSuffix(server, "/"), http: &http.Client{Timeout: 30 * time.Second}}
+}
+
+// do sends body, if any, as JSON and decodes the JSON response into result,
+// if any. The exit code of an error follows the HTTP status, see httpError.
+func (c *recordsClient) do(method, path string, body, result any) (http.Header, error) {
+	var reqBody io.Reader
+	if body != nil {
+		b, err := json.Marshal(body)
+		if err != nil {
+			return nil, err
+		}
+		reqBody = bytes.NewReader(b)
+	}
+	req, err := http.NewRequest(method, c.server+path, reqBody)
+	if err != nil {
+		return nil, cli.Usagef("%s", err)
+	}
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	resp, err := c.http.Do(req)
+	if err != nil {
+		return nil, cli.IOError(err)
+	}
+	defer resp.Body.Close()
+	data, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, cli.IOError(err)
+	}
+	if resp.StatusCode >= 300 {
+		return nil, httpError(resp.StatusCode, data)
+	}
+	if result != nil {
+		if err := json.Unmarshal(data, result); err != nil {
+			return nil, cli.IOError(fmt.Errorf("invalid response: %w", err))
+		}
+	}
+	return resp.Header, nil
+}
+
+// httpError maps a missing record to not found, a rejected request to a
+// transform error and the rest, server failures mostly, to I/O errors.
+func httpError(status int, body []byte) error {
+	err := fmt.Errorf("%d %s: %s", status, http.StatusText(status), strings.TrimSpace(string(body)))
+	switch status {
+	case http.StatusNotFound:
+		return cli.NotFoundError(err)
+	case http.StatusBadRequest, http.StatusUnprocessableEntity:
+		return cli.TransformError(err)
+	default:
+		return cli.IOError(err)
+	}
+}
+
+// list returns a page of the records and how many match the filter.
+func (c *recordsClient) list(filter crud_handler.RecordFilter) ([]repo.Record, int, error) {
+	var records []repo.Record
+	header, err := c.do(http.MethodGet, "/records?"+filter.Query().Encode(), nil, &records)
+	if err != nil {
+		return nil, 0, err
+	}
+	total, err := strconv.Atoi(header.Get(crud_handler.TotalCountHeader))
+	if err != nil {
+		// servers before pagination return all records
+		total = filter.Offset + len(records)
+	}
+	return records, total, nil
+}
+
+// listAll fetches every page of the matching records.
+func (c *recordsClient) listAll(filter crud_handler.RecordFilter) ([]repo.Record, error) {
+	filter.Limit, filter.Offset = exportPageSize, 0
+	var all []repo.Record
+	for {
+		records, total, err := c.list(filter)
+		if err != nil {
+			return nil, err
+		}
+		all = append(all, records...)
+		filter.Offset += len(records)
+		if len(records) == 0 || filter.Offset >= total {
+			return all, nil
+		}
+	}
+}
+
+// Command returns the records command and its subcommands.
+func Command() *cli.Command {
+	var server, printFormat, exportFormat string
+	var filter filterFlags
+	var limit, page int
+	var all bool
+	var create, update requestFlags
+	var exportFile string
+
+	oneID := func(args []string) (string, error) {
+		if len(args) != 1 {
+			return "", cli.Usagef("expected one record id")
+		}
+		return url.PathEscape(args[0]), nil
+	}
+	return &cli.Command{
+		Name:  "records",
+		Short: "Manage the records of a running server",
+		Long: `
+Manage the records of a running server, see the serve command.
+
+Options come before the record ids:
+	./bin/main records get -print yaml 0b6c1d42-...
+	./bin/main records create -type caesar:3 -input "hello"
+	./bin/main records create -pipeline "reverse | base64" -input-file notes.txt
+	./bin/main records list -type caesar -after 24h -page 2`,
+		Commands: []*cli.Command{
+			{
+				Name:  "list",
+				Short: "List the records, newest first, a page at a time",
+				Flags: func(fs *flag.FlagSet) {
+					serverFlag(fs, &server)
+					printFlag(fs, &printFormat, PrintTable)
+					filter.register(fs)
+					fs.IntVar(&limit, "limit", 20, "Records per page")
+					fs.IntVar(&page, "page", 1, "Page to show, from 1")
+					fs.BoolVar(&all, "all", false, "Show all the records instead of a page")
+				},
+				Exclusive: [][]string{{"all", "page"}, {"all", "limit"}},
+				Run: func(args []string) error {
+					if len(args) > 0 {
+						return cli.Usagef("unexpected argument %q", args[0])
+					}
+					if err := checkFormat(printFormat); err != nil {
+						return err
+					}
+					if limit < 1 || page < 1 {
+						return cli.Usagef("-limit and -page must be at least 1")
+					}
+					f, err := filter.filter()
+					if err != nil {
+						return err
+					}
+					client := newRecordsClient(server)
+					var records []repo.Record
+					total := 0
+					if all {
+						records, err = client.listAll(f)
+						total = len(records)
+					} else {
+						f.Limit, f.Offset = limit, (page-1)*limit
+						records, total, err = client.list(f)
+					}
+					if err != nil {
+						return err
+					}
+					if err := printRecords(os.Stdout, records, printFormat); err != nil {
+						return err
+					}
+					if printFormat == PrintTable && !all {
+						printPage(os.Stderr, f.Offset, len(records), total, page)
+					}
+					return nil
+				},
+			},
+			{
+				Name:  "get",
+				Args:  "ID",
+				Short: "Show a record",
+				Flags: func(fs *flag.FlagSet) {
+					serverFlag(fs, &server)
+					printFlag(fs, &printFormat, PrintTable)
+				},
+				Run: func(args []string) error {
+					id, err := oneID(args)
+					if err != nil {
+						return err
+					}
+					if err := checkFormat(printFormat); err != nil {
+						return err
+					}
+					var record repo.Record
+					_, err = newRecordsClient(server).do(http.MethodGet, "/records/"+id, nil, &record)
+					if err != nil {
+						return err
+					}
+					return printRecord(os.Stdout, record, printFormat)
+				},
+			},
+			{
+				Name:      "create",
+				Short:     "Transform an input on the server and store it as a new record",
+				Exclusive: requestExclusive,
+				Flags: func(fs *flag.FlagSet) {
+					serverFlag(fs, &server)
+					printFlag(fs, &printFormat, PrintTable)
+					create.register(fs)
+				},
+				Run: func(args []string) error {
+					if len(args) > 0 {
+						return cli.Usagef("unexpected argument %q", args[0])
+					}
+					if err := checkFormat(printFormat); err != nil {
+						return err
+					}
+					request, err := create.request(os.Stdin)
+					if err != nil {
+						return err
+					}
+					var record repo.Record
+					_, err = newRecordsClient(server).do(http.MethodPost, "/records", request, &record)
+					if err != nil {
+						return err
+					}
+					return printRecord(os.Stdout, record, printFormat)
+				},
+			},
+			{
+				Name:      "update",
+				Args:      "ID",
+				Short:     "Transform an input on the server and store it in the record, which is created if missing",
+				Exclusive: requestExclusive,
+				Flags: func(fs *flag.FlagSet) {
+					serverFlag(fs, &server)
+					printFlag(fs, &printFormat, PrintTable)
+					update.register(fs)
+				},
+				Run: func(args []string) error {
+					id, err := oneID(args)
+					if err != nil {
+						return err
+					}
+					if err := checkFormat(printFormat); err != nil {
+						return err
+					}
+					request, err := update.request(os.Stdin)
+					if err != nil {
+						return err
+					}
+					var record repo.Record
+					_, err = newRecordsClient(server).do(http.MethodPut, "/records/"+id, request, &record)
+					if err != nil {
+						return err
+					}
+					return printRecord(os.Stdout, record, printFormat)
+				},
+			},
+			{
+				Name:  "delete",
+				Args:  "ID...",
+				Short: "Delete records",
+				Flags: func(fs *flag.FlagSet) { serverFlag(fs, &server) },
+				Run: func(args []string) error {
+					if len(args) == 0 {
+						return cli.Usagef("expected record ids")
+					}
+					client := newRecordsClient(server)
+					for _, id := range args {
+						_, err := client.do(http.MethodDelete, "/records/"+url.PathEscape(id), nil, nil)
+						if err != nil {
+							return fmt.Errorf("%s: %w", id, err)
+						}
+						fmt.Printf("deleted %s\n", id)
+					}
+					return nil
+				},
+			},
+			{
+				Name:  "export",
+				Short: "Write all the records that match the filters, as JSON by default",
+				Flags: func(fs *flag.FlagSet) {
+					serverFlag(fs, &server)
+					printFlag(fs, &exportFormat, PrintJSON)
+					filter.register(fs)
+					fs.StringVar(&exportFile, "output", "", "Path to file output, std.out if not set")
+				},
+				Run: func(args []string) error {
+					if len(args) > 0 {
+						return cli.Usagef("unexpected argument %q", args[0])
+					}
+					f, err := filter.filter()
+					if err != nil {
+						return err
+					}
+					if err := checkFormat(exportFormat); err != nil {
+						return err
+					}
+					records, err := newRecordsClient(server).listAll(f)
+					if err != nil {
+						return err
+					}
+					out, err := createOutput(exportFile)
+					if err != nil {
+						return cli.IOError(err)
+					}
+					err = printRecords(out, records, exportFormat)
+					if closeErr := out.Close(); err == nil && closeErr != nil {
+						return cli.IOError(closeErr)
+					}
+					if err != nil {
+						return err
+					}
+					if exportFile != "" {
+						fmt.Fprintf(os.Stderr, "exported %d records to %s\n", len(records), exportFile)
+					}
+					return nil
+				},
+			},
+		},
+	}
+}
+
+// createOutput opens the export file, std.out for none or -.
+func createOutput(path string) (io.WriteCloser, error) {
+	if path == "" || path == "-" {
+		return nopWriteCloser{os.Stdout}, nil
+	}
+	return os.Create(path)
+}
+
+type nopWriteCloser struct {
+	io.Writer
+}
+
+func (nopWriteCloser) Close() error {
+	return nil
+}
+
+// filterFlags are the options of list and export that become a
+// crud_handler.RecordFilter.
+type filterFlags struct {
+	transform, after, before string
+}
+
+func (f *filterFlags) register(fs *flag.FlagSet) {
+	fs.StringVar(&f.transform, "type", "", "Only records of this transformation, e.g. caesar or pipeline")
+	fs.StringVar(&f.after, "after", "", "Only records created at or after a time: 2006, 2006-01, 2006-01-02, RFC 3339, @ and Unix seconds, or a duration ago such as 24h")
+	fs.StringVar(&f.before, "before", "", "Only records created before a time, written as for -after")
+}
+
+func (f *filterFlags) filter() (crud_handler.RecordFilter, error) {
+	filter := crud_handler.RecordFilter{Type: f.transform}
+	var err error
+	filter.CreatedAfter, err = parseTime("after", f.after)
+	if err != nil {
+		return filter, err
+	}
+	filter.CreatedBefore, err = parseTime("before", f.before)
+	return filter, err
+}
+
+// parseTime returns the Unix seconds of a year, a month, a date, an RFC 3339
+// time, Unix seconds written as @1700000000, or a positive duration before
+// now, 0 for an empty value. A bare number is a year, never Unix seconds.
+func parseTime(flagName, value string) (int64, error) {
+	if value == "" {
+		return 0, nil
+	}
+	if strings.HasPrefix(value, "@") {
+		n, err := strconv.ParseInt(value[1:], 10, 64)
+		if err != nil || n < 0 {
+			return 0, cli.Usagef("-%s: expected Unix seconds after @, got %q", flagName, value)
+		}
+		return n, nil
+	}
+	if d, err := time.ParseDuration(value); err == nil {
+		if d <= 0 {
+			return 0, cli.Usagef("-%s: the duration %q counts back from now and must be positive", flagName, value)
+		}
+		return time.Now().Add(-d).Unix(), nil
+	}
+	for _, layout := range []string{time.RFC3339, "2006-01-02", "2006-01", "2006"} {
+		if t, err := time.ParseInLocation(layout, value, time.Local); err == nil {
+			return t.Unix(), nil
+		}
+	}
+	return 0, cli.Usagef("-%s: expected a date, an RFC 3339 time, @ and Unix seconds or a duration, got %q", flagName, value)
+}
+
+// requestFlags build the crud_handler.TransformRequest of create and update.
+type requestFlags struct {
+	step, pipeline, requestFile string
+	input, inputFile            string
+	params                      paramsFlag
+	// options holds the fields set directly by the flags
+	options crud_handler.TransformRequest
+}
+
+var requestExclusive = [][]string{{"type", "pipeline", "request"}, {"input", "input-file"}}
+
+func (f *requestFlags) register(fs *flag.FlagSet) {
+	fs.StringVar(&f.step, "type", "", "Transformation written as a pipeline step, e.g. caesar:3 or vigenere:lemon,alphabet=latin")
+	fs.StringVar(&f.pipeline, "pipeline", "", "Transformations applied in order, e.g. \"reverse | caesar:3 | base64\"")
+	fs.StringVar(&f.requestFile, "request", "", "JSON or YAML file with the request body of POST /records, - for std.in")
+	fs.StringVar(&f.input, "input", "", "Text to transform, std.in if neither -input nor -input-file is set")
+	fs.StringVar(&f.inputFile, "input-file", "", "File to transform")
+	fs.Var(&f.params, "param", "Parameter of the transformation as key=value, can be repeated")
+	fs.BoolVar(&f.options.Decode, "decode", false, "Apply the inverse of the transformation")
+	fs.StringVar(&f.options.KeyID, "key-id", "", "Key loaded by the server for aes")
+	fs.StringVar(&f.options.Mode, "mode", "", "Input mode of the text ciphers: utf8 or bytes")
+	fs.BoolVar(&f.options.Lines, "lines", false, "Transform every line on its own")
+	fs.IntVar(&f.options.Field, "field", 0, "Transform only the Nth field (from 1) of every CSV or TSV line")
+	fs.StringVar(&f.options.Delimiter, "delimiter", "", "Field delimiter for -field: a character or tab")
+	fs.StringVar(&f.options.Select, "select", "", "Transform only the string values a JSONPath selector picks")
+	fs.StringVar(&f.options.Format, "format", "", "Document format for -select: json or yaml")
+}
+
+// request builds the request from the file of -request and the options,
+// which take precedence. The input is read from stdin when no option and no
+// request file give one.
+func (f *requestFlags) request(stdin io.Reader) (*crud_handler.TransformRequest, error) {
+	request := new(crud_handler.TransformRequest)
+	switch {
+	case f.requestFile != "":
+		err := readRequest(f.requestFile, stdin, request)
+		if err != nil {
+			return nil, err
+		}
+	case f.step != "":
+		step, err := transformer.ParseStep(f.step)
+		if err != nil {
+			return nil, cli.Usagef("%s", err)
+		}
+		request.Type, request.Params = step.Name, step.Params
+	case f.pipeline != "":
+		steps, err := transformer.ParseSteps(f.pipeline)
+		if err != nil {
+			return nil, cli.Usagef("%s", err)
+		}
+		request.Type = "pipeline"
+		for _, step := range steps {
+			request.Steps = append(request.Steps, crud_handler.TransformRequest{Type: step.Name, Params: step.Params})
+		}
+	default:
+		return nil, cli.Usagef("expected -type, -pipeline or -request")
+	}
+
+	if len(f.params) > 0 && request.Params == nil {
+		request.Params = map[string]string{}
+	}
+	for k, v := range f.params {
+		request.Params[k] = v
+	}
+	o := f.options
+	request.Decode = request.Decode || o.Decode
+	request.Lines = request.Lines || o.Lines
+	if o.KeyID != "" {
+		request.KeyID = o.KeyID
+	}
+	if o.Mode != "" {
+		request.Mode = o.Mode
+	}
+	if o.Field != 0 {
+		request.Field = o.Field
+	}
+	if o.Delimiter != "" {
+		request.Delimiter = o.Delimiter
+	}
+	if o.Select != "" {
+		request.Select = o.Select
+	}
+	if o.Format != "" {
+		request.Format = o.Format
+	}
+
+	switch {
+	case f.input != "":
+		request.Input = f.input
+	case f.inputFile != "":
+		data, err := os.ReadFile(f.inputFile)
+		if err != nil {
+			return nil, cli.IOError(err)
+		}
+		request.Input = string(data)
+	case request.Input == "" && f.requestFile != "-":
+		data, err := io.ReadAll(stdin)
+		if err != nil {
+			return nil, cli.IOError(err)
+		}
+		// the newline ending typed input is not part of it
+		request.Input = strings.TrimSuffix(string(data), "\n")
+	}
+	return request, nil
+}
+
+// readRequest decodes a JSON or YAML request. YAML is decoded into generic
+// values first so that it uses the JSON names of the fields.
+func readRequest(path string, stdin io.Reader, request *crud_handler.TransformRequest) error {
+	var data []byte
+	var err error
+	if path == "-" {
+		data, err = io.ReadAll(stdin)
+	} else {
+		data, err = os.ReadFile(path)
+	}
+	if err != nil {
+		return cli.IOError(err)
+	}
+	var v any
+	err = yaml.Unmarshal(data, &v)
+	if err == nil {
+		data, err = json.Marshal(v)
+	}
+	if err == nil {
+		err = json.Unmarshal(data, request)
+	}
+	if err != nil {
+		return cli.Usagef("request %s: %s", path, err)
+	}
+	return nil
+}
+
+// paramsFlag collects key=value options.
+type paramsFlag map[string]string
+
+func (p *paramsFlag) String() string {
+	keys := make([]string, 0, len(*p))
+	for k := range *p {
+		keys = append(keys, k+"="+(*p)[k])
+	}
+	sort.Strings(keys)
+	return strings.Join(keys, ",")
+}
+
+func (p *paramsFlag) Set(s string) error {
+	key, value, ok := strings.Cut(s, "=")
+	if !ok || key == "" {
+		return errors.New("expected key=value")
+	}
+	if *p == nil {
+		*p = paramsFlag{}
+	}
+	(*p)[key] = value
+	return nil
+}
+
+func checkFormat(format string) error {
+	switch format {
+	case PrintTable, PrintJSON, PrintYAML:
+		return nil
+	}
+	return cli.Usagef("unknown -print format %q, expected table, json or yaml", format)
+}
+
+func printRecords(w io.Writer, records []repo.Record, format string) error {
+	if records == nil {
+		records = []repo.Record{}
+	}
+	if format != PrintTable {
+		return encode(w, records, format)
+	}
+	tw := tabwriter.NewWriter(w, 0, 8, 2, ' ', 0)
+	fmt.Fprintln(tw, "ID\tTYPE\tCREATED\tUPDATED\tRESULT")
+	for _, r := range records {
+		fmt.Fprintf(tw, "%s\t%s\t%s\t%s\t%s\n", r.ID, r.Type, formatTime(r.CreatedAt), formatTime(r.UpdatedAt), truncate(r.Result, 40))
+	}
+	return tw.Flush()
+}
+
+func printRecord(w io.Writer, r repo.Record, format string) error {
+	if format != PrintTable {
+		return encode(w, r, format)
+	}
+	tw := tabwriter.NewWriter(w, 0, 8, 2, ' ', 0)
+	fmt.Fprintf(tw, "ID\t%s\n", r.ID)
+	fmt.Fprintf(tw, "Type\t%s\n", r.Type)
+	if r.CaesarShift != 0 {
+		fmt.Fprintf(tw, "Shift\t%d\n", r.CaesarShift)
+	}
+	if len(r.Params) > 0 {
+		params := paramsFlag(r.Params)
+		fmt.Fprintf(tw, "Params\t%s\n", params.String())
+	}
+	fmt.Fprintf(tw, "Created\t%s\n", formatTime(r.CreatedAt))
+	fmt.Fprintf(tw, "Updated\t%s\n", formatTime(r.UpdatedAt))
+	if r.OriginalSize != 0 || r.CompressedSize != 0 {
+		fmt.Fprintf(tw, "Size\t%d bytes, %d compressed\n", r.OriginalSize, r.CompressedSize)
+	}
+	tw.Flush()
+	fmt.Fprintf(w, "\n%s\n", r.Result)
+	return nil
+}
+
+func encode(w io.Writer, v any, format string) error {
+	switch format {
+	case PrintJSON:
+		enc := json.NewEncoder(w)
+		enc.SetIndent("", "  ")
+		enc.SetEscapeHTML(false)
+		return enc.Encode(v)
+	case PrintYAML:
+		enc := yaml.NewEncoder(w)
+		enc.SetIndent(2)
+		if err := enc.Encode(v); err != nil {
+			return err
+		}
+		return enc.Close()
+	}
+	return checkFormat(format)
+}
+
+// printPage tells which records of the total a page shows and how to get the
+// next one.
+func printPage(w io.Writer, offset, shown, total, page int) {
+	if shown == 0 {
+		fmt.Fprintf(w, "no records on page %d of %d records\n", page, total)
+		return
+	}
+	fmt.Fprintf(w, "records %d-%d of %d", offset+1, offset+shown, total)
+	if offset+shown < total {
+		fmt.Fprintf(w, ", next page with -page %d", page+1)
+	}
+	fmt.Fprintln(w)
+}
+
+func formatTime(unix int64) string {
+	if unix == 0 {
+		return "-"
+	}
+	return time.Unix(unix, 0).Format("2006-01-02 15:04:05")
+}
+
+// truncate quotes s, shortened to n runes, so that control characters and
+// line breaks do not break the table.
+func truncate(s string, n int) string {
+	if utf8.RuneCountInString(s) <= n {
+		return strconv.Quote(s)
+	}
+	return strconv.Quote(string([]rune(s)[:n])) + "…"
+}
@@ -0,0 +1,266 @@
+package records
+
+import (
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"main/cli"
+	"main/crud_handler"
+	"main/repo"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+)
+
+// exitCode is the exit code err gives a command, see cli.Error.
+func exitCode(err error) int {
+	var e *cli.Error
+	if errors.As(err, &e) {
+		return e.Code
+	}
+	if err != nil {
+		return cli.ExitFailure
+	}
+	return cli.ExitOK
+}
+
+type TestParseTime struct {
+	value string
+	want  time.Time
+	code  int
+}
+
+var TestArrayParseTime = []TestParseTime{
+	TestParseTime{"@1700000000", time.Unix(1700000000, 0), cli.ExitOK},
+	TestParseTime{"@0", time.Unix(0, 0), cli.ExitOK},
+	TestParseTime{"2024", time.Date(2024, 1, 1, 0, 0, 0, 0, time.Local), cli.ExitOK},
+	TestParseTime{"2024-03", time.Date(2024, 3, 1, 0, 0, 0, 0, time.Local), cli.ExitOK},
+	TestParseTime{"2024-03-05", time.Date(2024, 3, 5, 0, 0, 0, 0, time.Local), cli.ExitOK},
+	TestParseTime{"2024-03-05T10:00:00Z", time.Date(2024, 3, 5, 10, 0, 0, 0, time.UTC), cli.ExitOK},
+	TestParseTime{"1700000000", time.Time{}, cli.ExitUsage},
+	TestParseTime{"@-1", time.Time{}, cli.ExitUsage},
+	TestParseTime{"@soon", time.Time{}, cli.ExitUsage},
+	TestParseTime{"-24h", time.Time{}, cli.ExitUsage},
+	TestParseTime{"0s", time.Time{}, cli.ExitUsage},
+	TestParseTime{"yesterday", time.Time{}, cli.ExitUsage},
+}
+
+func TestTableParseTime(t *testing.T) {
+
+	for _, test := range TestArrayParseTime {
+
+		got, err := parseTime("after", test.value)
+		if code := exitCode(err); code != test.code {
+			t.Errorf("Error: parseTime(%q) exits with %d (%v), expected %d", test.value, code, err, test.code)
+			continue
+		}
+		if err == nil && got != test.want.Unix() {
+			t.Errorf("Error: parseTime(%q) = %d, expected %d", test.value, got, test.want.Unix())
+		}
+	}
+
+	got, err := parseTime("after", "")
+	if got != 0 || err != nil {
+		t.Errorf("Error: an empty time gives %d, %v", got, err)
+	}
+	got, err = parseTime("after", "24h")
+	if want := time.Now().Add(-24 * time.Hour).Unix(); err != nil || got < want-5 || got > want {
+		t.Errorf("Error: 24h ago gives %d, %v, expected about %d", got, err, want)
+	}
+}
+
+type TestRequest struct {
+	args  []string
+	stdin string
+	want  string
+	code  int
+}
+
+var TestArrayRequest = []TestRequest{
+	TestRequest{[]string{"-type", "caesar:3", "-input", "hi"}, "", `{"type":"caesar","input":"hi","params":{"shift":"3"}}`, cli.ExitOK},
+	TestRequest{[]string{"-type", "caesar", "-param", "shift=4", "-decode"}, "typed\n", `{"type":"caesar","input":"typed","decode":true,"params":{"shift":"4"}}`, cli.ExitOK},
+	TestRequest{[]string{"-pipeline", "reverse | base64", "-input-file", "input.txt"}, "", `{"type":"pipeline","input":"from file\n","steps":[{"type":"reverse"},{"type":"base64"}]}`, cli.ExitOK},
+	TestRequest{[]string{"-request", "request.json"}, "", `{"type":"caesar","input":"file","params":{"shift":"3"}}`, cli.ExitOK},
+	TestRequest{[]string{"-request", "request.yaml", "-param", "shift=5", "-lines", "-input", "flag"}, "", `{"type":"caesar","input":"flag","lines":true,"params":{"shift":"5"}}`, cli.ExitOK},
+	TestRequest{[]string{"-request", "request.yaml", "-mode", "bytes"}, "", `{"type":"caesar","input":"yaml","mode":"bytes","params":{"shift":"3"}}`, cli.ExitOK},
+	TestRequest{[]string{"-request", "noinput.json"}, "typed\n", `{"type":"reverse","input":"typed"}`, cli.ExitOK},
+	TestRequest{[]string{"-request", "-"}, `{"type": "reverse", "input": "stdin"}`, `{"type":"reverse","input":"stdin"}`, cli.ExitOK},
+	TestRequest{[]string{"-request", "-"}, "type: reverse\n", `{"type":"reverse"}`, cli.ExitOK},
+	TestRequest{[]string{"-request", "-", "-input", "flag"}, "type: reverse\ninput: stdin\n", `{"type":"reverse","input":"flag"}`, cli.ExitOK},
+	TestRequest{[]string{"-request", "-"}, "[not a request", "", cli.ExitUsage},
+	TestRequest{[]string{"-request", "missing.json"}, "", "", cli.ExitIO},
+	TestRequest{[]string{"-type", "caesar:3", "-input-file", "missing.txt"}, "", "", cli.ExitIO},
+	TestRequest{[]string{"-type", ":3"}, "", "", cli.ExitUsage},
+	TestRequest{[]string{"-input", "hi"}, "", "", cli.ExitUsage},
+}
+
+func TestTableRequest(t *testing.T) {
+
+	dir := t.TempDir()
+	files := map[string]string{
+		"input.txt":    "from file\n",
+		"request.json": `{"type": "caesar", "params": {"shift": "3"}, "input": "file"}`,
+		"request.yaml": "type: caesar\nparams:\n  shift: \"3\"\ninput: yaml\n",
+		"noinput.json": `{"type": "reverse"}`,
+	}
+	for name, content := range files {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(content), 0o644); err != nil {
+			t.Fatalf("Error: %s", err)
+		}
+	}
+
+	for _, test := range TestArrayRequest {
+
+		var flags requestFlags
+		fs := flag.NewFlagSet("create", flag.ContinueOnError)
+		flags.register(fs)
+		args := make([]string, len(test.args))
+		for i, arg := range test.args {
+			if _, ok := files[arg]; ok || strings.HasPrefix(arg, "missing.") {
+				arg = filepath.Join(dir, arg)
+			}
+			args[i] = arg
+		}
+		if err := fs.Parse(args); err != nil {
+			t.Fatalf("Error parsing %q: %s", test.args, err)
+		}
+
+		request, err := flags.request(strings.NewReader(test.stdin))
+		if code := exitCode(err); code != test.code {
+			t.Errorf("Error: %q exits with %d (%v), expected %d", test.args, code, err, test.code)
+			continue
+		}
+		if err != nil {
+			continue
+		}
+		got, _ := json.Marshal(request)
+		if string(got) != test.want {
+			t.Errorf("Error: %q gives %s, expected %s", test.args, got, test.want)
+		}
+	}
+}
+
+// recordsServer serves count records a page at a time, as GET /records does.
+// Without pagination it ignores the filter and sends no total, as servers
+// did before it. It counts the requests it gets.
+func recordsServer(t *testing.T, count int, pagination bool, requests *int) *httptest.Server {
+	var all []repo.Record
+	for i := 0; i < count; i++ {
+		all = append(all, repo.Record{ID: strconv.Itoa(i), Type: "reverse", CreatedAt: int64(count - i)})
+	}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		*requests++
+		records := all
+		if pagination {
+			filter, err := crud_handler.ParseRecordFilter(r.URL.Query())
+			if err != nil {
+				http.Error(w, err.Error(), http.StatusBadRequest)
+				return
+			}
+			var total int
+			records, total = filter.Apply(all)
+			w.Header().Set(crud_handler.TotalCountHeader, strconv.Itoa(total))
+		}
+		if records == nil {
+			records = []repo.Record{}
+		}
+		json.NewEncoder(w).Encode(records)
+	}))
+	t.Cleanup(server.Close)
+	return server
+}
+
+type TestListAll struct {
+	count      int
+	pagination bool
+	requests   int
+}
+
+var TestArrayListAll = []TestListAll{
+	TestListAll{0, true, 1},
+	TestListAll{1, true, 1},
+	TestListAll{exportPageSize, true, 1},
+	TestListAll{exportPageSize + 1, true, 2},
+	TestListAll{2*exportPageSize + 50, true, 3},
+	TestListAll{0, false, 1},
+	TestListAll{2*exportPageSize + 50, false, 1},
+}
+
+func TestTableListAll(t *testing.T) {
+
+	for _, test := range TestArrayListAll {
+
+		requests := 0
+		server := recordsServer(t, test.count, test.pagination, &requests)
+		records, err := newRecordsClient(server.URL + "/").listAll(crud_handler.RecordFilter{Limit: 3, Offset: 7})
+		if err != nil {
+			t.Fatalf("Error listing %d records: %s", test.count, err)
+		}
+		if len(records) != test.count {
+			t.Errorf("Error: listed %d of %d records", len(records), test.count)
+		}
+		for i, record := range records {
+			if record.ID != strconv.Itoa(i) {
+				t.Errorf("Error: record %d of %d is %s", i, test.count, record.ID)
+				break
+			}
+		}
+		if requests != test.requests {
+			t.Errorf("Error: listing %d records took %d requests, expected %d", test.count, requests, test.requests)
+		}
+	}
+}
+
+type TestHTTPError struct {
+	status int
+	code   int
+}
+
+var TestArrayHTTPError = []TestHTTPError{
+	TestHTTPError{http.StatusOK, cli.ExitOK},
+	TestHTTPError{http.StatusCreated, cli.ExitOK},
+	TestHTTPError{http.StatusNotFound, cli.ExitNotFound},
+	TestHTTPError{http.StatusBadRequest, cli.ExitTransform},
+	TestHTTPError{http.StatusUnprocessableEntity, cli.ExitTransform},
+	TestHTTPError{http.StatusMethodNotAllowed, cli.ExitIO},
+	TestHTTPError{http.StatusInternalServerError, cli.ExitIO},
+	TestHTTPError{http.StatusServiceUnavailable, cli.ExitIO},
+}
+
+func TestTableHTTPError(t *testing.T) {
+
+	for _, test := range TestArrayHTTPError {
+
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.WriteHeader(test.status)
+			fmt.Fprintf(w, "{}\n")
+		}))
+		_, err := newRecordsClient(server.URL).do(http.MethodGet, "/records/1", nil, nil)
+		server.Close()
+		if code := exitCode(err); code != test.code {
+			t.Errorf("Error: status %d exits with %d (%v), expected %d", test.status, code, err, test.code)
+		}
+		if err != nil && !strings.HasPrefix(err.Error(), strconv.Itoa(test.status)+" ") {
+			t.Errorf("Error: status %d gives the message %q", test.status, err)
+		}
+	}
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprintf(w, "not json")
+	}))
+	_, err := newRecordsClient(server.URL).do(http.MethodGet, "/records/1", nil, new(repo.Record))
+	server.Close()
+	if code := exitCode(err); code != cli.ExitIO {
+		t.Errorf("Error: an invalid response exits with %d (%v), expected %d", code, err, cli.ExitIO)
+	}
+	_, err = newRecordsClient(server.URL).do(http.MethodGet, "/records/1", nil, nil)
+	if code := exitCode(err); code != cli.ExitIO {
+		t.Errorf("Error: a closed server exits with %d (%v), expected %d", code, err, cli.ExitIO)
+	}
+}
@@ -0,0 +1,272 @@
+// Package repl is an interactive shell that shows what transformers and
+// pipelines make of the lines typed into it.
+package repl
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"main/cli"
+	"main/transformer"
+	"os"
+	"strconv"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// Help lists the commands of a session.
+const Help = `
+Type text to see it transformed by the current transformer, and the inverse
+applied to the output when the transformer has one.
+
+Commands:
+	:use NAME [ARGS...]   use one transformer, e.g. :use caesar 3 or :use vigenere lemon alphabet=latin
+	:pipe STEPS           use a pipeline, e.g. :pipe reverse|base64
+	:inverse              turn showing the inverse on or off
+	:show                 show the current transformer
+	:list                 list the transformers
+	:history              list the history, !N runs entry N again and !! the last one
+	:help                 show this help
+	:quit                 leave, as does Ctrl+D
+`
+
+// maxHistory is how many entries of the history file are kept.
+const maxHistory = 1000
+
+// Session runs the lines typed into the REPL with the current transformer.
+type Session struct {
+	out io.Writer
+	// spec is the current pipeline, written as for -pipeline
+	spec        string
+	tr          transformer.StreamTransformer
+	inverse     transformer.StreamTransformer
+	showInverse bool
+	history     []string
+	historyFile *os.File
+}
+
+// New starts a session that writes to out, transforming with the pipeline.
+func New(out io.Writer, pipeline string) (*Session, error) {
+	s := &Session{out: out, showInverse: true}
+	if err := s.setPipeline(pipeline); err != nil {
+		return nil, err
+	}
+	return s, nil
+}
+
+// Run reads lines until :quit or the end of the input, the prompts are only
+// shown to a terminal.
+func (s *Session) Run(in io.Reader, prompt bool) error {
+	if prompt {
+		fmt.Fprintf(s.out, "Transforming with %s, :help lists the commands.\n", s.spec)
+	}
+	scanner := bufio.NewScanner(in)
+	scanner.Buffer(make([]byte, 64*1024), 1<<20)
+	for {
+		if prompt {
+			fmt.Fprint(s.out, "> ")
+		}
+		if !scanner.Scan() {
+			break
+		}
+		if !s.eval(scanner.Text()) {
+			return nil
+		}
+	}
+	if prompt {
+		fmt.Fprintln(s.out)
+	}
+	if err := scanner.Err(); err != nil {
+		return cli.IOError(err)
+	}
+	return nil
+}
+
+// eval runs a line, it returns false to quit.
+func (s *Session) eval(line string) bool {
+	if strings.HasPrefix(line, "!") {
+		entry, err := s.historyEntry(line[1:])
+		if err != nil {
+			fmt.Fprintf(s.out, "error: %s\n", err)
+			return true
+		}
+		fmt.Fprintln(s.out, entry)
+		line = entry
+	}
+	if strings.TrimSpace(line) != "" {
+		s.addHistory(line)
+	}
+	if !strings.HasPrefix(line, ":") {
+		s.transform(line)
+		return true
+	}
+
+	command, arg, _ := strings.Cut(strings.TrimSpace(line[1:]), " ")
+	arg = strings.TrimSpace(arg)
+	var err error
+	switch command {
+	case "use":
+		fields := strings.Fields(arg)
+		if len(fields) == 0 {
+			err = errors.New("expected a transformer, e.g. :use caesar 3")
+			break
+		}
+		step := fields[0]
+		if len(fields) > 1 {
+			step += ":" + strings.Join(fields[1:], ",")
+		}
+		err = s.setPipeline(step)
+	case "pipe":
+		err = s.setPipeline(arg)
+	case "inverse":
+		s.showInverse = !s.showInverse
+		s.show()
+	case "show":
+		s.show()
+	case "list":
+		for _, spec := range transformer.Specs() {
+			fmt.Fprintf(s.out, "%-14s %s\n", spec.Name, spec.Description)
+		}
+	case "history":
+		for i, entry := range s.history {
+			fmt.Fprintf(s.out, "%5d  %s\n", i+1, entry)
+		}
+	case "help":
+		fmt.Fprint(s.out, strings.TrimPrefix(Help, "\n"))
+	case "quit", "q", "exit":
+		return false
+	default:
+		err = fmt.Errorf("unknown command :%s, :help lists the commands", command)
+	}
+	if err != nil {
+		fmt.Fprintf(s.out, "error: %s\n", err)
+	}
+	return true
+}
+
+// setPipeline replaces the transformer, keeping the current one on errors.
+func (s *Session) setPipeline(spec string) error {
+	spec = strings.TrimSpace(spec)
+	if spec == "" {
+		return errors.New("expected pipeline steps, e.g. :pipe reverse|base64")
+	}
+	p, err := transformer.ParsePipeline(spec)
+	if err != nil {
+		return err
+	}
+	var tr transformer.StreamTransformer = p
+	if len(p) == 1 {
+		tr = p[0]
+	}
+	s.spec, s.tr = spec, tr
+	s.inverse, _ = transformer.Inverse(tr)
+	s.show()
+	return nil
+}
+
+func (s *Session) show() {
+	switch {
+	case s.inverse == nil:
+		fmt.Fprintf(s.out, "using %s, it has no inverse\n", s.spec)
+	case s.showInverse:
+		fmt.Fprintf(s.out, "using %s, showing the inverse\n", s.spec)
+	default:
+		fmt.Fprintf(s.out, "using %s\n", s.spec)
+	}
+}
+
+func (s *Session) transform(line string) {
+	var out bytes.Buffer
+	err := s.tr.TransformStream(strings.NewReader(line), &out)
+	if err != nil {
+		fmt.Fprintf(s.out, "error: %s\n", err)
+		return
+	}
+	fmt.Fprintf(s.out, "%s\n", printable(out.Bytes()))
+	if s.inverse == nil || !s.showInverse {
+		return
+	}
+	var back bytes.Buffer
+	err = s.inverse.TransformStream(bytes.NewReader(out.Bytes()), &back)
+	if err != nil {
+		fmt.Fprintf(s.out, "inverse error: %s\n", err)
+		return
+	}
+	fmt.Fprintf(s.out, "inverse: %s\n", printable(back.Bytes()))
+}
+
+// printable shows binary output, such as compressed or encrypted data, in hex.
+func printable(b []byte) string {
+	if utf8.Valid(b) && bytes.IndexFunc(b, func(r rune) bool { return unicode.IsControl(r) && r != '\t' && r != '\n' }) < 0 {
+		return string(b)
+	}
+	return fmt.Sprintf("(%d bytes in hex) %s", len(b), hex.EncodeToString(b))
+}
+
+func (s *Session) historyEntry(ref string) (string, error) {
+	if len(s.history) == 0 {
+		return "", errors.New("the history is empty")
+	}
+	if ref == "!" {
+		return s.history[len(s.history)-1], nil
+	}
+	n, err := strconv.Atoi(ref)
+	if err != nil || n < 1 || n > len(s.history) {
+		return "", fmt.Errorf("no history entry %q, :history lists them", ref)
+	}
+	return s.history[n-1], nil
+}
+
+// OpenHistory loads the history and keeps the file open to append the new
+// entries. A file grown past twice maxHistory is cut down to maxHistory.
+func (s *Session) OpenHistory(path string) error {
+	data, err := os.ReadFile(path)
+	if err != nil && !errors.Is(err, os.ErrNotExist) {
+		return err
+	}
+	lines := strings.Split(strings.TrimSuffix(string(data), "\n"), "\n")
+	if len(data) == 0 {
+		lines = nil
+	}
+	flags := os.O_WRONLY | os.O_CREATE | os.O_APPEND
+	if len(lines) > 2*maxHistory {
+		lines = lines[len(lines)-maxHistory:]
+		flags |= os.O_TRUNC
+	}
+	f, err := os.OpenFile(path, flags, 0o600)
+	if err != nil {
+		return err
+	}
+	if flags&os.O_TRUNC != 0 {
+		_, err = f.WriteString(strings.Join(lines, "\n") + "\n")
+		if err != nil {
+			f.Close()
+			return err
+		}
+	}
+	if len(lines) > maxHistory {
+		lines = lines[len(lines)-maxHistory:]
+	}
+	s.history, s.historyFile = lines, f
+	return nil
+}
+
+func (s *Session) addHistory(line string) {
+	if len(s.history) > 0 && s.history[len(s.history)-1] == line {
+		return
+	}
+	s.history = append(s.history, line)
+	if s.historyFile != nil {
+		fmt.Fprintln(s.historyFile, line)
+	}
+}
+
+func (s *Session) Close() {
+	if s.historyFile != nil {
+		s.historyFile.Close()
+	}
+}
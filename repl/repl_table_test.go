@@ -0,0 +1,138 @@
+package repl
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+type TestSession struct {
+	input    string
+	contains []string
+	missing  []string
+	spec     string
+}
+
+var TestArraySession = []TestSession{
+	TestSession{"abc\n", []string{"cba\ninverse: abc\n"}, nil, "reverse"},
+	TestSession{":use caesar 3\nabc\n", []string{"using caesar:3, showing the inverse", "def\ninverse: abc\n"}, nil, "caesar:3"},
+	TestSession{":use vigenere lemon alphabet=latin\nattack\n", []string{"lxfopv"}, nil, "vigenere:lemon,alphabet=latin"},
+	TestSession{":pipe reverse | base64\nabc\n", []string{"Y2Jh\ninverse: abc\n"}, nil, "reverse | base64"},
+	TestSession{":use sha256\nabc\n", []string{"it has no inverse", "ba7816bf"}, []string{"inverse:"}, "sha256"},
+	TestSession{":use gzip\nhi\n", []string{"bytes in hex) 1f8b", "inverse: hi\n"}, nil, "gzip"},
+	TestSession{":inverse\nabc\n", []string{"cba\n"}, []string{"inverse: abc"}, "reverse"},
+	TestSession{":use nope\nabc\n", []string{`error: unknown transformation "nope"`, "cba\n"}, nil, "reverse"},
+	TestSession{":use\n:pipe\n:what\n", []string{"expected a transformer", "expected pipeline steps", "unknown command :what"}, nil, "reverse"},
+	TestSession{"abc\n!!\n", []string{"abc\ncba\ninverse: abc\n"}, nil, "reverse"},
+	TestSession{":use caesar 1\nabc\n!1\n:use caesar 2\n!1\nabc\n", []string{"bcd", "using caesar:1"}, []string{"cde"}, "caesar:1"},
+	TestSession{"!!\n", []string{"error: the history is empty"}, nil, "reverse"},
+	TestSession{"abc\n!5\n!0\n!x\n", []string{`no history entry "5"`, `no history entry "0"`, `no history entry "x"`}, nil, "reverse"},
+	TestSession{":quit\nabc\n", nil, []string{"cba"}, "reverse"},
+}
+
+func TestTableSession(t *testing.T) {
+
+	for _, test := range TestArraySession {
+
+		out := new(strings.Builder)
+		s, err := New(out, "reverse")
+		if err != nil {
+			t.Fatalf("Error: %s", err)
+		}
+		err = s.Run(strings.NewReader(test.input), false)
+		if err != nil {
+			t.Errorf("Error running %q: %s", test.input, err)
+		}
+		for _, c := range test.contains {
+			if !strings.Contains(out.String(), c) {
+				t.Errorf("Error: %q printed %q, expected it to contain %q", test.input, out, c)
+			}
+		}
+		for _, m := range test.missing {
+			if strings.Contains(out.String(), m) {
+				t.Errorf("Error: %q printed %q, expected no %q", test.input, out, m)
+			}
+		}
+		if s.spec != test.spec {
+			t.Errorf("Error: %q left the pipeline %q, expected %q", test.input, s.spec, test.spec)
+		}
+	}
+}
+
+var TestArrayPrintable = [][2]string{
+	{"abc", "abc"},
+	{"a\tb\nc", "a\tb\nc"},
+	{"привіт", "привіт"},
+	{"\x00\x01", "(2 bytes in hex) 0001"},
+	{"a\xffb", "(3 bytes in hex) 61ff62"},
+	{"\x1b[2J", "(4 bytes in hex) 1b5b324a"},
+}
+
+func TestTablePrintable(t *testing.T) {
+
+	for _, test := range TestArrayPrintable {
+
+		if got := printable([]byte(test[0])); got != test[1] {
+			t.Errorf("Error: printable(%q) = %q, expected %q", test[0], got, test[1])
+		}
+	}
+}
+
+type TestHistoryFile struct {
+	lines    int
+	kept     int
+	fileKeep int
+}
+
+var TestArrayHistoryFile = []TestHistoryFile{
+	TestHistoryFile{0, 0, 0},
+	TestHistoryFile{10, 10, 10},
+	TestHistoryFile{maxHistory + 500, maxHistory, maxHistory + 500},
+	TestHistoryFile{2 * maxHistory, maxHistory, 2 * maxHistory},
+	TestHistoryFile{2*maxHistory + 1, maxHistory, maxHistory},
+}
+
+func TestTableHistoryFile(t *testing.T) {
+
+	for _, test := range TestArrayHistoryFile {
+
+		path := filepath.Join(t.TempDir(), "history")
+		var content strings.Builder
+		for i := 1; i <= test.lines; i++ {
+			fmt.Fprintf(&content, "line %d\n", i)
+		}
+		if test.lines > 0 {
+			if err := os.WriteFile(path, []byte(content.String()), 0o600); err != nil {
+				t.Fatalf("Error: %s", err)
+			}
+		}
+
+		s, err := New(new(strings.Builder), "reverse")
+		if err != nil {
+			t.Fatalf("Error: %s", err)
+		}
+		err = s.OpenHistory(path)
+		if err != nil {
+			t.Fatalf("Error opening a history of %d lines: %s", test.lines, err)
+		}
+		if len(s.history) != test.kept {
+			t.Errorf("Error: %d lines kept %d entries, expected %d", test.lines, len(s.history), test.kept)
+		}
+		if test.kept > 0 && s.history[len(s.history)-1] != fmt.Sprintf("line %d", test.lines) {
+			t.Errorf("Error: %d lines end with %q", test.lines, s.history[len(s.history)-1])
+		}
+		s.Run(strings.NewReader("new entry\n"), false)
+		s.Close()
+
+		data, err := os.ReadFile(path)
+		if err != nil {
+			t.Fatalf("Error: %s", err)
+		}
+		lines := strings.Split(strings.TrimSuffix(string(data), "\n"), "\n")
+		if len(lines) != test.fileKeep+1 || lines[len(lines)-1] != "new entry" {
+			t.Errorf("Error: history file of %d lines has %d lines ending with %q, expected %d", test.lines, len(lines), lines[len(lines)-1], test.fileKeep+1)
+		}
+	}
+}
@@ -1,14 +1,56 @@
 package repo
 
-import "github.com/google/uuid"
+import (
+	"database/sql/driver"
+	"encoding/json"
+	"fmt"
 
+	"github.com/google/uuid"
+)
+
+// Record is stored in the database and returned by the API. The YAML names
+// are the JSON ones.
 type Record struct {
-	ID          string `db:"id"`
-	Type        string `db:"transform_type"`
-	CaesarShift int    `db:"caesar_shift"`
-	Result      string `db:"result"`
-	CreatedAt   int64  `db:"created_at"`
-	UpdatedAt   int64  `db:"updated_at"`
+	ID          string `db:"id" yaml:"ID"`
+	Type        string `db:"transform_type" yaml:"Type"`
+	CaesarShift int    `db:"caesar_shift" yaml:"CaesarShift"`
+	Result      string `db:"result" yaml:"Result"`
+	CreatedAt   int64  `db:"created_at" yaml:"CreatedAt"`
+	UpdatedAt   int64  `db:"updated_at" yaml:"UpdatedAt"`
+	Params      Params `db:"params" json:",omitempty" yaml:"Params,omitempty"`
+	// OriginalSize and CompressedSize are set when the transformation
+	// compresses or decompresses, in bytes.
+	OriginalSize   int64 `db:"original_size" json:",omitempty" yaml:"OriginalSize,omitempty"`
+	CompressedSize int64 `db:"compressed_size" json:",omitempty" yaml:"CompressedSize,omitempty"`
+}
+
+// Params holds the transformation parameters other than the Caesar shift,
+// stored as a JSON object.
+type Params map[string]string
+
+func (p Params) Value() (driver.Value, error) {
+	if len(p) == 0 {
+		return nil, nil
+	}
+	b, err := json.Marshal(p)
+	if err != nil {
+		return nil, err
+	}
+	return string(b), nil
+}
+
+func (p *Params) Scan(src interface{}) error {
+	switch v := src.(type) {
+	case nil:
+		*p = nil
+		return nil
+	case string:
+		return json.Unmarshal([]byte(v), p)
+	case []byte:
+		return json.Unmarshal(v, p)
+	default:
+		return fmt.Errorf("cannot scan %T into Params", src)
+	}
 }
 
 type RecordDB interface {
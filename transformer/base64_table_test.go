@@ -1,6 +1,7 @@
 package transformer
 
 import (
+	"errors"
 	"strings"
 	"testing"
 )
@@ -14,7 +15,7 @@ var TestArrayBase64 = []TestBase64{
 	TestBase64{"Man", "TWFu", false},
 	TestBase64{"Ma", "TWE=", false},
 	TestBase64{"M", "TQ==", false},
-	TestBase64{"Man1", "TWFu", true},
+	TestBase64{"Man\n", "TWFu", true},
 }
 
 func TestTableBase64(t *testing.T) {
@@ -32,3 +33,81 @@ func TestTableBase64(t *testing.T) {
 
 	}
 }
+
+type TestBase64Variant struct {
+	input, expected, variant string
+	decode                   bool
+}
+
+var TestArrayBase64Variant = []TestBase64Variant{
+	TestBase64Variant{"TWFu", "Man", "std", true},
+	TestBase64Variant{"TQ==", "M", "std", true},
+	TestBase64Variant{"TWFu\r\nTWE=\n", "ManMa", "std", true},
+	TestBase64Variant{"\xfb\xff", "-_8=", "url", false},
+	TestBase64Variant{"-_8=", "\xfb\xff", "url", true},
+	TestBase64Variant{"M", "TQ", "rawstd", false},
+	TestBase64Variant{"TQ", "M", "rawstd", true},
+	TestBase64Variant{"\xfb\xff", "-_8", "rawurl", false},
+	TestBase64Variant{"-_8", "\xfb\xff", "rawurl", true},
+}
+
+func TestTableBase64Variant(t *testing.T) {
+
+	for _, test := range TestArrayBase64Variant {
+
+		tr, err := NewBase64VariantTransformer(test.variant, test.decode)
+		if err != nil {
+			t.Fatalf("Error creating transformer: %s", err)
+		}
+		result, err := tr.Transform(strings.NewReader(test.input), false)
+		if err != nil {
+			t.Errorf("Error transforming: %s", err)
+		}
+
+		if result != test.expected {
+			t.Errorf("Error: result = %q, expected = %q", result, test.expected)
+		}
+
+	}
+}
+
+type TestBase64Malformed struct {
+	input, variant string
+	offset         int64
+}
+
+var TestArrayBase64Malformed = []TestBase64Malformed{
+	TestBase64Malformed{"TW*u", "std", 2},
+	TestBase64Malformed{"TWFu\nT!Fu", "std", 6},
+	TestBase64Malformed{"TQ", "std", 0},
+	TestBase64Malformed{"TQ==", "rawstd", 2},
+	TestBase64Malformed{"+/8=", "url", 0},
+}
+
+func TestTableBase64Malformed(t *testing.T) {
+
+	for _, test := range TestArrayBase64Malformed {
+
+		tr, err := NewBase64VariantTransformer(test.variant, true)
+		if err != nil {
+			t.Fatalf("Error creating transformer: %s", err)
+		}
+		_, err = tr.Transform(strings.NewReader(test.input), false)
+		var malformed *MalformedInputError
+		if !errors.As(err, &malformed) {
+			t.Errorf("Error: expected malformed input error for %q, got %v", test.input, err)
+			continue
+		}
+		if malformed.Offset != test.offset {
+			t.Errorf("Error: offset = %d, expected = %d", malformed.Offset, test.offset)
+		}
+
+	}
+}
+
+func TestBase64UnknownVariant(t *testing.T) {
+	_, err := NewBase64VariantTransformer("base65", false)
+	if err == nil {
+		t.Errorf("Error: expected unknown variant error")
+	}
+}
@@ -15,12 +15,12 @@ type TestBasic struct {
 var TestArrayBasic = []TestBasic{
 	TestBasic{"Man", "TWFu", 0, true, false},
 	TestBasic{"Ma", "TWE=", 0, true, false},
-	TestBasic{"Ma1", "TWE=", 0, true, true},
+	TestBasic{"Ma\n", "TWE=", 0, true, true},
 	TestBasic{"za", "ab", 1, false, false},
 	TestBasic{"abc", "xyz", -3, false, false},
-	TestBasic{"za1", "ab", 1, false, true},
+	TestBasic{"za\r\n", "ab", 1, false, true},
 	TestBasic{"12345", "54321", 0, false, false},
-	TestBasic{"123450", "54321", 0, false, true},
+	TestBasic{"12345\n", "54321", 0, false, true},
 }
 
 func TestTableBasic(t *testing.T) {
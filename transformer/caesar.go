@@ -0,0 +1,123 @@
+package transformer
+
+import (
+	"fmt"
+	"io"
+	"unicode"
+)
+
+const DefaultCaesarAlphabet = "latin"
+
+// CaesarAlphabets are the named alphabets a Caesar cipher can rotate through.
+// Letters are listed in lower case; their upper-case forms are rotated too.
+var CaesarAlphabets = map[string]string{
+	"latin":     "abcdefghijklmnopqrstuvwxyz",
+	"ukrainian": "абвгґдеєжзиіїйклмнопрстуфхцчшщьюя",
+}
+
+var caesarSpec = Spec{
+	Name:        "caesar",
+	Description: "Caesar cipher, rotate letters by a fixed shift",
+	MainParam:   "shift",
+	Params: []Param{
+		{Name: "shift", Type: ParamInt, Required: true, Description: "Number of positions to rotate, not 0"},
+		alphabetParam,
+		{Name: "rotate_digits", Type: ParamBool, Description: "Rotate digits 0-9 as well"},
+	},
+	Text:       true,
+	Invertible: true,
+	New: func(p Params) (StreamTransformer, error) {
+		shift, _ := p.Int("shift")
+		if shift == 0 {
+			return nil, fmt.Errorf("expected shift (not 0)")
+		}
+		digits, _ := p.Bool("rotate_digits")
+		return NewCaesarTransformerWithOptions(shift, CaesarOptions{Alphabet: p["alphabet"], RotateDigits: digits})
+	},
+}
+
+type CaesarOptions struct {
+	// Alphabet is a name from CaesarAlphabets or the letters of a custom
+	// alphabet in order. Latin is used when it is empty.
+	Alphabet     string
+	RotateDigits bool
+}
+
+type CaesarTransformer struct {
+	Shift        int
+	Alphabet     []rune
+	RotateDigits bool
+}
+
+func NewCaesarTransformer(shift int) *CaesarTransformer {
+	return &CaesarTransformer{Shift: shift}
+}
+
+func NewCaesarTransformerWithOptions(shift int, opts CaesarOptions) (*CaesarTransformer, error) {
+	alphabet, err := CaesarAlphabet(opts.Alphabet)
+	if err != nil {
+		return nil, err
+	}
+	return &CaesarTransformer{Shift: shift, Alphabet: alphabet, RotateDigits: opts.RotateDigits}, nil
+}
+
+// CaesarAlphabet resolves an alphabet name or a custom list of letters.
+func CaesarAlphabet(name string) ([]rune, error) {
+	if name == "" {
+		name = DefaultCaesarAlphabet
+	}
+	if letters, ok := CaesarAlphabets[name]; ok {
+		return []rune(letters), nil
+	}
+	alphabet := []rune(name)
+	if len(alphabet) < 2 {
+		return nil, fmt.Errorf("unknown caesar alphabet %q", name)
+	}
+	seen := make(map[rune]bool, len(alphabet))
+	for i, r := range alphabet {
+		r = unicode.ToLower(r)
+		if seen[r] {
+			return nil, fmt.Errorf("caesar alphabet %q repeats letter %q", name, r)
+		}
+		seen[r] = true
+		alphabet[i] = r
+	}
+	return alphabet, nil
+}
+
+func (t *CaesarTransformer) Transform(in io.Reader, ioinput bool) (string, error) {
+	return transformToString(t, in, ioinput)
+}
+
+func (t *CaesarTransformer) TransformStream(in io.Reader, out io.Writer) error {
+	return mapRunes(in, out, t.table())
+}
+
+func (t *CaesarTransformer) Inverse() (StreamTransformer, error) {
+	return &CaesarTransformer{Shift: -t.Shift, Alphabet: t.Alphabet, RotateDigits: t.RotateDigits}, nil
+}
+
+// table maps every rune the cipher changes to its replacement. Letters keep
+// their case and everything outside the alphabet is left untouched.
+func (t *CaesarTransformer) table() map[rune]rune {
+	alphabet := orDefaultAlphabet(t.Alphabet)
+	n := len(alphabet)
+	shift := mod(t.Shift, n)
+	shifted := append(append([]rune{}, alphabet[shift:]...), alphabet[:shift]...)
+	table := substitutionTable(alphabet, shifted)
+	if t.RotateDigits {
+		shift = mod(t.Shift, 10)
+		for d := 0; d < 10; d++ {
+			table[rune('0'+d)] = rune('0' + (d+shift)%10)
+		}
+	}
+	return table
+}
+
+func mod(a, n int) int {
+	a %= n
+	if a < 0 {
+		a += n
+	}
+	return a
+}
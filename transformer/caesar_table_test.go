@@ -15,7 +15,7 @@ var TestArrayCaesar = []TestCaesar{
 	TestCaesar{"zab", "abc", 1, false},
 	TestCaesar{"abc", "xyz", -3, false},
 	TestCaesar{"abc", "xyz", -3, false},
-	TestCaesar{"abc1", "xyz", -3, true},
+	TestCaesar{"abc\n", "xyz", -3, true},
 }
 
 func TestTableCaesar(t *testing.T) {
@@ -33,3 +33,50 @@ func TestTableCaesar(t *testing.T) {
 
 	}
 }
+
+type TestCaesarOptions struct {
+	input, expected string
+	caesarshift     int
+	options         CaesarOptions
+}
+
+var TestArrayCaesarOptions = []TestCaesarOptions{
+	TestCaesarOptions{"Hello, World!", "Ifmmp, Xpsme!", 1, CaesarOptions{}},
+	TestCaesarOptions{"a b", "b c", 1, CaesarOptions{}},
+	TestCaesarOptions{"XYZ abc", "ABC def", 29, CaesarOptions{}},
+	TestCaesarOptions{"abc", "xyz", -55, CaesarOptions{}},
+	TestCaesarOptions{"a1b9", "b2c0", 1, CaesarOptions{RotateDigits: true}},
+	TestCaesarOptions{"a1b9", "b1c9", 1, CaesarOptions{}},
+	TestCaesarOptions{"Привіт, світ", "Рсігїу, тгїу", 1, CaesarOptions{Alphabet: "ukrainian"}},
+	TestCaesarOptions{"Яя abc", "Аа abc", 1, CaesarOptions{Alphabet: "ukrainian"}},
+	TestCaesarOptions{"abcd", "bcad", 1, CaesarOptions{Alphabet: "abc"}},
+}
+
+func TestTableCaesarOptions(t *testing.T) {
+
+	for _, test := range TestArrayCaesarOptions {
+
+		tr, err := NewCaesarTransformerWithOptions(test.caesarshift, test.options)
+		if err != nil {
+			t.Fatalf("Error creating transformer: %s", err)
+		}
+		result, err := tr.Transform(strings.NewReader(test.input), false)
+		if err != nil {
+			t.Errorf("Error transforming")
+		}
+
+		if result != test.expected {
+			t.Errorf("Error: result = %q, expected = %q", result, test.expected)
+		}
+
+	}
+}
+
+func TestCaesarAlphabetInvalid(t *testing.T) {
+	for _, name := range []string{"a", "abca"} {
+		_, err := CaesarAlphabet(name)
+		if err == nil {
+			t.Errorf("Error: expected error for alphabet %q", name)
+		}
+	}
+}
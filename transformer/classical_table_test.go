@@ -0,0 +1,92 @@
+package transformer
+
+import (
+	"strings"
+	"testing"
+)
+
+type TestClassical struct {
+	name            string
+	params          Params
+	input, expected string
+}
+
+var TestArrayClassical = []TestClassical{
+	TestClassical{"railfence", Params{"rails": "3"}, "WEAREDISCOVEREDFLEEATONCE", "WECRLTEERDSOEEFEAOCAIVDEN"},
+	TestClassical{"railfence", Params{"rails": "3", "decode": "true"}, "WECRLTEERDSOEEFEAOCAIVDEN", "WEAREDISCOVEREDFLEEATONCE"},
+	TestClassical{"railfence", Params{"rails": "2"}, "привіт", "пиірвт"},
+	TestClassical{"railfence", Params{"rails": "5"}, "ab", "ab"},
+	TestClassical{"railfence", Params{"rails": "19"}, "abcdefghijklmnopqrst", "abcdefghijklmnopqrts"},
+	TestClassical{"railfence", Params{"rails": "19", "decode": "true"}, "abcdefghijklmnopqrts", "abcdefghijklmnopqrst"},
+	TestClassical{"railfence", Params{"rails": "200000000"}, "abcdefghijklmnopqrst", "abcdefghijklmnopqrst"},
+	TestClassical{"railfence", Params{"rails": "9223372036854775807", "decode": "true"}, "abc", "abc"},
+	TestClassical{"columnar", Params{"key": "ba"}, "abcd", "bdac"},
+	TestClassical{"columnar", Params{"key": "ba"}, "abcde", "bdace"},
+	TestClassical{"columnar", Params{"key": "ba", "decode": "true"}, "bdace", "abcde"},
+	TestClassical{"playfair", Params{"key": "playfair example"}, "Hide the gold in the tree stump", "bmodzbxdnabekudmuixmmouvif"},
+	TestClassical{"playfair", Params{"key": "playfair example", "decode": "true"}, "bmodzbxdnabekudmuixmmouvif", "hidethegoldinthetrexestump"},
+	TestClassical{"playfair", Params{"key": "monarchy"}, "balloon", "ibsupmna"},
+	TestClassical{"xor", Params{"key": "ICE"}, "Burning 'em, if you ain't quick and nimble\nI go crazy when I hear a cymbal",
+		"0b3637272a2b2e63622c2e69692a23693a2a3c6324202d623d63343c2a26226324272765272a282b2f20430a652e2c652a3124333a653e2b2027630c692b20283165286326302e27282f"},
+	TestClassical{"xor", Params{"key": "ICE", "decode": "true"}, "0b3637272a\n2b2e", "Burning"},
+}
+
+func TestTableClassical(t *testing.T) {
+
+	for _, test := range TestArrayClassical {
+
+		tr, err := New(test.name, test.params)
+		if err != nil {
+			t.Fatalf("Error creating %s: %s", test.name, err)
+		}
+		result := new(strings.Builder)
+		err = tr.TransformStream(strings.NewReader(test.input), result)
+		if err != nil {
+			t.Errorf("Error transforming: %s", err)
+		}
+
+		if result.String() != test.expected {
+			t.Errorf("Error: %s result = %q, expected = %q", test.name, result.String(), test.expected)
+		}
+
+	}
+}
+
+func TestTranspositionRoundTrip(t *testing.T) {
+	input := "The quick brown fox jumps over the lazy dog, двічі."
+	for _, spec := range []string{"railfence:2", "railfence:4", "railfence:9", "railfence:60", "columnar:zebras", "columnar:aaa", "xor:k"} {
+		p, err := ParsePipeline(spec)
+		if err != nil {
+			t.Fatalf("Error parsing %s: %s", spec, err)
+		}
+		inv, err := p.Inverse()
+		if err != nil {
+			t.Fatalf("Error inverting %s: %s", spec, err)
+		}
+		result := new(strings.Builder)
+		err = Pipeline{p, inv}.TransformStream(strings.NewReader(input), result)
+		if err != nil || result.String() != input {
+			t.Errorf("Error: %s round trip = %q, %v", spec, result.String(), err)
+		}
+	}
+}
+
+var TestArrayClassicalInvalid = []TestClassical{
+	TestClassical{"railfence", Params{"rails": "1"}, "", ""},
+	TestClassical{"railfence", nil, "", ""},
+	TestClassical{"columnar", nil, "", ""},
+	TestClassical{"playfair", Params{"key": "ключ"}, "", ""},
+	TestClassical{"xor", nil, "", ""},
+}
+
+func TestTableClassicalInvalid(t *testing.T) {
+
+	for _, test := range TestArrayClassicalInvalid {
+
+		_, err := New(test.name, test.params)
+		if err == nil {
+			t.Errorf("Error: expected error creating %s with %v", test.name, test.params)
+		}
+
+	}
+}
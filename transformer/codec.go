@@ -0,0 +1,592 @@
+package transformer
+
+import (
+	"bufio"
+	"encoding/ascii85"
+	"encoding/base32"
+	"errors"
+	"fmt"
+	"io"
+	"math/big"
+	"strings"
+)
+
+const codecChunkSize = 4 << 10
+
+// Codecs are the transformers that write binary data as text. Each of them
+// decodes its own output when inverted.
+var Codecs = []string{"base64", "hex", "base32", "base58", "ascii85", "z85", "percent"}
+
+var hexSpec = Spec{
+	Name:        "hex",
+	Description: "Hexadecimal encoding",
+	MainParam:   "case",
+	Params: []Param{
+		{Name: "case", Type: ParamString, Default: "lower", Choices: []string{"lower", "upper"}, Description: "Case of the digits a-f, decoding accepts both"},
+	},
+	Invertible: true,
+	New: func(p Params) (StreamTransformer, error) {
+		return &HexTransformer{Upper: p["case"] == "upper"}, nil
+	},
+}
+
+var base32Spec = Spec{
+	Name:        "base32",
+	Description: "Base32 encoding (RFC 4648)",
+	MainParam:   "variant",
+	Params: []Param{
+		{Name: "variant", Type: ParamString, Default: "std", Choices: []string{"std", "hex"}, Description: "Standard or extended hex alphabet"},
+	},
+	Invertible: true,
+	New: func(p Params) (StreamTransformer, error) {
+		return NewBase32VariantTransformer(p["variant"], false)
+	},
+}
+
+var base58Spec = Spec{
+	Name:        "base58",
+	Description: "Base58 encoding with the Bitcoin alphabet, for values up to 4 KiB",
+	Invertible:  true,
+	New: func(p Params) (StreamTransformer, error) {
+		return &Base58Transformer{}, nil
+	},
+}
+
+var ascii85Spec = Spec{
+	Name:        "ascii85",
+	Description: "Ascii85 encoding as used by btoa and PostScript, without <~ ~> delimiters",
+	Invertible:  true,
+	New: func(p Params) (StreamTransformer, error) {
+		return &Ascii85Transformer{}, nil
+	},
+}
+
+var z85Spec = Spec{
+	Name:        "z85",
+	Description: "Z85 encoding (ZeroMQ), input length must be a multiple of 4 bytes",
+	Invertible:  true,
+	New: func(p Params) (StreamTransformer, error) {
+		return &Z85Transformer{}, nil
+	},
+}
+
+var percentSpec = Spec{
+	Name:        "percent",
+	Description: "URL percent-encoding of everything but unreserved characters",
+	MainParam:   "variant",
+	Params: []Param{
+		{Name: "variant", Type: ParamString, Default: "component", Choices: []string{"component", "query"}, Description: "query writes spaces as +"},
+	},
+	Invertible: true,
+	New: func(p Params) (StreamTransformer, error) {
+		return &PercentTransformer{Query: p["variant"] == "query"}, nil
+	},
+}
+
+// encodedReader reads encoded input with line breaks removed and remembers
+// the offset of every kept byte, so that decoding errors can point at the
+// offending byte of the original input.
+type encodedReader struct {
+	br      *bufio.Reader
+	pos     int64
+	buf     []byte
+	offsets []int64
+	eof     bool
+}
+
+func newEncodedReader(in io.Reader) *encodedReader {
+	return &encodedReader{
+		br:      bufio.NewReader(in),
+		buf:     make([]byte, 0, codecChunkSize),
+		offsets: make([]int64, 0, codecChunkSize),
+	}
+}
+
+// fill reads until n bytes are pending or the input ends.
+func (r *encodedReader) fill(n int) error {
+	for len(r.buf) < n && !r.eof {
+		b, err := r.br.ReadByte()
+		if err == io.EOF {
+			r.eof = true
+			break
+		}
+		if err != nil {
+			return err
+		}
+		r.pos++
+		if b == '\r' || b == '\n' {
+			continue
+		}
+		r.buf = append(r.buf, b)
+		r.offsets = append(r.offsets, r.pos-1)
+	}
+	return nil
+}
+
+// consume drops the first n pending bytes.
+func (r *encodedReader) consume(n int) {
+	r.buf = r.buf[:copy(r.buf, r.buf[n:])]
+	r.offsets = r.offsets[:copy(r.offsets, r.offsets[n:])]
+}
+
+// offset returns the input offset of the i-th pending byte, or the end of
+// the input when i is past the pending bytes.
+func (r *encodedReader) offset(i int) int64 {
+	if i < len(r.offsets) {
+		return r.offsets[i]
+	}
+	return r.pos
+}
+
+type HexTransformer struct {
+	Upper  bool
+	Decode bool
+}
+
+func (t *HexTransformer) TransformStream(in io.Reader, out io.Writer) error {
+	if t.Decode {
+		return t.decodeStream(in, out)
+	}
+	digits := "0123456789abcdef"
+	if t.Upper {
+		digits = "0123456789ABCDEF"
+	}
+	buf := make([]byte, codecChunkSize)
+	dst := make([]byte, 2*codecChunkSize)
+	for {
+		n, err := in.Read(buf)
+		for i, b := range buf[:n] {
+			dst[2*i] = digits[b>>4]
+			dst[2*i+1] = digits[b&0x0f]
+		}
+		if _, werr := out.Write(dst[:2*n]); werr != nil {
+			return werr
+		}
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+	}
+}
+
+func (t *HexTransformer) decodeStream(in io.Reader, out io.Writer) error {
+	r := newEncodedReader(in)
+	dst := make([]byte, codecChunkSize/2)
+	for {
+		err := r.fill(codecChunkSize)
+		if err != nil {
+			return err
+		}
+		n := len(r.buf) &^ 1
+		if n == 0 {
+			if len(r.buf) > 0 {
+				return &MalformedInputError{Format: "hex", Offset: r.offset(0)}
+			}
+			return nil
+		}
+		for i := 0; i < n; i += 2 {
+			hi, ok := hexDigit(r.buf[i])
+			if !ok {
+				return &MalformedInputError{Format: "hex", Offset: r.offset(i)}
+			}
+			lo, ok := hexDigit(r.buf[i+1])
+			if !ok {
+				return &MalformedInputError{Format: "hex", Offset: r.offset(i + 1)}
+			}
+			dst[i/2] = hi<<4 | lo
+		}
+		if _, err := out.Write(dst[:n/2]); err != nil {
+			return err
+		}
+		r.consume(n)
+	}
+}
+
+func (t *HexTransformer) Inverse() (StreamTransformer, error) {
+	return &HexTransformer{Upper: t.Upper, Decode: !t.Decode}, nil
+}
+
+func hexDigit(c byte) (byte, bool) {
+	switch {
+	case '0' <= c && c <= '9':
+		return c - '0', true
+	case 'a' <= c && c <= 'f':
+		return c - 'a' + 10, true
+	case 'A' <= c && c <= 'F':
+		return c - 'A' + 10, true
+	}
+	return 0, false
+}
+
+type Base32Transformer struct {
+	Encoding *base32.Encoding
+	Decode   bool
+}
+
+var Base32Variants = map[string]*base32.Encoding{
+	"std": base32.StdEncoding,
+	"hex": base32.HexEncoding,
+}
+
+func NewBase32VariantTransformer(variant string, decode bool) (*Base32Transformer, error) {
+	if variant == "" {
+		variant = "std"
+	}
+	enc, ok := Base32Variants[variant]
+	if !ok {
+		return nil, fmt.Errorf("unknown base32 variant %q", variant)
+	}
+	return &Base32Transformer{Encoding: enc, Decode: decode}, nil
+}
+
+func (t *Base32Transformer) TransformStream(in io.Reader, out io.Writer) error {
+	if t.Decode {
+		return t.decodeStream(in, out)
+	}
+	enc := base32.NewEncoder(t.encoding(), out)
+	_, err := io.Copy(enc, in)
+	if err != nil {
+		return err
+	}
+	return enc.Close()
+}
+
+// decodeStream decodes whole groups of 8 characters at a time. Padding may
+// only end the input.
+func (t *Base32Transformer) decodeStream(in io.Reader, out io.Writer) error {
+	enc := t.encoding()
+	r := newEncodedReader(in)
+	dst := make([]byte, enc.DecodedLen(codecChunkSize))
+	padded := false
+	for {
+		err := r.fill(codecChunkSize)
+		if err != nil {
+			return err
+		}
+		if len(r.buf) == 0 {
+			return nil
+		}
+		if padded {
+			return &MalformedInputError{Format: "base32", Offset: r.offset(0)}
+		}
+		n, err := enc.Decode(dst, r.buf)
+		if _, werr := out.Write(dst[:n]); werr != nil {
+			return werr
+		}
+		var corrupt base32.CorruptInputError
+		if errors.As(err, &corrupt) {
+			return &MalformedInputError{Format: "base32", Offset: r.offset(int(corrupt))}
+		}
+		if err != nil {
+			return err
+		}
+		padded = r.buf[len(r.buf)-1] == '='
+		r.consume(len(r.buf))
+	}
+}
+
+func (t *Base32Transformer) Inverse() (StreamTransformer, error) {
+	return &Base32Transformer{Encoding: t.Encoding, Decode: !t.Decode}, nil
+}
+
+func (t *Base32Transformer) encoding() *base32.Encoding {
+	if t.Encoding == nil {
+		return base32.StdEncoding
+	}
+	return t.Encoding
+}
+
+const base58Alphabet = "123456789ABCDEFGHJKLMNPQRSTUVWXYZabcdefghijkmnopqrstuvwxyz"
+
+// Base58 input is limited because the conversion takes quadratic time: at
+// most maxBase58Input bytes are encoded, and at most maxBase58Digits digits,
+// enough for that many bytes, are decoded.
+const (
+	maxBase58Input  = 4 << 10
+	maxBase58Digits = maxBase58Input * 3 / 2
+)
+
+// Base58Transformer treats the whole input as one big number, so it reads
+// the input into memory and takes quadratic time. It is meant for keys and
+// identifiers rather than files, longer inputs are rejected.
+type Base58Transformer struct {
+	Decode bool
+}
+
+func (t *Base58Transformer) TransformStream(in io.Reader, out io.Writer) error {
+	if t.Decode {
+		return t.decodeStream(in, out)
+	}
+	data, err := io.ReadAll(io.LimitReader(in, maxBase58Input+1))
+	if err != nil {
+		return err
+	}
+	if len(data) > maxBase58Input {
+		return &MalformedInputError{Format: "base58", Offset: maxBase58Input, Hint: fmt.Sprintf("base58 encodes at most %d bytes, use base64 or hex for longer data", maxBase58Input)}
+	}
+	zeros := 0
+	for zeros < len(data) && data[zeros] == 0 {
+		zeros++
+	}
+	num := new(big.Int).SetBytes(data)
+	base, mod := big.NewInt(58), new(big.Int)
+	var digits []byte
+	for num.Sign() > 0 {
+		num.DivMod(num, base, mod)
+		digits = append(digits, base58Alphabet[mod.Int64()])
+	}
+	for i := 0; i < zeros; i++ {
+		digits = append(digits, base58Alphabet[0])
+	}
+	reverseBytes(digits)
+	_, err = out.Write(digits)
+	return err
+}
+
+func (t *Base58Transformer) decodeStream(in io.Reader, out io.Writer) error {
+	r := newEncodedReader(in)
+	num, base, digit := new(big.Int), big.NewInt(58), new(big.Int)
+	zeros, leading, digits := 0, true, 0
+	for {
+		err := r.fill(codecChunkSize)
+		if err != nil {
+			return err
+		}
+		if len(r.buf) == 0 {
+			break
+		}
+		for i, c := range r.buf {
+			d := strings.IndexByte(base58Alphabet, c)
+			if d < 0 {
+				return &MalformedInputError{Format: "base58", Offset: r.offset(i)}
+			}
+			if digits++; digits > maxBase58Digits {
+				return &MalformedInputError{Format: "base58", Offset: r.offset(i), Hint: fmt.Sprintf("base58 decodes at most %d digits", maxBase58Digits)}
+			}
+			if leading && d == 0 {
+				zeros++
+				continue
+			}
+			leading = false
+			num.Mul(num, base)
+			num.Add(num, digit.SetInt64(int64(d)))
+		}
+		r.consume(len(r.buf))
+	}
+	data := append(make([]byte, zeros), num.Bytes()...)
+	_, err := out.Write(data)
+	return err
+}
+
+func (t *Base58Transformer) Inverse() (StreamTransformer, error) {
+	return &Base58Transformer{Decode: !t.Decode}, nil
+}
+
+type Ascii85Transformer struct {
+	Decode bool
+}
+
+func (t *Ascii85Transformer) TransformStream(in io.Reader, out io.Writer) error {
+	if t.Decode {
+		return t.decodeStream(in, out)
+	}
+	enc := ascii85.NewEncoder(out)
+	_, err := io.Copy(enc, in)
+	if err != nil {
+		return err
+	}
+	return enc.Close()
+}
+
+// decodeStream decodes the input in chunks, keeping an incomplete group for
+// the next chunk. A "z" expands to four zero bytes.
+func (t *Ascii85Transformer) decodeStream(in io.Reader, out io.Writer) error {
+	r := newEncodedReader(in)
+	dst := make([]byte, 4*codecChunkSize)
+	for {
+		err := r.fill(codecChunkSize)
+		if err != nil {
+			return err
+		}
+		if len(r.buf) == 0 {
+			return nil
+		}
+		ndst, nsrc, err := ascii85.Decode(dst, r.buf, r.eof)
+		var corrupt ascii85.CorruptInputError
+		if errors.As(err, &corrupt) {
+			return &MalformedInputError{Format: "ascii85", Offset: r.offset(int(corrupt))}
+		}
+		if err != nil {
+			return err
+		}
+		if _, err := out.Write(dst[:ndst]); err != nil {
+			return err
+		}
+		r.consume(nsrc)
+		if r.eof && nsrc == 0 {
+			return &MalformedInputError{Format: "ascii85", Offset: r.offset(0)}
+		}
+	}
+}
+
+func (t *Ascii85Transformer) Inverse() (StreamTransformer, error) {
+	return &Ascii85Transformer{Decode: !t.Decode}, nil
+}
+
+const z85Alphabet = "0123456789abcdefghijklmnopqrstuvwxyzABCDEFGHIJKLMNOPQRSTUVWXYZ.-:+=^!/*?&<>()[]{}@%$#"
+
+type Z85Transformer struct {
+	Decode bool
+}
+
+func (t *Z85Transformer) TransformStream(in io.Reader, out io.Writer) error {
+	if t.Decode {
+		return t.decodeStream(in, out)
+	}
+	br := bufio.NewReader(in)
+	bw := bufio.NewWriter(out)
+	var group [4]byte
+	var digits [5]byte
+	for {
+		n, err := io.ReadFull(br, group[:])
+		if err == io.EOF {
+			break
+		}
+		if err == io.ErrUnexpectedEOF {
+			return fmt.Errorf("z85 input length must be a multiple of 4 bytes, %d bytes left over", n)
+		}
+		if err != nil {
+			return err
+		}
+		v := uint32(group[0])<<24 | uint32(group[1])<<16 | uint32(group[2])<<8 | uint32(group[3])
+		for i := 4; i >= 0; i-- {
+			digits[i] = z85Alphabet[v%85]
+			v /= 85
+		}
+		if _, err := bw.Write(digits[:]); err != nil {
+			return err
+		}
+	}
+	return bw.Flush()
+}
+
+func (t *Z85Transformer) decodeStream(in io.Reader, out io.Writer) error {
+	r := newEncodedReader(in)
+	dst := make([]byte, 4*codecChunkSize/5)
+	for {
+		err := r.fill(codecChunkSize - codecChunkSize%5)
+		if err != nil {
+			return err
+		}
+		if len(r.buf) == 0 {
+			return nil
+		}
+		if len(r.buf)%5 != 0 {
+			return &MalformedInputError{Format: "z85", Offset: r.offset(len(r.buf) - len(r.buf)%5)}
+		}
+		for g := 0; g < len(r.buf); g += 5 {
+			var v uint64
+			for i := g; i < g+5; i++ {
+				d := strings.IndexByte(z85Alphabet, r.buf[i])
+				if d < 0 {
+					return &MalformedInputError{Format: "z85", Offset: r.offset(i)}
+				}
+				v = v*85 + uint64(d)
+			}
+			if v > 0xffffffff {
+				return &MalformedInputError{Format: "z85", Offset: r.offset(g)}
+			}
+			dst[g/5*4], dst[g/5*4+1], dst[g/5*4+2], dst[g/5*4+3] = byte(v>>24), byte(v>>16), byte(v>>8), byte(v)
+		}
+		if _, err := out.Write(dst[:len(r.buf)/5*4]); err != nil {
+			return err
+		}
+		r.consume(len(r.buf))
+	}
+}
+
+func (t *Z85Transformer) Inverse() (StreamTransformer, error) {
+	return &Z85Transformer{Decode: !t.Decode}, nil
+}
+
+// PercentTransformer escapes every byte except the unreserved characters of
+// RFC 3986. The query variant writes spaces as "+" like HTML forms do.
+type PercentTransformer struct {
+	Query  bool
+	Decode bool
+}
+
+func (t *PercentTransformer) TransformStream(in io.Reader, out io.Writer) error {
+	if t.Decode {
+		return t.decodeStream(in, out)
+	}
+	const digits = "0123456789ABCDEF"
+	br := bufio.NewReader(in)
+	bw := bufio.NewWriter(out)
+	for {
+		b, err := br.ReadByte()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return err
+		}
+		switch {
+		case isUnreserved(b):
+			err = bw.WriteByte(b)
+		case b == ' ' && t.Query:
+			err = bw.WriteByte('+')
+		default:
+			_, err = bw.Write([]byte{'%', digits[b>>4], digits[b&0x0f]})
+		}
+		if err != nil {
+			return err
+		}
+	}
+	return bw.Flush()
+}
+
+func (t *PercentTransformer) decodeStream(in io.Reader, out io.Writer) error {
+	br := bufio.NewReader(in)
+	bw := bufio.NewWriter(out)
+	var pos int64
+	for {
+		b, err := br.ReadByte()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return err
+		}
+		switch {
+		case b == '%':
+			var escape [2]byte
+			_, err = io.ReadFull(br, escape[:])
+			hi, okHi := hexDigit(escape[0])
+			lo, okLo := hexDigit(escape[1])
+			if err != nil || !okHi || !okLo {
+				return &MalformedInputError{Format: "percent-encoded", Offset: pos}
+			}
+			pos += 2
+			b = hi<<4 | lo
+		case b == '+' && t.Query:
+			b = ' '
+		}
+		pos++
+		if err := bw.WriteByte(b); err != nil {
+			return err
+		}
+	}
+	return bw.Flush()
+}
+
+func (t *PercentTransformer) Inverse() (StreamTransformer, error) {
+	return &PercentTransformer{Query: t.Query, Decode: !t.Decode}, nil
+}
+
+func isUnreserved(b byte) bool {
+	return 'a' <= b && b <= 'z' || 'A' <= b && b <= 'Z' || '0' <= b && b <= '9' ||
+		b == '-' || b == '_' || b == '.' || b == '~'
+}
@@ -0,0 +1,156 @@
+package transformer
+
+import (
+	"errors"
+	"strings"
+	"testing"
+	"testing/iotest"
+)
+
+type TestCodec struct {
+	name            string
+	params          Params
+	input, expected string
+}
+
+var TestArrayCodec = []TestCodec{
+	TestCodec{"hex", nil, "\x01\xab", "01ab"},
+	TestCodec{"hex", Params{"case": "upper"}, "\x01\xab", "01AB"},
+	TestCodec{"hex", Params{"decode": "true"}, "01Ab\r\nff", "\x01\xab\xff"},
+	TestCodec{"base32", nil, "foobar", "MZXW6YTBOI======"},
+	TestCodec{"base32", Params{"variant": "hex"}, "foobar", "CPNMUOJ1E8======"},
+	TestCodec{"base32", Params{"decode": "true"}, "MZXW6YTB\nOI======", "foobar"},
+	TestCodec{"base58", nil, "Hello World!", "2NEpo7TZRRrLZSi2U"},
+	TestCodec{"base58", nil, "\x00\x00\x01", "112"},
+	TestCodec{"base58", Params{"decode": "true"}, "112", "\x00\x00\x01"},
+	TestCodec{"base58", Params{"decode": "true"}, "", ""},
+	TestCodec{"ascii85", nil, "\x00\x00\x00\x00hello", "zBOu!rDZ"},
+	TestCodec{"ascii85", Params{"decode": "true"}, "zBOu!\nrDZ", "\x00\x00\x00\x00hello"},
+	TestCodec{"z85", nil, "\x86\x4f\xd2\x6f\xb5\x59\xf7\x5b", "HelloWorld"},
+	TestCodec{"z85", Params{"decode": "true"}, "HelloWorld", "\x86\x4f\xd2\x6f\xb5\x59\xf7\x5b"},
+	TestCodec{"percent", nil, "a b&c/é~", "a%20b%26c%2F%C3%A9~"},
+	TestCodec{"percent", Params{"variant": "query"}, "a b&c", "a+b%26c"},
+	TestCodec{"percent", Params{"variant": "query", "decode": "true"}, "a+b%26c%2f", "a b&c/"},
+	TestCodec{"percent", Params{"decode": "true"}, "a+b", "a+b"},
+}
+
+func TestTableCodec(t *testing.T) {
+
+	for _, test := range TestArrayCodec {
+
+		tr, err := New(test.name, test.params)
+		if err != nil {
+			t.Fatalf("Error creating %s: %s", test.name, err)
+		}
+		result := new(strings.Builder)
+		err = tr.TransformStream(iotest.OneByteReader(strings.NewReader(test.input)), result)
+		if err != nil {
+			t.Errorf("Error transforming %q with %s: %s", test.input, test.name, err)
+		}
+
+		if result.String() != test.expected {
+			t.Errorf("Error: %s result = %q, expected = %q", test.name, result.String(), test.expected)
+		}
+
+	}
+}
+
+func TestCodecRoundTrip(t *testing.T) {
+	input := strings.Repeat("\x00\xffbinary\x00\x00\x00\x00 data/", 700)
+	for _, name := range Codecs {
+		tr, err := New(name, nil)
+		if err != nil {
+			t.Fatalf("Error creating %s: %s", name, err)
+		}
+		inv, err := Inverse(tr)
+		if err != nil {
+			t.Fatalf("Error inverting %s: %s", name, err)
+		}
+		in := input
+		if name == "base58" {
+			in = input[:maxBase58Input]
+		}
+		result := new(strings.Builder)
+		err = Pipeline{tr, inv}.TransformStream(strings.NewReader(in), result)
+		if err != nil {
+			t.Errorf("Error: %s round trip: %s", name, err)
+		}
+		if result.String() != in {
+			t.Errorf("Error: %s round trip changed the input", name)
+		}
+	}
+}
+
+type TestCodecMalformed struct {
+	name   string
+	input  string
+	offset int64
+}
+
+var TestArrayCodecMalformed = []TestCodecMalformed{
+	TestCodecMalformed{"hex", "0g", 1},
+	TestCodecMalformed{"hex", "abc", 2},
+	TestCodecMalformed{"hex", "01\nzz", 3},
+	TestCodecMalformed{"base32", "MZXW6YT!", 7},
+	TestCodecMalformed{"base32", "MY======MY======", 2},
+	TestCodecMalformed{"base58", "abc0", 3},
+	TestCodecMalformed{"base58", strings.Repeat("z", maxBase58Digits+1), maxBase58Digits},
+	TestCodecMalformed{"base58", "1\n" + strings.Repeat("z", maxBase58Digits), maxBase58Digits + 1},
+	TestCodecMalformed{"ascii85", "87cU~", 4},
+	TestCodecMalformed{"z85", "Hell", 0},
+	TestCodecMalformed{"z85", "HelloWor\"d", 8},
+	TestCodecMalformed{"percent", "a%2", 1},
+	TestCodecMalformed{"percent", "ab%zz", 2},
+}
+
+func TestTableCodecMalformed(t *testing.T) {
+
+	for _, test := range TestArrayCodecMalformed {
+
+		tr, err := New(test.name, Params{"decode": "true"})
+		if err != nil {
+			t.Fatalf("Error creating %s: %s", test.name, err)
+		}
+		err = tr.TransformStream(strings.NewReader(test.input), new(strings.Builder))
+		var malformed *MalformedInputError
+		if !errors.As(err, &malformed) {
+			t.Errorf("Error: expected malformed input error for %s %q, got %v", test.name, test.input, err)
+			continue
+		}
+		if malformed.Offset != test.offset {
+			t.Errorf("Error: %s %q offset = %d, expected = %d", test.name, test.input, malformed.Offset, test.offset)
+		}
+
+	}
+}
+
+func TestBase58Limit(t *testing.T) {
+	tr := &Base58Transformer{}
+	encoded := new(strings.Builder)
+	err := tr.TransformStream(strings.NewReader(strings.Repeat("\xff", maxBase58Input)), encoded)
+	if err != nil {
+		t.Fatalf("Error encoding %d bytes: %s", maxBase58Input, err)
+	}
+	if encoded.Len() > maxBase58Digits {
+		t.Errorf("Error: %d bytes encode to %d digits, more than can be decoded", maxBase58Input, encoded.Len())
+	}
+	decoded := new(strings.Builder)
+	err = (&Base58Transformer{Decode: true}).TransformStream(strings.NewReader(encoded.String()), decoded)
+	if err != nil || decoded.String() != strings.Repeat("\xff", maxBase58Input) {
+		t.Errorf("Error: decoding %d bytes fails: %v", maxBase58Input, err)
+	}
+
+	err = tr.TransformStream(strings.NewReader(strings.Repeat("a", 50000)), new(strings.Builder))
+	var malformed *MalformedInputError
+	if !errors.As(err, &malformed) || malformed.Offset != maxBase58Input {
+		t.Errorf("Error: encoding 50000 bytes gives %v", err)
+	}
+}
+
+func TestZ85Length(t *testing.T) {
+	tr := &Z85Transformer{}
+	err := tr.TransformStream(strings.NewReader("abc"), new(strings.Builder))
+	if err == nil {
+		t.Errorf("Error: expected error for input length not a multiple of 4")
+	}
+}
@@ -0,0 +1,265 @@
+package transformer
+
+import (
+	"bufio"
+	"compress/flate"
+	"compress/gzip"
+	"compress/zlib"
+	"errors"
+	"fmt"
+	"io"
+)
+
+const (
+	FormatGzip = "gzip"
+	FormatZlib = "zlib"
+)
+
+// DefaultCompressionLevel is the level used when none is given, the same
+// one the standard library picks for flate.DefaultCompression.
+const DefaultCompressionLevel = 6
+
+// Compressions are the compression transformers, decompressing when inverted.
+var Compressions = []string{FormatGzip, FormatZlib}
+
+var levelParam = Param{
+	Name:        "level",
+	Type:        ParamInt,
+	Default:     fmt.Sprint(DefaultCompressionLevel),
+	Description: "Compression level from 1 (fastest) to 9 (smallest), 0 stores, -2 Huffman coding only",
+}
+
+var gzipSpec = Spec{
+	Name:         FormatGzip,
+	Description:  "gzip compression, binary output to chain into base64",
+	MainParam:    "level",
+	Params:       []Param{levelParam},
+	Invertible:   true,
+	BinaryOutput: true,
+	New: func(p Params) (StreamTransformer, error) {
+		return newCompressTransformer(FormatGzip, p)
+	},
+}
+
+var zlibSpec = Spec{
+	Name:         FormatZlib,
+	Description:  "zlib compression, binary output to chain into base64",
+	MainParam:    "level",
+	Params:       []Param{levelParam},
+	Invertible:   true,
+	BinaryOutput: true,
+	New: func(p Params) (StreamTransformer, error) {
+		return newCompressTransformer(FormatZlib, p)
+	},
+}
+
+func newCompressTransformer(format string, p Params) (StreamTransformer, error) {
+	level := DefaultCompressionLevel
+	if p["level"] != "" {
+		level, _ = p.Int("level")
+	}
+	return NewCompressTransformer(format, level)
+}
+
+// CompressionStats are the sizes of the data a compression transformer
+// handled in a run.
+type CompressionStats struct {
+	Level      int
+	Original   int64
+	Compressed int64
+}
+
+// TransformStreamStats runs tr like TransformStream and returns the stats of
+// its first compression step. ok is false if tr does not compress. The stats
+// belong to this run, so tr may run concurrently. A compression step wrapped
+// by Lines, Field or Select runs once per line or value, and the sizes of
+// those runs add up.
+func TransformStreamStats(tr StreamTransformer, in io.Reader, out io.Writer) (stats CompressionStats, ok bool, err error) {
+	tr, ok = recordStats(tr, &stats)
+	err = tr.TransformStream(in, out)
+	return stats, ok, err
+}
+
+// recordStats returns a copy of tr whose first compression step stores its
+// stats in stats.
+func recordStats(tr StreamTransformer, stats *CompressionStats) (StreamTransformer, bool) {
+	switch t := tr.(type) {
+	case Pipeline:
+		for i, step := range t {
+			if step, ok := recordStats(step, stats); ok {
+				p := append(Pipeline{}, t...)
+				p[i] = step
+				return p, true
+			}
+		}
+	case *lineTransformer:
+		if inner, ok := recordStats(t.tr, stats); ok {
+			return &lineTransformer{tr: inner}, true
+		}
+	case *fieldTransformer:
+		if inner, ok := recordStats(t.tr, stats); ok {
+			return &fieldTransformer{tr: inner, n: t.n, delimiter: t.delimiter}, true
+		}
+	case *jsonSelectTransformer:
+		if inner, ok := recordStats(t.tr, stats); ok {
+			return &jsonSelectTransformer{tr: inner, sel: t.sel}, true
+		}
+	case *yamlSelectTransformer:
+		if inner, ok := recordStats(t.tr, stats); ok {
+			return &yamlSelectTransformer{tr: inner, sel: t.sel}, true
+		}
+	case *CompressTransformer:
+		return &statsRecorder{t: t, stats: stats}, true
+	}
+	return tr, false
+}
+
+// statsRecorder runs a compression transformer and adds its stats up.
+type statsRecorder struct {
+	t     *CompressTransformer
+	stats *CompressionStats
+}
+
+func (r *statsRecorder) TransformStream(in io.Reader, out io.Writer) error {
+	stats, err := r.t.run(in, out)
+	r.stats.Level = r.t.Level
+	r.stats.Original += stats.Original
+	r.stats.Compressed += stats.Compressed
+	return err
+}
+
+// CompressTransformer compresses its input in the gzip or zlib format, or
+// decompresses it.
+type CompressTransformer struct {
+	Format     string
+	Level      int
+	Decompress bool
+}
+
+func NewCompressTransformer(format string, level int) (*CompressTransformer, error) {
+	if format != FormatGzip && format != FormatZlib {
+		return nil, fmt.Errorf("unknown compression format %q", format)
+	}
+	if level < flate.HuffmanOnly || level > flate.BestCompression {
+		return nil, fmt.Errorf("invalid compression level %d", level)
+	}
+	return &CompressTransformer{Format: format, Level: level}, nil
+}
+
+func (t *CompressTransformer) TransformStream(in io.Reader, out io.Writer) error {
+	_, err := t.run(in, out)
+	return err
+}
+
+// run transforms the input and returns the sizes it read and wrote.
+func (t *CompressTransformer) run(in io.Reader, out io.Writer) (CompressionStats, error) {
+	if t.Decompress {
+		return t.decompress(in, out)
+	}
+	r := &countingReader{r: bufio.NewReader(in)}
+	w := &countingWriter{w: out}
+	var zw io.WriteCloser
+	var err error
+	if t.Format == FormatZlib {
+		zw, err = zlib.NewWriterLevel(w, t.Level)
+	} else {
+		zw, err = gzip.NewWriterLevel(w, t.Level)
+	}
+	if err != nil {
+		return CompressionStats{}, err
+	}
+	_, err = io.Copy(zw, r)
+	if err != nil {
+		return CompressionStats{}, err
+	}
+	err = zw.Close()
+	if err != nil {
+		return CompressionStats{}, err
+	}
+	return CompressionStats{Level: t.Level, Original: r.n, Compressed: w.n}, nil
+}
+
+// decompress reads the input through a byte counter that the decompressor
+// uses directly, so errors can name how far into the input they occurred.
+func (t *CompressTransformer) decompress(in io.Reader, out io.Writer) (CompressionStats, error) {
+	r := &countingReader{r: bufio.NewReader(in)}
+	w := &countingWriter{w: out}
+	var zr io.ReadCloser
+	var err error
+	if t.Format == FormatZlib {
+		zr, err = zlib.NewReader(r)
+	} else {
+		zr, err = gzip.NewReader(r)
+	}
+	if err == nil {
+		_, err = io.Copy(w, zr)
+		if err == nil {
+			err = zr.Close()
+		}
+	}
+	if w.err != nil {
+		return CompressionStats{}, w.err
+	}
+	if err != nil {
+		return CompressionStats{}, t.malformed(err, r.n)
+	}
+	return CompressionStats{Level: t.Level, Original: w.n, Compressed: r.n}, nil
+}
+
+func (t *CompressTransformer) malformed(err error, read int64) error {
+	var corrupt flate.CorruptInputError
+	switch {
+	case errors.Is(err, gzip.ErrHeader), errors.Is(err, zlib.ErrHeader):
+		return &MalformedInputError{Format: t.Format, Offset: 0}
+	case errors.Is(err, io.EOF), errors.Is(err, io.ErrUnexpectedEOF):
+		return &MalformedInputError{Format: t.Format, Offset: read, Hint: "input is truncated"}
+	case errors.As(err, &corrupt), errors.Is(err, gzip.ErrChecksum), errors.Is(err, zlib.ErrChecksum):
+		if read > 0 {
+			read--
+		}
+		return &MalformedInputError{Format: t.Format, Offset: read}
+	}
+	return err
+}
+
+func (t *CompressTransformer) Inverse() (StreamTransformer, error) {
+	return &CompressTransformer{Format: t.Format, Level: t.Level, Decompress: !t.Decompress}, nil
+}
+
+func (t *CompressTransformer) ReadsBinary() bool {
+	return t.Decompress
+}
+
+type countingReader struct {
+	r *bufio.Reader
+	n int64
+}
+
+func (r *countingReader) Read(p []byte) (int, error) {
+	n, err := r.r.Read(p)
+	r.n += int64(n)
+	return n, err
+}
+
+func (r *countingReader) ReadByte() (byte, error) {
+	b, err := r.r.ReadByte()
+	if err == nil {
+		r.n++
+	}
+	return b, err
+}
+
+type countingWriter struct {
+	w   io.Writer
+	n   int64
+	err error
+}
+
+func (w *countingWriter) Write(p []byte) (int, error) {
+	n, err := w.w.Write(p)
+	w.n += int64(n)
+	if err != nil {
+		w.err = err
+	}
+	return n, err
+}
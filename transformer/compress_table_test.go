@@ -0,0 +1,176 @@
+package transformer
+
+import (
+	"errors"
+	"io"
+	"strings"
+	"sync"
+	"testing"
+)
+
+type TestCompress struct {
+	name   string
+	params Params
+	input  string
+}
+
+var TestArrayCompress = []TestCompress{
+	TestCompress{"gzip", nil, strings.Repeat("abc", 1000)},
+	TestCompress{"gzip", Params{"level": "1"}, "hello"},
+	TestCompress{"gzip", Params{"level": "0"}, "stored"},
+	TestCompress{"zlib", Params{"level": "9"}, strings.Repeat("xyz", 1000)},
+	TestCompress{"zlib", Params{"level": "-2"}, ""},
+}
+
+func TestTableCompress(t *testing.T) {
+
+	for _, test := range TestArrayCompress {
+
+		tr, err := New(test.name, test.params)
+		if err != nil {
+			t.Fatalf("Error creating %s: %s", test.name, err)
+		}
+		inv, err := Inverse(tr)
+		if err != nil {
+			t.Fatalf("Error inverting %s: %s", test.name, err)
+		}
+		compressed := new(strings.Builder)
+		stats, ok, err := TransformStreamStats(tr, strings.NewReader(test.input), compressed)
+		if err != nil {
+			t.Errorf("Error compressing: %s", err)
+		}
+		if !ok || stats.Original != int64(len(test.input)) || stats.Compressed != int64(compressed.Len()) {
+			t.Errorf("Error: stats = %+v, expected %d -> %d bytes", stats, len(test.input), compressed.Len())
+		}
+		result := new(strings.Builder)
+		err = inv.TransformStream(strings.NewReader(compressed.String()), result)
+		if err != nil {
+			t.Errorf("Error decompressing: %s", err)
+		}
+		if result.String() != test.input {
+			t.Errorf("Error: %s round trip changed the input", test.name)
+		}
+
+	}
+}
+
+func TestCompressPipelineStats(t *testing.T) {
+	p, err := ParsePipeline("gzip:9 | base64")
+	if err != nil {
+		t.Fatalf("Error parsing pipeline: %s", err)
+	}
+	result := new(strings.Builder)
+	stats, ok, err := TransformStreamStats(p, strings.NewReader(strings.Repeat("a", 100)), result)
+	if err != nil {
+		t.Fatalf("Error transforming: %s", err)
+	}
+	if !ok || stats.Level != 9 || stats.Original != 100 || stats.Compressed >= 100 {
+		t.Errorf("Error: stats = %+v", stats)
+	}
+	_, ok, _ = TransformStreamStats(Pipeline{NewReverseTransformer()}, strings.NewReader("abc"), io.Discard)
+	if ok {
+		t.Errorf("Error: stats reported for a pipeline without compression")
+	}
+}
+
+type TestCompressStats struct {
+	tr       func() (StreamTransformer, error)
+	input    string
+	ok       bool
+	original int64
+}
+
+func newGzip() StreamTransformer {
+	gz, _ := New("gzip", nil)
+	return gz
+}
+
+func gzipBase64() Pipeline {
+	return Pipeline{newGzip(), &Base64Transformer{}}
+}
+
+var TestArrayCompressStats = []TestCompressStats{
+	TestCompressStats{func() (StreamTransformer, error) { return ParsePipeline("gzip | base64") }, "hello", true, 5},
+	TestCompressStats{func() (StreamTransformer, error) { return Pipeline{Lines(newGzip()), &Base64Transformer{}}, nil }, "ab\ncde\n", true, 5},
+	TestCompressStats{func() (StreamTransformer, error) { return Lines(gzipBase64()), nil }, "ab\r\ncde\n\nf", true, 6},
+	TestCompressStats{func() (StreamTransformer, error) { return Field(gzipBase64(), 2, ',') }, "x,abc\ny,de\nz\n", true, 5},
+	TestCompressStats{func() (StreamTransformer, error) { return Select(gzipBase64(), "$.a[*]", FormatJSON) }, `{"a":["hello","de"],"b":"x"}`, true, 7},
+	TestCompressStats{func() (StreamTransformer, error) { return Select(gzipBase64(), "$.a", FormatYAML) }, "a: hello\nb: x\n", true, 5},
+	TestCompressStats{func() (StreamTransformer, error) { return Lines(NewReverseTransformer()), nil }, "ab\n", false, 0},
+}
+
+func TestTableCompressStats(t *testing.T) {
+
+	for i, test := range TestArrayCompressStats {
+
+		tr, err := test.tr()
+		if err != nil {
+			t.Fatalf("Error creating transformer %d: %s", i, err)
+		}
+		stats, ok, err := TransformStreamStats(tr, strings.NewReader(test.input), io.Discard)
+		if err != nil {
+			t.Errorf("Error transforming %q: %s", test.input, err)
+		}
+		if ok != test.ok || stats.Original != test.original || (ok && (stats.Compressed == 0 || stats.Level != DefaultCompressionLevel)) {
+			t.Errorf("Error: %q gives stats = %+v, %v, expected %d original bytes", test.input, stats, ok, test.original)
+		}
+	}
+}
+
+func TestCompressConcurrentStats(t *testing.T) {
+	p, err := ParsePipeline("reverse | gzip | base64")
+	if err != nil {
+		t.Fatalf("Error parsing pipeline: %s", err)
+	}
+	var wg sync.WaitGroup
+	for i := 1; i <= 20; i++ {
+		wg.Add(1)
+		go func(size int) {
+			defer wg.Done()
+			stats, ok, err := TransformStreamStats(p, strings.NewReader(strings.Repeat("a", size)), io.Discard)
+			if err != nil || !ok || stats.Original != int64(size) {
+				t.Errorf("Error: %d bytes give stats = %+v, %v, %v", size, stats, ok, err)
+			}
+		}(i * 1000)
+	}
+	wg.Wait()
+}
+
+type TestDecompressMalformed struct {
+	name, input string
+	offset      int64
+}
+
+var TestArrayDecompressMalformed = []TestDecompressMalformed{
+	TestDecompressMalformed{"gzip", "not gzip at all", 0},
+	TestDecompressMalformed{"gzip", "\x1f\x8b\x08\x00\x00\x00\x00\x00\x00\xff", 10},
+	TestDecompressMalformed{"zlib", "xx", 0},
+}
+
+func TestTableDecompressMalformed(t *testing.T) {
+
+	for _, test := range TestArrayDecompressMalformed {
+
+		tr, err := New(test.name, Params{"decode": "true"})
+		if err != nil {
+			t.Fatalf("Error creating %s: %s", test.name, err)
+		}
+		err = tr.TransformStream(strings.NewReader(test.input), new(strings.Builder))
+		var malformed *MalformedInputError
+		if !errors.As(err, &malformed) {
+			t.Errorf("Error: expected malformed input error for %q, got %v", test.input, err)
+			continue
+		}
+		if malformed.Offset != test.offset {
+			t.Errorf("Error: %q offset = %d, expected = %d", test.input, malformed.Offset, test.offset)
+		}
+
+	}
+}
+
+func TestCompressLevel(t *testing.T) {
+	_, err := New("gzip", Params{"level": "10"})
+	if err == nil {
+		t.Errorf("Error: expected error for compression level 10")
+	}
+}
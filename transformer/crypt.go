@@ -0,0 +1,333 @@
+package transformer
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/binary"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+
+	"golang.org/x/crypto/scrypt"
+)
+
+// The aes transformer writes an envelope: a header followed by the
+// plaintext sealed in chunks of cryptChunkSize bytes, each with its own
+// nonce, in the spirit of the STREAM construction. The nonce is the random
+// prefix from the header, the chunk counter and a flag set on the last
+// chunk, so chunks cannot be reordered, dropped or cut off unnoticed. The
+// header is authenticated with every chunk.
+//
+//	magic "TRAE" | version | kdf | [scrypt log2 N | r | p | salt] | nonce prefix
+const (
+	cryptMagic       = "TRAE"
+	cryptVersion     = 1
+	cryptChunkSize   = 64 << 10
+	cryptNoncePrefix = 7
+	cryptSaltSize    = 16
+	cryptKeySize     = 32
+
+	kdfNone   = 0
+	kdfScrypt = 1
+
+	scryptLogN = 15
+	scryptR    = 8
+	scryptP    = 1
+	// scryptMaxLogN bounds the cost an envelope may ask for on decryption,
+	// r and p have to be scryptR and scryptP.
+	scryptMaxLogN = 20
+)
+
+// DefaultKeyID names the key used when an aes step does not choose one.
+const DefaultKeyID = "default"
+
+var aesSpec = Spec{
+	Name:        "aes",
+	Description: "AES-256-GCM authenticated encryption with a server-side key, binary output",
+	MainParam:   "key_id",
+	Params: []Param{
+		{Name: "key_id", Type: ParamString, Default: DefaultKeyID, Description: "ID of a key loaded by the server or given to the CLI, never the key itself"},
+	},
+	Invertible:   true,
+	BinaryOutput: true,
+	New: func(p Params) (StreamTransformer, error) {
+		id := p["key_id"]
+		if id == "" {
+			id = DefaultKeyID
+		}
+		key, ok := LookupKey(id)
+		if !ok {
+			return nil, fmt.Errorf("unknown key id %q", id)
+		}
+		return &AESGCMTransformer{Key: key}, nil
+	},
+}
+
+// Key is the secret of the aes transformer, either a raw 256-bit key or a
+// passphrase that a key is derived from with scrypt.
+type Key struct {
+	Raw        []byte
+	Passphrase []byte
+}
+
+func PassphraseKey(passphrase string) (Key, error) {
+	if passphrase == "" {
+		return Key{}, errors.New("empty passphrase")
+	}
+	return Key{Passphrase: []byte(passphrase)}, nil
+}
+
+// LoadKeyFile reads a key file holding either 32 raw bytes or 64 hex
+// digits.
+func LoadKeyFile(path string) (Key, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return Key{}, err
+	}
+	if len(data) == cryptKeySize {
+		return Key{Raw: data}, nil
+	}
+	raw, err := hex.DecodeString(strings.TrimSpace(string(data)))
+	if err != nil || len(raw) != cryptKeySize {
+		return Key{}, fmt.Errorf("%s: expected %d raw bytes or %d hex digits", path, cryptKeySize, 2*cryptKeySize)
+	}
+	return Key{Raw: raw}, nil
+}
+
+var keyring = struct {
+	sync.RWMutex
+	keys map[string]Key
+}{keys: map[string]Key{}}
+
+// RegisterKey makes a key available to aes steps under id, replacing any
+// key registered before.
+func RegisterKey(id string, key Key) {
+	keyring.Lock()
+	defer keyring.Unlock()
+	keyring.keys[id] = key
+}
+
+func LookupKey(id string) (Key, bool) {
+	keyring.RLock()
+	defer keyring.RUnlock()
+	key, ok := keyring.keys[id]
+	return key, ok
+}
+
+// LoadKeys registers every "<id>.key" file of dir under its id and returns
+// the ids. A key that fails to load is reported and skipped.
+func LoadKeys(dir string) ([]string, []error) {
+	paths, err := filepath.Glob(filepath.Join(dir, "*.key"))
+	if err != nil {
+		return nil, []error{err}
+	}
+	var ids []string
+	var errs []error
+	for _, path := range paths {
+		key, err := LoadKeyFile(path)
+		if err != nil {
+			errs = append(errs, err)
+			continue
+		}
+		id := strings.TrimSuffix(filepath.Base(path), ".key")
+		RegisterKey(id, key)
+		ids = append(ids, id)
+	}
+	return ids, errs
+}
+
+type AESGCMTransformer struct {
+	Key     Key
+	Decrypt bool
+}
+
+func (t *AESGCMTransformer) TransformStream(in io.Reader, out io.Writer) error {
+	if t.Decrypt {
+		return t.decrypt(in, out)
+	}
+	header := []byte{}
+	header = append(header, cryptMagic...)
+	header = append(header, cryptVersion)
+	var key []byte
+	if t.Key.Passphrase != nil {
+		salt := make([]byte, cryptSaltSize)
+		if _, err := rand.Read(salt); err != nil {
+			return err
+		}
+		header = append(header, kdfScrypt, scryptLogN, scryptR, scryptP)
+		header = append(header, salt...)
+		var err error
+		key, err = scrypt.Key(t.Key.Passphrase, salt, 1<<scryptLogN, scryptR, scryptP, cryptKeySize)
+		if err != nil {
+			return err
+		}
+	} else {
+		header = append(header, kdfNone)
+		key = t.Key.Raw
+	}
+	prefix := make([]byte, cryptNoncePrefix)
+	if _, err := rand.Read(prefix); err != nil {
+		return err
+	}
+	header = append(header, prefix...)
+	aead, err := newGCM(key)
+	if err != nil {
+		return err
+	}
+	if _, err := out.Write(header); err != nil {
+		return err
+	}
+
+	br := bufio.NewReaderSize(in, cryptChunkSize)
+	chunk := make([]byte, cryptChunkSize)
+	sealed := make([]byte, 0, cryptChunkSize+aead.Overhead())
+	for counter := uint32(0); ; counter++ {
+		n, err := io.ReadFull(br, chunk)
+		if err != nil && err != io.EOF && err != io.ErrUnexpectedEOF {
+			return err
+		}
+		last := n < cryptChunkSize
+		if !last {
+			if _, err := br.Peek(1); err == io.EOF {
+				last = true
+			}
+		}
+		sealed = aead.Seal(sealed[:0], chunkNonce(prefix, counter, last), chunk[:n], header)
+		if _, err := out.Write(sealed); err != nil {
+			return err
+		}
+		if last {
+			return nil
+		}
+		if counter == 1<<32-1 {
+			return errors.New("input too large to encrypt")
+		}
+	}
+}
+
+func (t *AESGCMTransformer) decrypt(in io.Reader, out io.Writer) error {
+	br := bufio.NewReaderSize(in, cryptChunkSize)
+	header, key, err := t.readHeader(br)
+	if err != nil {
+		return err
+	}
+	aead, err := newGCM(key)
+	if err != nil {
+		return err
+	}
+	prefix := header[len(header)-cryptNoncePrefix:]
+	offset := int64(len(header))
+	sealed := make([]byte, cryptChunkSize+aead.Overhead())
+	plain := make([]byte, 0, cryptChunkSize)
+	for counter := uint32(0); ; counter++ {
+		n, err := io.ReadFull(br, sealed)
+		if err != nil && err != io.EOF && err != io.ErrUnexpectedEOF {
+			return err
+		}
+		last := n < len(sealed)
+		if !last {
+			if _, err := br.Peek(1); err == io.EOF {
+				last = true
+			}
+		}
+		plain, err = aead.Open(plain[:0], chunkNonce(prefix, counter, last), sealed[:n], header)
+		if err != nil {
+			return &MalformedInputError{Format: "aes-gcm", Offset: offset, Hint: "wrong key or corrupted data"}
+		}
+		if _, err := out.Write(plain); err != nil {
+			return err
+		}
+		if last {
+			return nil
+		}
+		offset += int64(n)
+	}
+}
+
+// readHeader reads the envelope header and derives the key it was
+// encrypted with.
+func (t *AESGCMTransformer) readHeader(br *bufio.Reader) ([]byte, []byte, error) {
+	header := make([]byte, len(cryptMagic)+2)
+	_, err := io.ReadFull(br, header)
+	if err != nil || !bytes.Equal(header[:len(cryptMagic)], []byte(cryptMagic)) {
+		return nil, nil, &MalformedInputError{Format: "aes-gcm", Offset: 0, Hint: "not an encrypted envelope"}
+	}
+	if header[len(cryptMagic)] != cryptVersion {
+		return nil, nil, &MalformedInputError{Format: "aes-gcm", Offset: int64(len(cryptMagic)), Hint: "unsupported version"}
+	}
+	kdf := header[len(cryptMagic)+1]
+	rest := cryptNoncePrefix
+	switch kdf {
+	case kdfNone:
+		if t.Key.Raw == nil {
+			return nil, nil, &MalformedInputError{Format: "aes-gcm", Offset: int64(len(header) - 1), Hint: "encrypted with a key file, not a passphrase"}
+		}
+	case kdfScrypt:
+		if t.Key.Passphrase == nil {
+			return nil, nil, &MalformedInputError{Format: "aes-gcm", Offset: int64(len(header) - 1), Hint: "encrypted with a passphrase, not a key file"}
+		}
+		rest += 3 + cryptSaltSize
+	default:
+		return nil, nil, &MalformedInputError{Format: "aes-gcm", Offset: int64(len(header) - 1), Hint: "unknown key derivation"}
+	}
+	offset := int64(len(header))
+	header = append(header, make([]byte, rest)...)
+	if _, err := io.ReadFull(br, header[offset:]); err != nil {
+		return nil, nil, &MalformedInputError{Format: "aes-gcm", Offset: offset, Hint: "input is truncated"}
+	}
+	if kdf == kdfNone {
+		return header, t.Key.Raw, nil
+	}
+	logN, r, p := header[offset], header[offset+1], header[offset+2]
+	if logN > scryptMaxLogN {
+		return nil, nil, &MalformedInputError{Format: "aes-gcm", Offset: offset, Hint: "scrypt cost too high"}
+	}
+	// memory and time grow with r and p as well, only the values written
+	// by encryption are accepted
+	if r != scryptR || p != scryptP {
+		return nil, nil, &MalformedInputError{Format: "aes-gcm", Offset: offset + 1, Hint: "unsupported scrypt parameters"}
+	}
+	salt := header[offset+3 : offset+3+cryptSaltSize]
+	key, err := scrypt.Key(t.Key.Passphrase, salt, 1<<logN, int(r), int(p), cryptKeySize)
+	if err != nil {
+		return nil, nil, &MalformedInputError{Format: "aes-gcm", Offset: offset, Hint: err.Error()}
+	}
+	return header, key, nil
+}
+
+func (t *AESGCMTransformer) Inverse() (StreamTransformer, error) {
+	return &AESGCMTransformer{Key: t.Key, Decrypt: !t.Decrypt}, nil
+}
+
+func (t *AESGCMTransformer) ReadsBinary() bool {
+	return t.Decrypt
+}
+
+func newGCM(key []byte) (cipher.AEAD, error) {
+	if len(key) != cryptKeySize {
+		return nil, fmt.Errorf("expected a %d-byte key, got %d bytes", cryptKeySize, len(key))
+	}
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
+
+func chunkNonce(prefix []byte, counter uint32, last bool) []byte {
+	nonce := make([]byte, 0, cryptNoncePrefix+5)
+	nonce = append(nonce, prefix...)
+	nonce = binary.BigEndian.AppendUint32(nonce, counter)
+	if last {
+		return append(nonce, 1)
+	}
+	return append(nonce, 0)
+}
@@ -0,0 +1,159 @@
+package transformer
+
+import (
+	"bytes"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+var testKey = Key{Raw: bytes.Repeat([]byte{7}, cryptKeySize)}
+
+func encryptDecrypt(t *testing.T, enc, dec *AESGCMTransformer, input []byte) ([]byte, []byte, error) {
+	sealed := new(bytes.Buffer)
+	err := enc.TransformStream(bytes.NewReader(input), sealed)
+	if err != nil {
+		t.Fatalf("Error encrypting: %s", err)
+	}
+	plain := new(bytes.Buffer)
+	err = dec.TransformStream(bytes.NewReader(sealed.Bytes()), plain)
+	return sealed.Bytes(), plain.Bytes(), err
+}
+
+var TestArrayCryptSize = []int{0, 1, cryptChunkSize - 1, cryptChunkSize, cryptChunkSize + 1, 3*cryptChunkSize + 100}
+
+func TestTableCryptRoundTrip(t *testing.T) {
+
+	for _, size := range TestArrayCryptSize {
+
+		input := bytes.Repeat([]byte("0123456789abcdef"), size/16+1)[:size]
+		enc := &AESGCMTransformer{Key: testKey}
+		sealed, plain, err := encryptDecrypt(t, enc, &AESGCMTransformer{Key: testKey, Decrypt: true}, input)
+		if err != nil {
+			t.Errorf("Error decrypting %d bytes: %s", size, err)
+		}
+		if !bytes.Equal(plain, input) {
+			t.Errorf("Error: round trip of %d bytes changed the input", size)
+		}
+		if bytes.Contains(sealed, []byte("0123456789abcdef")) {
+			t.Errorf("Error: plaintext found in the envelope")
+		}
+
+	}
+}
+
+func TestCryptPassphrase(t *testing.T) {
+	key, err := PassphraseKey("correct horse")
+	if err != nil {
+		t.Fatalf("Error creating key: %s", err)
+	}
+	enc := &AESGCMTransformer{Key: key}
+	_, plain, err := encryptDecrypt(t, enc, &AESGCMTransformer{Key: key, Decrypt: true}, []byte("attack at dawn"))
+	if err != nil || string(plain) != "attack at dawn" {
+		t.Errorf("Error: result = %q, %v", plain, err)
+	}
+
+	wrong, _ := PassphraseKey("battery staple")
+	_, _, err = encryptDecrypt(t, enc, &AESGCMTransformer{Key: wrong, Decrypt: true}, []byte("attack at dawn"))
+	var malformed *MalformedInputError
+	if !errors.As(err, &malformed) {
+		t.Errorf("Error: expected malformed input error for a wrong passphrase, got %v", err)
+	}
+
+	_, _, err = encryptDecrypt(t, enc, &AESGCMTransformer{Key: testKey, Decrypt: true}, []byte("attack at dawn"))
+	if !errors.As(err, &malformed) {
+		t.Errorf("Error: expected malformed input error for a key file, got %v", err)
+	}
+
+	// an envelope asking for a huge scrypt block size or parallelism is
+	// rejected before the key is derived
+	sealed, _, _ := encryptDecrypt(t, enc, &AESGCMTransformer{Key: key, Decrypt: true}, []byte("attack at dawn"))
+	for _, i := range []int{len(cryptMagic) + 3, len(cryptMagic) + 4} {
+		tampered := append([]byte{}, sealed...)
+		tampered[i] = 255
+		err = (&AESGCMTransformer{Key: key, Decrypt: true}).TransformStream(bytes.NewReader(tampered), new(bytes.Buffer))
+		if !errors.As(err, &malformed) || !strings.Contains(err.Error(), "scrypt parameters") {
+			t.Errorf("Error: expected unsupported scrypt parameters for byte %d, got %v", i, err)
+		}
+	}
+}
+
+type TestCryptTamper struct {
+	name   string
+	tamper func([]byte) []byte
+}
+
+var TestArrayCryptTamper = []TestCryptTamper{
+	TestCryptTamper{"flipped ciphertext", func(b []byte) []byte { b[len(b)-20] ^= 1; return b }},
+	TestCryptTamper{"flipped header", func(b []byte) []byte { b[8] ^= 1; return b }},
+	TestCryptTamper{"truncated chunk", func(b []byte) []byte { return b[:len(b)-1] }},
+	TestCryptTamper{"dropped last chunk", func(b []byte) []byte { return b[:len(b)-116] }},
+	TestCryptTamper{"header only", func(b []byte) []byte { return b[:13] }},
+	TestCryptTamper{"not an envelope", func(b []byte) []byte { return []byte("hello") }},
+}
+
+func TestTableCryptTamper(t *testing.T) {
+	input := bytes.Repeat([]byte{'x'}, cryptChunkSize+100)
+	sealed := new(bytes.Buffer)
+	err := (&AESGCMTransformer{Key: testKey}).TransformStream(bytes.NewReader(input), sealed)
+	if err != nil {
+		t.Fatalf("Error encrypting: %s", err)
+	}
+
+	for _, test := range TestArrayCryptTamper {
+
+		tampered := test.tamper(append([]byte{}, sealed.Bytes()...))
+		err := (&AESGCMTransformer{Key: testKey, Decrypt: true}).TransformStream(bytes.NewReader(tampered), new(bytes.Buffer))
+		var malformed *MalformedInputError
+		if !errors.As(err, &malformed) {
+			t.Errorf("Error: %s: expected malformed input error, got %v", test.name, err)
+		}
+
+	}
+}
+
+func TestCryptKeys(t *testing.T) {
+	dir := t.TempDir()
+	err := os.WriteFile(filepath.Join(dir, "raw.key"), testKey.Raw, 0600)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = os.WriteFile(filepath.Join(dir, "hex.key"), []byte(strings.Repeat("07", cryptKeySize)+"\n"), 0600)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = os.WriteFile(filepath.Join(dir, "short.key"), []byte("0707"), 0600)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ids, errs := LoadKeys(dir)
+	if len(ids) != 2 || len(errs) != 1 {
+		t.Errorf("Error: loaded %v, errors %v", ids, errs)
+	}
+	key, ok := LookupKey("hex")
+	if !ok || !bytes.Equal(key.Raw, testKey.Raw) {
+		t.Errorf("Error: hex key not loaded")
+	}
+
+	p, err := ParsePipeline("aes:hex | base64")
+	if err != nil {
+		t.Fatalf("Error parsing pipeline: %s", err)
+	}
+	inv, err := p.Inverse()
+	if err != nil {
+		t.Fatalf("Error inverting pipeline: %s", err)
+	}
+	result := new(strings.Builder)
+	err = Pipeline{p, inv}.TransformStream(strings.NewReader("secret"), result)
+	if err != nil || result.String() != "secret" {
+		t.Errorf("Error: result = %q, %v", result.String(), err)
+	}
+
+	_, err = New("aes", Params{"key_id": "missing"})
+	if err == nil {
+		t.Errorf("Error: expected error for an unknown key id")
+	}
+}
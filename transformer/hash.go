@@ -0,0 +1,139 @@
+package transformer
+
+import (
+	"crypto/hmac"
+	"crypto/md5"
+	"crypto/sha1"
+	"crypto/sha256"
+	"crypto/sha512"
+	"encoding/base64"
+	"encoding/hex"
+	"fmt"
+	"hash"
+	"io"
+
+	"golang.org/x/crypto/blake2b"
+)
+
+const (
+	DigestHex    = "hex"
+	DigestBase64 = "base64"
+)
+
+// Hashes are the transformers that replace their input with its digest.
+var Hashes = []string{"sha256", "sha512", "sha1", "md5", "blake2b", "hmac-sha256"}
+
+var digestParam = Param{
+	Name:        "encoding",
+	Type:        ParamString,
+	Default:     DigestHex,
+	Choices:     []string{DigestHex, DigestBase64},
+	Description: "Text encoding of the digest",
+}
+
+func hashSpec(name, description string, legacy bool, newHash func() hash.Hash) Spec {
+	return Spec{
+		Name:        name,
+		Description: description,
+		MainParam:   digestParam.Name,
+		Params:      []Param{digestParam},
+		Legacy:      legacy,
+		New: func(p Params) (StreamTransformer, error) {
+			return &HashTransformer{Hash: newHash, Encoding: p[digestParam.Name]}, nil
+		},
+	}
+}
+
+var (
+	sha256Spec = hashSpec("sha256", "SHA-256 digest", false, sha256.New)
+	sha512Spec = hashSpec("sha512", "SHA-512 digest", false, sha512.New)
+	sha1Spec   = hashSpec("sha1", "SHA-1 digest, not collision resistant", true, sha1.New)
+	md5Spec    = hashSpec("md5", "MD5 digest, not collision resistant", true, md5.New)
+)
+
+var blake2bSpec = Spec{
+	Name:        "blake2b",
+	Description: "BLAKE2b digest",
+	MainParam:   digestParam.Name,
+	Params: []Param{
+		digestParam,
+		{Name: "size", Type: ParamInt, Default: "512", Choices: []string{"256", "384", "512"}, Description: "Digest size in bits"},
+	},
+	New: func(p Params) (StreamTransformer, error) {
+		size := blake2b.Size
+		if p["size"] != "" {
+			bits, _ := p.Int("size")
+			size = bits / 8
+		}
+		newHash := func() hash.Hash {
+			h, _ := blake2b.New(size, nil)
+			return h
+		}
+		return &HashTransformer{Hash: newHash, Encoding: p[digestParam.Name]}, nil
+	},
+}
+
+var hmacSHA256Spec = Spec{
+	Name:        "hmac-sha256",
+	Description: "HMAC-SHA256 of the input with a server-side key",
+	MainParam:   "key_id",
+	Params: []Param{
+		{Name: "key_id", Type: ParamString, Default: DefaultKeyID, Description: "ID of a key loaded by the server or given to the CLI, never the key itself"},
+		digestParam,
+	},
+	New: func(p Params) (StreamTransformer, error) {
+		id := p["key_id"]
+		if id == "" {
+			id = DefaultKeyID
+		}
+		key, ok := LookupKey(id)
+		if !ok {
+			return nil, fmt.Errorf("unknown key id %q", id)
+		}
+		secret := key.Raw
+		if secret == nil {
+			secret = key.Passphrase
+		}
+		newHash := func() hash.Hash {
+			return hmac.New(sha256.New, secret)
+		}
+		return &HashTransformer{Hash: newHash, Encoding: p[digestParam.Name]}, nil
+	},
+}
+
+// HashTransformer replaces its input with the encoded digest of it. The
+// input is hashed as it streams in.
+type HashTransformer struct {
+	Hash     func() hash.Hash
+	Encoding string
+}
+
+// Sum returns the raw digest of the input.
+func (t *HashTransformer) Sum(in io.Reader) ([]byte, error) {
+	h := t.Hash()
+	_, err := io.Copy(h, in)
+	if err != nil {
+		return nil, err
+	}
+	return h.Sum(nil), nil
+}
+
+func (t *HashTransformer) TransformStream(in io.Reader, out io.Writer) error {
+	sum, err := t.Sum(in)
+	if err != nil {
+		return err
+	}
+	var digest string
+	if t.Encoding == DigestBase64 {
+		digest = base64.StdEncoding.EncodeToString(sum)
+	} else {
+		digest = hex.EncodeToString(sum)
+	}
+	_, err = io.WriteString(out, digest)
+	return err
+}
+
+// ReadsBinary reports true because a digest covers every byte it is given.
+func (t *HashTransformer) ReadsBinary() bool {
+	return true
+}
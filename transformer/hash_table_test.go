@@ -0,0 +1,64 @@
+package transformer
+
+import (
+	"strings"
+	"testing"
+)
+
+type TestHash struct {
+	name            string
+	params          Params
+	input, expected string
+}
+
+var TestArrayHash = []TestHash{
+	TestHash{"sha256", nil, "abc", "ba7816bf8f01cfea414140de5dae2223b00361a396177a9cb410ff61f20015ad"},
+	TestHash{"sha256", Params{"encoding": "base64"}, "abc", "ungWv48Bz+pBQUDeXa4iI7ADYaOWF3qctBD/YfIAFa0="},
+	TestHash{"sha512", nil, "", "cf83e1357eefb8bdf1542850d66d8007d620e4050b5715dc83f4a921d36ce9ce47d0d13c5d85f2b0ff8318d2877eec2f63b931bd47417a81a538327af927da3e"},
+	TestHash{"sha1", nil, "abc", "a9993e364706816aba3e25717850c26c9cd0d89d"},
+	TestHash{"md5", nil, "abc", "900150983cd24fb0d6963f7d28e17f72"},
+	TestHash{"blake2b", nil, "abc", "ba80a53f981c4d0d6a2797b69f12f6e94c212f14685ac4b74b12bb6fdbffa2d17d87c5392aab792dc252d5de4533cc9518d38aa8dbf1925ab92386edd4009923"},
+	TestHash{"blake2b", Params{"size": "256"}, "abc", "bddd813c634239723171ef3fee98579b94964e3bb1cb3e427262c8c068d52319"},
+	TestHash{"hmac-sha256", Params{"key_id": "hash-test"}, "abc", "9c196e32dc0175f86f4b1cb89289d6619de6bee699e4c378e68309ed97a1a6ab"},
+}
+
+func TestTableHash(t *testing.T) {
+	RegisterKey("hash-test", Key{Passphrase: []byte("key")})
+
+	for _, test := range TestArrayHash {
+
+		tr, err := New(test.name, test.params)
+		if err != nil {
+			t.Fatalf("Error creating %s: %s", test.name, err)
+		}
+		result := new(strings.Builder)
+		err = tr.TransformStream(strings.NewReader(test.input), result)
+		if err != nil {
+			t.Errorf("Error transforming: %s", err)
+		}
+
+		if result.String() != test.expected {
+			t.Errorf("Error: %s result = %q, expected = %q", test.name, result.String(), test.expected)
+		}
+
+	}
+}
+
+func TestHashNotInvertible(t *testing.T) {
+	tr, err := NewHash("sha256:base64")
+	if err != nil {
+		t.Fatalf("Error creating hash: %s", err)
+	}
+	_, err = Inverse(tr)
+	if err != ErrNotInvertible {
+		t.Errorf("Error: expected ErrNotInvertible, got %v", err)
+	}
+	_, err = NewHash("caesar:3")
+	if err == nil {
+		t.Errorf("Error: expected error for a transformer that is not a hash")
+	}
+	spec, _ := Lookup("md5")
+	if !spec.Legacy {
+		t.Errorf("Error: md5 not flagged as legacy")
+	}
+}
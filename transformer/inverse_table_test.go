@@ -0,0 +1,58 @@
+package transformer
+
+import (
+	"errors"
+	"io"
+	"strings"
+	"testing"
+)
+
+type TestInverse struct {
+	tr    StreamTransformer
+	input string
+}
+
+var TestArrayInverse = []TestInverse{
+	TestInverse{NewCaesarTransformer(3), "abcxyz"},
+	TestInverse{NewCaesarTransformer(-7), "hello"},
+	TestInverse{NewReverseTransformer(), "12345"},
+	TestInverse{NewBase64Transformer(), "Man"},
+	TestInverse{&Base64Transformer{Encoding: Base64Variants["rawurl"]}, "\xfb\xff"},
+}
+
+func TestTableInverse(t *testing.T) {
+
+	for _, test := range TestArrayInverse {
+
+		sb := new(strings.Builder)
+		err := test.tr.TransformStream(strings.NewReader(test.input), sb)
+		if err != nil {
+			t.Fatalf("Error transforming: %s", err)
+		}
+		inv, err := Inverse(test.tr)
+		if err != nil {
+			t.Fatalf("Error inverting: %s", err)
+		}
+		result := new(strings.Builder)
+		err = inv.TransformStream(strings.NewReader(sb.String()), result)
+		if err != nil {
+			t.Errorf("Error transforming back: %s", err)
+		}
+
+		if result.String() != test.input {
+			t.Errorf("Error: result = %q, expected = %q", result.String(), test.input)
+		}
+
+	}
+}
+
+type notInvertible struct{}
+
+func (notInvertible) TransformStream(in io.Reader, out io.Writer) error { return nil }
+
+func TestNotInvertible(t *testing.T) {
+	_, err := Inverse(notInvertible{})
+	if !errors.Is(err, ErrNotInvertible) {
+		t.Errorf("Error: expected ErrNotInvertible, got %v", err)
+	}
+}
@@ -0,0 +1,190 @@
+package transformer
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+	"unicode/utf8"
+)
+
+// DefaultDelimiter separates the fields of CSV input.
+const DefaultDelimiter = ','
+
+// Lines makes tr transform every line of its input on its own. The line
+// endings, LF or CRLF, are copied unchanged.
+func Lines(tr StreamTransformer) StreamTransformer {
+	return &lineTransformer{tr: tr}
+}
+
+type lineTransformer struct {
+	tr StreamTransformer
+}
+
+func (t *lineTransformer) TransformStream(in io.Reader, out io.Writer) error {
+	return forEachLine(in, out, func(line string, offset int64) (string, error) {
+		return transformString(t.tr, line, offset)
+	})
+}
+
+func (t *lineTransformer) Inverse() (StreamTransformer, error) {
+	inv, err := Inverse(t.tr)
+	if err != nil {
+		return nil, err
+	}
+	return &lineTransformer{tr: inv}, nil
+}
+
+// Field makes tr transform only the n-th field, counted from 1, of every
+// line of delimiter separated input such as CSV or TSV. Quoted fields are
+// unquoted before and quoted again after the transformation, the other
+// fields are copied unchanged, and so are lines with fewer fields. A quoted
+// field cannot span lines.
+func Field(tr StreamTransformer, n int, delimiter rune) (StreamTransformer, error) {
+	if n < 1 {
+		return nil, fmt.Errorf("expected field number from 1, got %d", n)
+	}
+	if delimiter == '"' || delimiter == '\r' || delimiter == '\n' || !utf8.ValidRune(delimiter) {
+		return nil, fmt.Errorf("invalid field delimiter %q", delimiter)
+	}
+	return &fieldTransformer{tr: tr, n: n, delimiter: delimiter}, nil
+}
+
+// ParseDelimiter reads a field delimiter, a single character or "tab".
+func ParseDelimiter(s string) (rune, error) {
+	switch s {
+	case "":
+		return DefaultDelimiter, nil
+	case "tab", `\t`:
+		return '\t', nil
+	}
+	r, size := utf8.DecodeRuneInString(s)
+	if size != len(s) {
+		return 0, fmt.Errorf("expected a single character field delimiter, got %q", s)
+	}
+	return r, nil
+}
+
+type fieldTransformer struct {
+	tr        StreamTransformer
+	n         int
+	delimiter rune
+}
+
+func (t *fieldTransformer) TransformStream(in io.Reader, out io.Writer) error {
+	return forEachLine(in, out, func(line string, offset int64) (string, error) {
+		start, end, quoted, err := t.find(line)
+		if err != nil {
+			return "", &MalformedInputError{Format: "CSV", Offset: offset + int64(start), Hint: err.Error()}
+		}
+		if start < 0 {
+			return line, nil
+		}
+		field := line[start:end]
+		if quoted {
+			field = strings.ReplaceAll(field[1:len(field)-1], `""`, `"`)
+		}
+		result, err := transformString(t.tr, field, offset+int64(start))
+		if err != nil {
+			return "", err
+		}
+		if quoted || strings.ContainsAny(result, "\"\r\n") || strings.ContainsRune(result, t.delimiter) {
+			result = `"` + strings.ReplaceAll(result, `"`, `""`) + `"`
+		}
+		return line[:start] + result + line[end:], nil
+	})
+}
+
+// find returns the byte span of the field in line, quotes included, or a
+// negative start if the line has fewer fields.
+func (t *fieldTransformer) find(line string) (start, end int, quoted bool, err error) {
+	delim := string(t.delimiter)
+	for i := 1; ; i++ {
+		quoted = strings.HasPrefix(line[start:], `"`)
+		if quoted {
+			end = start + 1
+			for {
+				next := strings.IndexByte(line[end:], '"')
+				if next < 0 {
+					return start, 0, false, errors.New("unterminated quoted field")
+				}
+				end += next + 1
+				if !strings.HasPrefix(line[end:], `"`) {
+					break
+				}
+				end++
+			}
+			if end < len(line) && !strings.HasPrefix(line[end:], delim) {
+				return end, 0, false, errors.New("text after quoted field")
+			}
+		} else {
+			next := strings.Index(line[start:], delim)
+			if next < 0 {
+				end = len(line)
+			} else {
+				end = start + next
+			}
+		}
+		if i == t.n {
+			return start, end, quoted, nil
+		}
+		if end == len(line) {
+			return -1, 0, false, nil
+		}
+		start = end + len(delim)
+	}
+}
+
+func (t *fieldTransformer) Inverse() (StreamTransformer, error) {
+	inv, err := Inverse(t.tr)
+	if err != nil {
+		return nil, err
+	}
+	return &fieldTransformer{tr: inv, n: t.n, delimiter: t.delimiter}, nil
+}
+
+// transformString runs tr on s, moving the offsets of malformed input
+// errors to where s starts in the whole input.
+func transformString(tr StreamTransformer, s string, offset int64) (string, error) {
+	var sb strings.Builder
+	err := tr.TransformStream(strings.NewReader(s), &sb)
+	var malformed *MalformedInputError
+	if errors.As(err, &malformed) {
+		shifted := *malformed
+		shifted.Offset += offset
+		return "", &shifted
+	}
+	return sb.String(), err
+}
+
+// forEachLine writes f of every line of the input, given without its line
+// ending and with the offset where it starts, followed by the line ending.
+func forEachLine(in io.Reader, out io.Writer, f func(line string, offset int64) (string, error)) error {
+	br := bufio.NewReader(in)
+	bw := bufio.NewWriter(out)
+	var offset int64
+	for {
+		line, err := br.ReadString('\n')
+		if err != nil && err != io.EOF {
+			return err
+		}
+		content := strings.TrimSuffix(line, "\n")
+		if len(content) < len(line) {
+			content = strings.TrimSuffix(content, "\r")
+		}
+		if line != "" {
+			result, ferr := f(content, offset)
+			if ferr != nil {
+				return ferr
+			}
+			if _, werr := bw.WriteString(result + line[len(content):]); werr != nil {
+				return werr
+			}
+		}
+		offset += int64(len(line))
+		if err == io.EOF {
+			return bw.Flush()
+		}
+	}
+}
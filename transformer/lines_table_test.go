@@ -0,0 +1,85 @@
+package transformer
+
+import (
+	"errors"
+	"strings"
+	"testing"
+)
+
+type TestLines struct {
+	opts            Options
+	input, expected string
+}
+
+var TestArrayLines = []TestLines{
+	TestLines{Options{Lines: true}, "abc\r\ndef\n\nxyz", "cba\r\nfed\n\nzyx"},
+	TestLines{Options{Lines: true, CaesarShift: 1}, "ab\nyz\n", "bc\nza\n"},
+	TestLines{Options{Lines: true, Base64: true}, "a\nbc", "YQ==\nYmM="},
+	TestLines{Options{Field: 2}, "id,name\r\n1,alice\r\n2\n", "id,eman\r\n1,ecila\r\n2\n"},
+	TestLines{Options{Field: 2, CaesarShift: 1}, `1,"a, ""b""",c`, `1,"b, ""c""",c`},
+	TestLines{Options{Field: 1, Pipeline: "caesar:1"}, "z,", "a,"},
+	TestLines{Options{Field: 3, Delimiter: "tab", Base64: true}, "a\tb\tc\td\na\t\t", "a\tb\tYw==\td\na\t\t"},
+	TestLines{Options{Field: 2, Delimiter: ";"}, "a;b,c\n", "a;c,b\n"},
+	TestLines{Options{Field: 2}, "a, b", "a,b "},
+}
+
+func TestTableLines(t *testing.T) {
+
+	for _, test := range TestArrayLines {
+
+		tr, err := NewTransformer(test.opts)
+		if err != nil {
+			t.Fatalf("Error creating transformer: %s", err)
+		}
+		result := new(strings.Builder)
+		err = tr.TransformStream(strings.NewReader(test.input), result)
+		if err != nil {
+			t.Errorf("Error transforming %q: %s", test.input, err)
+		}
+
+		if result.String() != test.expected {
+			t.Errorf("Error: result = %q, expected = %q", result.String(), test.expected)
+		}
+
+		inv, err := Inverse(tr)
+		if err != nil {
+			t.Fatalf("Error inverting: %s", err)
+		}
+		result.Reset()
+		err = inv.TransformStream(strings.NewReader(test.expected), result)
+		if err != nil {
+			t.Errorf("Error transforming back %q: %s", test.expected, err)
+		}
+		if result.String() != test.input {
+			t.Errorf("Error: round trip = %q, expected = %q", result.String(), test.input)
+		}
+
+	}
+}
+
+func TestLinesErrors(t *testing.T) {
+	tr, err := NewTransformer(Options{Lines: true, Base64: true, Base64Decode: true})
+	if err != nil {
+		t.Fatalf("Error creating transformer: %s", err)
+	}
+	err = tr.TransformStream(strings.NewReader("YQ==\nY!=="), new(strings.Builder))
+	var malformed *MalformedInputError
+	if !errors.As(err, &malformed) || malformed.Offset != 6 {
+		t.Errorf("Error: expected malformed input at offset 6, got %v", err)
+	}
+
+	tr, err = NewTransformer(Options{Field: 2})
+	if err != nil {
+		t.Fatalf("Error creating transformer: %s", err)
+	}
+	err = tr.TransformStream(strings.NewReader("a,b\nc,\"d"), new(strings.Builder))
+	if !errors.As(err, &malformed) || malformed.Format != "CSV" || malformed.Offset != 6 {
+		t.Errorf("Error: expected malformed CSV at offset 6, got %v", err)
+	}
+
+	for _, opts := range []Options{{Field: -1}, {Field: 1, Delimiter: `"`}, {Field: 1, Delimiter: "ab"}} {
+		if _, err := NewTransformer(opts); err == nil {
+			t.Errorf("Error: expected error for %+v", opts)
+		}
+	}
+}
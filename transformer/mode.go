@@ -0,0 +1,233 @@
+package transformer
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"unicode/utf8"
+)
+
+// Modes in which text transformers read their input.
+const (
+	// ModeUTF8 requires valid UTF-8 input and rejects anything else.
+	ModeUTF8 = "utf8"
+	// ModeBytes treats the input as opaque bytes. Every byte is handled as
+	// the character with the same code (Latin-1), so letter ciphers only
+	// touch ASCII letters and binary data passes through unchanged.
+	ModeBytes = "bytes"
+)
+
+var modeParam = Param{
+	Name:        "mode",
+	Type:        ParamString,
+	Default:     ModeUTF8,
+	Choices:     []string{ModeUTF8, ModeBytes},
+	Description: "Read the input as UTF-8 text or as opaque bytes",
+}
+
+// WithMode wraps a text transformer so that it reads its input in the given
+// mode.
+func WithMode(tr StreamTransformer, mode string) (StreamTransformer, error) {
+	switch mode {
+	case "", ModeUTF8:
+		return &utf8Transformer{tr: tr}, nil
+	case ModeBytes:
+		return &bytesTransformer{tr: tr}, nil
+	}
+	return nil, fmt.Errorf("unknown mode %q, expected utf8 or bytes", mode)
+}
+
+type utf8Transformer struct {
+	tr StreamTransformer
+}
+
+func (t *utf8Transformer) TransformStream(in io.Reader, out io.Writer) error {
+	return t.tr.TransformStream(&utf8Validator{r: in, buf: make([]byte, 32<<10)}, out)
+}
+
+func (t *utf8Transformer) Inverse() (StreamTransformer, error) {
+	inv, err := Inverse(t.tr)
+	if err != nil {
+		return nil, err
+	}
+	return &utf8Transformer{tr: inv}, nil
+}
+
+type bytesTransformer struct {
+	tr StreamTransformer
+}
+
+func (t *bytesTransformer) TransformStream(in io.Reader, out io.Writer) error {
+	w := &latin1Writer{w: out}
+	err := t.tr.TransformStream(&latin1Reader{r: in, buf: make([]byte, 16<<10)}, w)
+	if err != nil {
+		return err
+	}
+	return w.Close()
+}
+
+func (t *bytesTransformer) Inverse() (StreamTransformer, error) {
+	inv, err := Inverse(t.tr)
+	if err != nil {
+		return nil, err
+	}
+	return &bytesTransformer{tr: inv}, nil
+}
+
+// utf8Validator passes valid UTF-8 through and fails with a
+// MalformedInputError at the first invalid byte.
+type utf8Validator struct {
+	r          io.Reader
+	buf        []byte
+	start, end int
+	validEnd   int
+	off        int64
+	err        error
+	invalid    error
+}
+
+func (v *utf8Validator) Read(p []byte) (int, error) {
+	for {
+		if v.start < v.validEnd {
+			n := copy(p, v.buf[v.start:v.validEnd])
+			v.start += n
+			v.off += int64(n)
+			return n, nil
+		}
+		if v.invalid != nil {
+			return 0, v.invalid
+		}
+		if v.err != nil {
+			return 0, v.err
+		}
+
+		copy(v.buf, v.buf[v.start:v.end])
+		v.end -= v.start
+		v.start = 0
+		n, err := v.r.Read(v.buf[v.end:])
+		v.end += n
+		v.err = err
+		v.validEnd = v.validate()
+	}
+}
+
+// validate returns the end of the valid prefix of the buffered data. An
+// incomplete rune at the end is left for the next read unless the input is
+// exhausted.
+func (v *utf8Validator) validate() int {
+	data := v.buf[:v.end]
+	i := 0
+	for i < len(data) {
+		if data[i] < utf8.RuneSelf {
+			i++
+			continue
+		}
+		if !utf8.FullRune(data[i:]) && v.err == nil {
+			break
+		}
+		r, size := utf8.DecodeRune(data[i:])
+		if r == utf8.RuneError && size <= 1 {
+			v.invalid = &MalformedInputError{Format: "UTF-8", Offset: v.off + int64(i), Hint: "use bytes mode for binary input"}
+			break
+		}
+		i += size
+	}
+	return i
+}
+
+// latin1Reader reads bytes and presents each of them as the UTF-8 encoding of
+// the character with the same code.
+type latin1Reader struct {
+	r       io.Reader
+	buf     []byte
+	pending []byte
+	err     error
+}
+
+func (l *latin1Reader) Read(p []byte) (int, error) {
+	for len(l.pending) == 0 {
+		if l.err != nil {
+			return 0, l.err
+		}
+		n, err := l.r.Read(l.buf)
+		l.err = err
+		enc := make([]byte, 0, 2*n)
+		for _, b := range l.buf[:n] {
+			enc = utf8.AppendRune(enc, rune(b))
+		}
+		l.pending = enc
+	}
+	n := copy(p, l.pending)
+	l.pending = l.pending[n:]
+	return n, nil
+}
+
+// latin1Writer turns the UTF-8 written to it back into bytes, failing for
+// characters above U+00FF.
+type latin1Writer struct {
+	w       io.Writer
+	partial []byte
+}
+
+func (l *latin1Writer) Write(p []byte) (int, error) {
+	data := p
+	if len(l.partial) > 0 {
+		data = append(l.partial, p...)
+	}
+	out := make([]byte, 0, len(data))
+	i := 0
+	for i < len(data) && utf8.FullRune(data[i:]) {
+		r, size := utf8.DecodeRune(data[i:])
+		if r > 0xFF {
+			return 0, fmt.Errorf("byte mode: output character %q does not fit in a byte", r)
+		}
+		out = append(out, byte(r))
+		i += size
+	}
+	l.partial = append([]byte(nil), data[i:]...)
+	_, err := l.w.Write(out)
+	if err != nil {
+		return 0, err
+	}
+	return len(p), nil
+}
+
+func (l *latin1Writer) Close() error {
+	if len(l.partial) > 0 {
+		return fmt.Errorf("byte mode: incomplete character at the end of the output")
+	}
+	return nil
+}
+
+// trimNewlineReader drops a single LF or CRLF at the very end of the input,
+// which is the line break that terminates text typed on stdin.
+type trimNewlineReader struct {
+	br *bufio.Reader
+}
+
+func newTrimNewlineReader(r io.Reader) *trimNewlineReader {
+	return &trimNewlineReader{br: bufio.NewReader(r)}
+}
+
+func (t *trimNewlineReader) Read(p []byte) (int, error) {
+	n, err := t.br.Read(p)
+	if n == 0 {
+		return n, err
+	}
+	if p[n-1] == '\r' {
+		next, _ := t.br.Peek(2)
+		if len(next) == 1 && next[0] == '\n' {
+			_, _ = t.br.Discard(1)
+			return n - 1, nil
+		}
+	}
+	if p[n-1] == '\n' {
+		if _, perr := t.br.Peek(1); perr == io.EOF {
+			n--
+			if n > 0 && p[n-1] == '\r' {
+				n--
+			}
+		}
+	}
+	return n, err
+}
@@ -0,0 +1,85 @@
+package transformer
+
+import (
+	"errors"
+	"strings"
+	"testing"
+	"testing/iotest"
+)
+
+type TestMode struct {
+	name            string
+	params          Params
+	input, expected string
+}
+
+var TestArrayMode = []TestMode{
+	TestMode{"reverse", Params{"mode": "bytes"}, "\x00\xff\xfe", "\xfe\xff\x00"},
+	TestMode{"reverse", Params{"mode": "bytes"}, "é", "\xa9\xc3"},
+	TestMode{"reverse", Params{"mode": "utf8"}, "é", "é"},
+	TestMode{"caesar", Params{"shift": "1", "mode": "bytes"}, "\xffaZ\x80", "\xffbA\x80"},
+	TestMode{"vigenere", Params{"key": "b", "mode": "bytes", "decode": "true"}, "\x89bc", "\x89ab"},
+	TestMode{"atbash", Params{"mode": "bytes"}, "a\xc3\xa9", "z\xc3\xa9"},
+	TestMode{"base64", nil, "\xff\x00", "/wA="},
+}
+
+func TestTableMode(t *testing.T) {
+
+	for _, test := range TestArrayMode {
+
+		tr, err := New(test.name, test.params)
+		if err != nil {
+			t.Fatalf("Error creating %s: %s", test.name, err)
+		}
+		result := new(strings.Builder)
+		err = tr.TransformStream(iotest.OneByteReader(strings.NewReader(test.input)), result)
+		if err != nil {
+			t.Errorf("Error transforming: %s", err)
+		}
+
+		if result.String() != test.expected {
+			t.Errorf("Error: result = %q, expected = %q", result.String(), test.expected)
+		}
+
+	}
+}
+
+type TestInvalidUTF8 struct {
+	input  string
+	offset int64
+}
+
+var TestArrayInvalidUTF8 = []TestInvalidUTF8{
+	TestInvalidUTF8{"ab\xffcd", 2},
+	TestInvalidUTF8{"привіт\xc3", 12},
+	TestInvalidUTF8{"\xe2\x82x", 0},
+}
+
+func TestTableInvalidUTF8(t *testing.T) {
+
+	for _, test := range TestArrayInvalidUTF8 {
+
+		tr, err := New("caesar", Params{"shift": "1"})
+		if err != nil {
+			t.Fatalf("Error creating transformer: %s", err)
+		}
+		err = tr.TransformStream(iotest.OneByteReader(strings.NewReader(test.input)), new(strings.Builder))
+		var malformed *MalformedInputError
+		if !errors.As(err, &malformed) {
+			t.Errorf("Error: expected malformed input error for %q, got %v", test.input, err)
+			continue
+		}
+		if malformed.Offset != test.offset {
+			t.Errorf("Error: offset = %d, expected = %d", malformed.Offset, test.offset)
+		}
+
+	}
+}
+
+func TestLatin1WriterOverflow(t *testing.T) {
+	w := &latin1Writer{w: new(strings.Builder)}
+	_, err := w.Write([]byte("ї"))
+	if err == nil {
+		t.Errorf("Error: expected error for a character above U+00FF")
+	}
+}
@@ -0,0 +1,239 @@
+package transformer
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"runtime"
+	"unicode/utf8"
+)
+
+// DefaultChunkSize is how much input RunParallel hands to a worker at once.
+const DefaultChunkSize = 1 << 20
+
+// Splitter is implemented by transformers whose output is the same whether
+// the input is transformed at once or in chunks cut where SplitPoint says.
+type Splitter interface {
+	// SplitPoint returns how much of data, the start of the input left, can
+	// be transformed as a chunk of its own. 0 asks for more data, and a
+	// negative value means the transformer cannot be split at all.
+	SplitPoint(data []byte) int
+}
+
+// Splittable reports whether tr can transform its input in chunks.
+func Splittable(tr StreamTransformer) bool {
+	s, ok := tr.(Splitter)
+	return ok && s.SplitPoint(nil) >= 0
+}
+
+// splitRunes cuts data after its last complete UTF-8 character. Bytes that
+// do not start a character are cut after, so any input makes progress.
+func splitRunes(data []byte) int {
+	for i := len(data) - 1; i >= 0 && i >= len(data)-utf8.UTFMax; i-- {
+		if utf8.RuneStart(data[i]) {
+			if utf8.FullRune(data[i:]) {
+				return len(data)
+			}
+			return i
+		}
+	}
+	return len(data)
+}
+
+func splitLines(data []byte) int {
+	return bytes.LastIndexByte(data, '\n') + 1
+}
+
+func (t *CaesarTransformer) SplitPoint(data []byte) int {
+	return splitRunes(data)
+}
+
+func (t *AtbashTransformer) SplitPoint(data []byte) int {
+	return splitRunes(data)
+}
+
+func (t *SubstitutionTransformer) SplitPoint(data []byte) int {
+	return splitRunes(data)
+}
+
+func (t *HexTransformer) SplitPoint(data []byte) int {
+	if t.Decode {
+		return -1
+	}
+	return len(data)
+}
+
+func (t *Base64Transformer) SplitPoint(data []byte) int {
+	if t.Decode {
+		return -1
+	}
+	// padding may only end the output
+	return len(data) - len(data)%3
+}
+
+func (t *utf8Transformer) SplitPoint(data []byte) int {
+	s, ok := t.tr.(Splitter)
+	if !ok {
+		return -1
+	}
+	return s.SplitPoint(data[:splitRunes(data)])
+}
+
+// SplitPoint works on the raw bytes, every one of them is a character of
+// the wrapped transformer.
+func (t *bytesTransformer) SplitPoint(data []byte) int {
+	s, ok := t.tr.(Splitter)
+	if !ok {
+		return -1
+	}
+	return s.SplitPoint(data)
+}
+
+func (t *lineTransformer) SplitPoint(data []byte) int {
+	return splitLines(data)
+}
+
+func (t *fieldTransformer) SplitPoint(data []byte) int {
+	return splitLines(data)
+}
+
+type ParallelOptions struct {
+	// Workers defaults to GOMAXPROCS, ChunkSize to DefaultChunkSize.
+	Workers   int
+	ChunkSize int
+	// Progress is called with the number of input bytes whose output has
+	// been written, after every chunk.
+	Progress func(done int64)
+}
+
+type parallelChunk struct {
+	offset int64
+	data   []byte
+	out    bytes.Buffer
+	err    error
+	done   chan struct{}
+}
+
+// RunParallel transforms the input in chunks on a pool of workers and writes
+// their output in order. tr must be Splittable, otherwise the input is
+// transformed at once. The splittable transformers can run concurrently.
+func RunParallel(in io.Reader, out io.Writer, tr StreamTransformer, opts ParallelOptions) error {
+	if !Splittable(tr) {
+		return tr.TransformStream(in, out)
+	}
+	workers := opts.Workers
+	if workers <= 0 {
+		workers = runtime.GOMAXPROCS(0)
+	}
+	chunkSize := opts.ChunkSize
+	if chunkSize <= 0 {
+		chunkSize = DefaultChunkSize
+	}
+
+	jobs := make(chan *parallelChunk)
+	ordered := make(chan *parallelChunk, workers)
+	stop := make(chan struct{})
+	for i := 0; i < workers; i++ {
+		go func() {
+			for c := range jobs {
+				c.err = transformChunk(tr, c)
+				close(c.done)
+			}
+		}()
+	}
+	readErr := make(chan error, 1)
+	go func() {
+		defer close(ordered)
+		defer close(jobs)
+		readErr <- splitInput(in, tr.(Splitter), chunkSize, func(c *parallelChunk) bool {
+			select {
+			case ordered <- c:
+			case <-stop:
+				return false
+			}
+			select {
+			case jobs <- c:
+				return true
+			case <-stop:
+				close(c.done)
+				return false
+			}
+		})
+	}()
+
+	var err error
+	var done int64
+	for c := range ordered {
+		<-c.done
+		if err != nil {
+			continue
+		}
+		err = c.err
+		if err == nil {
+			_, err = out.Write(c.out.Bytes())
+		}
+		if err != nil {
+			close(stop)
+			continue
+		}
+		done += int64(len(c.data))
+		if opts.Progress != nil {
+			opts.Progress(done)
+		}
+	}
+	if err != nil {
+		return err
+	}
+	return <-readErr
+}
+
+func transformChunk(tr StreamTransformer, c *parallelChunk) error {
+	err := tr.TransformStream(bytes.NewReader(c.data), &c.out)
+	var malformed *MalformedInputError
+	if errors.As(err, &malformed) {
+		shifted := *malformed
+		shifted.Offset += c.offset
+		return &shifted
+	}
+	return err
+}
+
+// splitInput reads the input and passes it to emit in chunks of about size
+// bytes cut at split points, until emit returns false.
+func splitInput(in io.Reader, s Splitter, size int, emit func(*parallelChunk) bool) error {
+	var offset int64
+	var carry []byte
+	for {
+		buf := make([]byte, size+len(carry))
+		n := copy(buf, carry)
+		m, err := io.ReadFull(in, buf[n:])
+		buf = buf[:n+m]
+		atEOF := err == io.EOF || err == io.ErrUnexpectedEOF
+		if err != nil && !atEOF {
+			return err
+		}
+		cut := len(buf)
+		if !atEOF {
+			cut = s.SplitPoint(buf)
+		}
+		carry = append([]byte(nil), buf[cut:]...)
+		if cut > 0 {
+			if !emit(&parallelChunk{offset: offset, data: buf[:cut], done: make(chan struct{})}) {
+				return nil
+			}
+			offset += int64(cut)
+		}
+		if atEOF {
+			return nil
+		}
+	}
+}
+
+// RunTransformSize runs tr on input of the given size, in parallel chunks
+// when the input is larger than a chunk and tr can be split.
+func RunTransformSize(in io.Reader, out io.Writer, tr StreamTransformer, size int64, ioinput bool, opts ParallelOptions) error {
+	if size > DefaultChunkSize && Splittable(tr) {
+		return RunParallel(in, out, tr, opts)
+	}
+	return RunTransform(in, out, tr, ioinput)
+}
@@ -0,0 +1,146 @@
+package transformer
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"strings"
+	"testing"
+)
+
+type TestParallel struct {
+	opts       Options
+	splittable bool
+}
+
+var TestArrayParallel = []TestParallel{
+	TestParallel{Options{CaesarShift: 3}, true},
+	TestParallel{Options{CaesarShift: 5, Mode: ModeBytes}, true},
+	TestParallel{Options{Atbash: true, CaesarOptions: CaesarOptions{Alphabet: "ukrainian"}}, true},
+	TestParallel{Options{Substitution: "zebras"}, true},
+	TestParallel{Options{Codec: "hex"}, true},
+	TestParallel{Options{Base64: true}, true},
+	TestParallel{Options{Lines: true, Vigenere: "key"}, true},
+	TestParallel{Options{Field: 2, Pipeline: "reverse | caesar:1"}, true},
+	TestParallel{Options{Vigenere: "key"}, false},
+	TestParallel{Options{Codec: "base32"}, false},
+	TestParallel{Options{Pipeline: "caesar:1 | base64"}, false},
+	TestParallel{Options{}, false},
+}
+
+func parallelInput(lines int) []byte {
+	var b bytes.Buffer
+	for i := 0; i < lines; i++ {
+		b.WriteString("Съешь же ещё этих мягких, Quick brown fox; 12345,\tjumps over the lazy dog\r\n")
+	}
+	return b.Bytes()
+}
+
+func TestTableParallel(t *testing.T) {
+	input := parallelInput(200)
+
+	for _, test := range TestArrayParallel {
+
+		tr, err := NewTransformer(test.opts)
+		if err != nil {
+			t.Fatalf("Error creating transformer: %s", err)
+		}
+		if Splittable(tr) != test.splittable {
+			t.Errorf("Error: %+v splittable = %v, expected %v", test.opts, !test.splittable, test.splittable)
+		}
+		expected := new(bytes.Buffer)
+		err = tr.TransformStream(bytes.NewReader(input), expected)
+		if err != nil {
+			t.Fatalf("Error transforming: %s", err)
+		}
+
+		for _, chunkSize := range []int{1, 7, 100, 1 << 20} {
+			var progress int64
+			result := new(bytes.Buffer)
+			err = RunParallel(bytes.NewReader(input), result, tr, ParallelOptions{Workers: 4, ChunkSize: chunkSize, Progress: func(done int64) { progress = done }})
+			if err != nil {
+				t.Errorf("Error transforming in chunks of %d: %s", chunkSize, err)
+			}
+			if !bytes.Equal(result.Bytes(), expected.Bytes()) {
+				t.Errorf("Error: %+v in chunks of %d differs from the whole input", test.opts, chunkSize)
+			}
+			if test.splittable && progress != int64(len(input)) {
+				t.Errorf("Error: progress = %d, expected %d", progress, len(input))
+			}
+		}
+
+	}
+}
+
+func TestParallelErrors(t *testing.T) {
+	tr, err := NewTransformer(Options{CaesarShift: 1})
+	if err != nil {
+		t.Fatalf("Error creating transformer: %s", err)
+	}
+	input := append(bytes.Repeat([]byte("a"), 1000), 0xff)
+	err = RunParallel(bytes.NewReader(input), io.Discard, tr, ParallelOptions{ChunkSize: 64})
+	var malformed *MalformedInputError
+	if !errors.As(err, &malformed) || malformed.Offset != 1000 {
+		t.Errorf("Error: expected malformed input at offset 1000, got %v", err)
+	}
+
+	failing := errors.New("disk full")
+	err = RunParallel(strings.NewReader(strings.Repeat("abc", 1000)), failingWriter{failing}, tr, ParallelOptions{ChunkSize: 16})
+	if !errors.Is(err, failing) {
+		t.Errorf("Error: expected write error, got %v", err)
+	}
+}
+
+type failingWriter struct {
+	err error
+}
+
+func (w failingWriter) Write(p []byte) (int, error) {
+	return 0, w.err
+}
+
+// The benchmarks compare transforming 16 MB at once, the way the CLI reads
+// small files, with transforming it in chunks on all CPUs.
+func benchmarkTransform(b *testing.B, opts Options, parallel bool) {
+	tr, err := NewTransformer(opts)
+	if err != nil {
+		b.Fatalf("Error creating transformer: %s", err)
+	}
+	input := parallelInput(200000)
+	b.SetBytes(int64(len(input)))
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		if parallel {
+			err = RunParallel(bytes.NewReader(input), io.Discard, tr, ParallelOptions{})
+		} else {
+			err = tr.TransformStream(bytes.NewReader(input), io.Discard)
+		}
+		if err != nil {
+			b.Fatalf("Error transforming: %s", err)
+		}
+	}
+}
+
+func BenchmarkCaesarSequential(b *testing.B) {
+	benchmarkTransform(b, Options{CaesarShift: 3}, false)
+}
+
+func BenchmarkCaesarParallel(b *testing.B) {
+	benchmarkTransform(b, Options{CaesarShift: 3}, true)
+}
+
+func BenchmarkHexSequential(b *testing.B) {
+	benchmarkTransform(b, Options{Codec: "hex"}, false)
+}
+
+func BenchmarkHexParallel(b *testing.B) {
+	benchmarkTransform(b, Options{Codec: "hex"}, true)
+}
+
+func BenchmarkLinesSequential(b *testing.B) {
+	benchmarkTransform(b, Options{Lines: true, Vigenere: "key"}, false)
+}
+
+func BenchmarkLinesParallel(b *testing.B) {
+	benchmarkTransform(b, Options{Lines: true, Vigenere: "key"}, true)
+}
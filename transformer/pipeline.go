@@ -0,0 +1,156 @@
+package transformer
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// Step is one transformation of a pipeline, written as name[:args], where
+// args is a comma separated list of key=value pairs. The first argument may
+// omit its key, it then sets the main parameter of the transformation, e.g.
+// caesar:3 or vigenere:lemon,alphabet=latin.
+type Step struct {
+	Name   string
+	Params Params
+}
+
+func ParseStep(spec string) (Step, error) {
+	spec = strings.TrimSpace(spec)
+	name, args, hasArgs := strings.Cut(spec, ":")
+	step := Step{Name: strings.TrimSpace(name), Params: Params{}}
+	if step.Name == "" {
+		return Step{}, fmt.Errorf("empty pipeline step %q", spec)
+	}
+	if !hasArgs {
+		return step, nil
+	}
+	for i, arg := range strings.Split(args, ",") {
+		key, value, ok := strings.Cut(arg, "=")
+		if !ok {
+			s, _ := Lookup(step.Name)
+			if i != 0 || s.MainParam == "" {
+				return Step{}, fmt.Errorf("pipeline step %q: expected key=value, got %q", spec, arg)
+			}
+			key, value = s.MainParam, arg
+		}
+		step.Params[strings.TrimSpace(key)] = strings.TrimSpace(value)
+	}
+	return step, nil
+}
+
+func (s Step) String() string {
+	if len(s.Params) == 0 {
+		return s.Name
+	}
+	keys := make([]string, 0, len(s.Params))
+	for k := range s.Params {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	args := make([]string, 0, len(keys))
+	for _, k := range keys {
+		args = append(args, k+"="+s.Params[k])
+	}
+	return s.Name + ":" + strings.Join(args, ",")
+}
+
+func NewStep(step Step) (StreamTransformer, error) {
+	return New(step.Name, step.Params)
+}
+
+// ParsePipeline parses steps separated by "|", e.g. "reverse | caesar:3 | base64".
+func ParsePipeline(spec string) (Pipeline, error) {
+	steps, err := ParseSteps(spec)
+	if err != nil {
+		return nil, err
+	}
+	return NewPipeline(steps)
+}
+
+// ParseSteps splits a pipeline spec on "|" and parses every step.
+func ParseSteps(spec string) ([]Step, error) {
+	var steps []Step
+	for _, s := range strings.Split(spec, "|") {
+		step, err := ParseStep(s)
+		if err != nil {
+			return nil, err
+		}
+		steps = append(steps, step)
+	}
+	return steps, nil
+}
+
+// NewPipeline builds the transformers of the steps in order.
+func NewPipeline(steps []Step) (Pipeline, error) {
+	var p Pipeline
+	for _, step := range steps {
+		tr, err := NewStep(step)
+		if err != nil {
+			return nil, err
+		}
+		p = append(p, tr)
+	}
+	return p, nil
+}
+
+// Pipeline feeds the output of every transformer into the next one. The
+// steps run concurrently, connected by pipes.
+type Pipeline []StreamTransformer
+
+func (p Pipeline) TransformStream(in io.Reader, out io.Writer) error {
+	if len(p) == 0 {
+		_, err := io.Copy(out, in)
+		return err
+	}
+	errs := make([]error, len(p))
+	readers := make([]*io.PipeReader, 0, len(p)-1)
+	var wg sync.WaitGroup
+	for i, tr := range p[:len(p)-1] {
+		pr, pw := io.Pipe()
+		wg.Add(1)
+		go func(i int, tr StreamTransformer, in io.Reader) {
+			defer wg.Done()
+			err := tr.TransformStream(in, pw)
+			if err != nil {
+				errs[i] = fmt.Errorf("step %d: %w", i+1, err)
+			}
+			pw.CloseWithError(errs[i])
+		}(i, tr, in)
+		readers = append(readers, pr)
+		in = pr
+	}
+	last := len(p) - 1
+	if err := p[last].TransformStream(in, out); err != nil {
+		errs[last] = fmt.Errorf("step %d: %w", last+1, err)
+	}
+	// Unblock the earlier steps in case the last one stopped reading early.
+	for _, pr := range readers {
+		pr.CloseWithError(errPipelineAborted)
+	}
+	wg.Wait()
+
+	for _, err := range errs {
+		if err != nil && !errors.Is(err, errPipelineAborted) {
+			return err
+		}
+	}
+	return nil
+}
+
+var errPipelineAborted = errors.New("pipeline aborted")
+
+func (p Pipeline) Inverse() (StreamTransformer, error) {
+	inv := make(Pipeline, len(p))
+	for i, tr := range p {
+		t, err := Inverse(tr)
+		if err != nil {
+			return nil, err
+		}
+		inv[len(p)-1-i] = t
+	}
+	return inv, nil
+}
@@ -0,0 +1,83 @@
+package transformer
+
+import (
+	"errors"
+	"strings"
+	"testing"
+)
+
+type TestPipeline struct {
+	spec, input, expected string
+}
+
+var TestArrayPipeline = []TestPipeline{
+	TestPipeline{"reverse | caesar:3 | base64", "abc", "ZmVk"},
+	TestPipeline{"base64:url,decode=true|reverse", "YWJj", "cba"},
+	TestPipeline{"caesar:shift=1,alphabet=ukrainian | caesar:-1,alphabet=ukrainian", "Київ", "Київ"},
+	TestPipeline{"vigenere:lemon", "attackatdawn", "lxfopvefrnhr"},
+	TestPipeline{"reverse", "", ""},
+}
+
+func TestTablePipeline(t *testing.T) {
+
+	for _, test := range TestArrayPipeline {
+
+		p, err := ParsePipeline(test.spec)
+		if err != nil {
+			t.Fatalf("Error parsing %q: %s", test.spec, err)
+		}
+		result := new(strings.Builder)
+		err = p.TransformStream(strings.NewReader(test.input), result)
+		if err != nil {
+			t.Errorf("Error transforming: %s", err)
+		}
+		if result.String() != test.expected {
+			t.Errorf("Error: result = %q, expected = %q", result.String(), test.expected)
+		}
+
+		inv, err := Inverse(p)
+		if err != nil {
+			t.Fatalf("Error inverting %q: %s", test.spec, err)
+		}
+		original := new(strings.Builder)
+		err = inv.TransformStream(strings.NewReader(result.String()), original)
+		if err != nil {
+			t.Errorf("Error transforming back: %s", err)
+		}
+		if original.String() != test.input {
+			t.Errorf("Error: original = %q, expected = %q", original.String(), test.input)
+		}
+
+	}
+}
+
+func TestPipelineInvalid(t *testing.T) {
+	for _, spec := range []string{"", "reverse |", "rot13", "caesar", "caesar:x", "reverse:1", "vigenere:a,b"} {
+		_, err := ParsePipeline(spec)
+		if err == nil {
+			t.Errorf("Error: expected error for pipeline %q", spec)
+		}
+	}
+}
+
+func TestPipelineStepError(t *testing.T) {
+	p, err := ParsePipeline("reverse | base64:decode=true | caesar:1")
+	if err != nil {
+		t.Fatalf("Error parsing: %s", err)
+	}
+	err = p.TransformStream(strings.NewReader("!!!!"), new(strings.Builder))
+	var malformed *MalformedInputError
+	if !errors.As(err, &malformed) || !strings.HasPrefix(err.Error(), "step 2:") {
+		t.Errorf("Error: expected malformed input error from step 2, got %v", err)
+	}
+}
+
+func TestStepString(t *testing.T) {
+	step, err := ParseStep(" caesar:3,alphabet=ukrainian ")
+	if err != nil {
+		t.Fatalf("Error parsing: %s", err)
+	}
+	if step.String() != "caesar:alphabet=ukrainian,shift=3" {
+		t.Errorf("Error: step = %q", step.String())
+	}
+}
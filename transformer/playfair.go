@@ -0,0 +1,141 @@
+package transformer
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+	"unicode"
+)
+
+// playfairAlphabet is the latin alphabet without j, which is written as i.
+const playfairAlphabet = "abcdefghiklmnopqrstuvwxyz"
+
+var playfairSpec = Spec{
+	Name:        "playfair",
+	Description: "Playfair digraph cipher on a 5x5 key square, latin letters only",
+	MainParam:   "key",
+	Params: []Param{
+		{Name: "key", Type: ParamString, Required: true, Description: "Keyword or all 25 letters of the key square, j counts as i"},
+	},
+	Text:       true,
+	Invertible: true,
+	New: func(p Params) (StreamTransformer, error) {
+		return NewPlayfairTransformer(p["key"])
+	},
+}
+
+// PlayfairTransformer encrypts pairs of letters. As in the classical cipher
+// only the letters of the input are kept, in lower case with j written as i,
+// and an x separates doubled letters and pads the last pair. Decryption
+// leaves those x in place.
+type PlayfairTransformer struct {
+	Square  []rune
+	Decrypt bool
+}
+
+func NewPlayfairTransformer(key string) (*PlayfairTransformer, error) {
+	letters := make([]rune, 0, len(key))
+	for _, r := range key {
+		r = playfairLetter(r)
+		if unicode.IsSpace(r) {
+			continue
+		}
+		if !strings.ContainsRune(playfairAlphabet, r) {
+			return nil, fmt.Errorf("playfair key letter %q is not a latin letter", r)
+		}
+		letters = append(letters, r)
+	}
+	if len(letters) == 0 {
+		return nil, errors.New("expected playfair key")
+	}
+	square, err := keyedAlphabet(string(letters), []rune(playfairAlphabet))
+	if err != nil {
+		return nil, err
+	}
+	return &PlayfairTransformer{Square: square}, nil
+}
+
+func (t *PlayfairTransformer) TransformStream(in io.Reader, out io.Writer) error {
+	if len(t.Square) != len(playfairAlphabet) {
+		return errors.New("expected a 5x5 playfair key square")
+	}
+	index := alphabetIndex(t.Square)
+	br := bufio.NewReader(in)
+	bw := bufio.NewWriter(out)
+	var pair []rune
+	for {
+		rn, _, err := br.ReadRune()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return err
+		}
+		rn = playfairLetter(rn)
+		if _, ok := index[rn]; !ok {
+			continue
+		}
+		if len(pair) == 1 && pair[0] == rn && !t.Decrypt {
+			pair = append(pair, playfairFiller(rn))
+			if err := t.writePair(bw, index, pair); err != nil {
+				return err
+			}
+			pair = pair[:0]
+		}
+		pair = append(pair, rn)
+		if len(pair) == 2 {
+			if err := t.writePair(bw, index, pair); err != nil {
+				return err
+			}
+			pair = pair[:0]
+		}
+	}
+	if len(pair) == 1 {
+		pair = append(pair, playfairFiller(pair[0]))
+		if err := t.writePair(bw, index, pair); err != nil {
+			return err
+		}
+	}
+	return bw.Flush()
+}
+
+func (t *PlayfairTransformer) writePair(w *bufio.Writer, index map[rune]int, pair []rune) error {
+	step := 1
+	if t.Decrypt {
+		step = -1
+	}
+	a, b := index[pair[0]], index[pair[1]]
+	rowA, colA, rowB, colB := a/5, a%5, b/5, b%5
+	switch {
+	case rowA == rowB:
+		colA, colB = mod(colA+step, 5), mod(colB+step, 5)
+	case colA == colB:
+		rowA, rowB = mod(rowA+step, 5), mod(rowB+step, 5)
+	default:
+		colA, colB = colB, colA
+	}
+	_, err := w.WriteString(string([]rune{t.Square[rowA*5+colA], t.Square[rowB*5+colB]}))
+	return err
+}
+
+func (t *PlayfairTransformer) Inverse() (StreamTransformer, error) {
+	return &PlayfairTransformer{Square: t.Square, Decrypt: !t.Decrypt}, nil
+}
+
+func playfairLetter(r rune) rune {
+	r = unicode.ToLower(r)
+	if r == 'j' {
+		return 'i'
+	}
+	return r
+}
+
+// playfairFiller separates a doubled letter, an x unless the letter is x.
+func playfairFiller(r rune) rune {
+	if r == 'x' {
+		return 'q'
+	}
+	return 'x'
+}
@@ -0,0 +1,297 @@
+package transformer
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// Plugins are executables that add transformers written in other languages.
+// The host runs a plugin in two ways:
+//
+//	plugin describe   prints the Spec of the transformer as JSON, e.g.
+//	                  {"name":"rot47","description":"...","params":[...],"invertible":true}
+//	plugin transform  transforms the framed input read from stdin and writes
+//	                  framed output to stdout
+//
+// A frame is a one byte kind, a big-endian uint32 payload length and the
+// payload. The host sends a 'P' frame with the parameters as a JSON object,
+// then 'D' frames with the input and finally an empty 'E' frame. The plugin
+// answers with 'D' frames carrying the output followed by an 'E' frame, or
+// with an 'X' frame holding an error message. Invertible plugins receive
+// "decode": "true" in the parameters when the inverse is requested.
+const (
+	frameParams = 'P'
+	frameData   = 'D'
+	frameEnd    = 'E'
+	frameError  = 'X'
+)
+
+const (
+	maxFramePayload   = 64 << 10
+	DefaultPluginTime = 30 * time.Second
+	DefaultPluginOut  = 64 << 20
+	pluginDescribeRun = 5 * time.Second
+)
+
+type PluginLimits struct {
+	Timeout   time.Duration
+	MaxOutput int64
+}
+
+type PluginTransformer struct {
+	Path   string
+	Params Params
+	Decode bool
+	Limits PluginLimits
+}
+
+// LoadPlugins registers every executable in dir as a transformer. A plugin
+// that cannot be described or clashes with a registered name is skipped and
+// reported in the returned errors.
+func LoadPlugins(dir string, limits PluginLimits) ([]string, []error) {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, []error{err}
+	}
+	var names []string
+	var errs []error
+	for _, entry := range entries {
+		if entry.IsDir() || strings.HasPrefix(entry.Name(), ".") {
+			continue
+		}
+		info, err := entry.Info()
+		if err != nil || info.Mode()&0o111 == 0 {
+			continue
+		}
+		path := filepath.Join(dir, entry.Name())
+		spec, err := describePlugin(path, limits)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("plugin %s: %w", path, err))
+			continue
+		}
+		if _, ok := Lookup(spec.Name); ok {
+			errs = append(errs, fmt.Errorf("plugin %s: transformer %q already exists", path, spec.Name))
+			continue
+		}
+		Register(spec)
+		names = append(names, spec.Name)
+	}
+	return names, errs
+}
+
+func describePlugin(path string, limits PluginLimits) (Spec, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), pluginDescribeRun)
+	defer cancel()
+	var stdout, stderr bytes.Buffer
+	cmd := exec.CommandContext(ctx, path, "describe")
+	cmd.Stdout = &limitedBuffer{buf: &stdout, limit: maxFramePayload}
+	cmd.Stderr = &limitedBuffer{buf: &stderr, limit: maxFramePayload}
+	err := cmd.Run()
+	if err != nil {
+		return Spec{}, fmt.Errorf("describe: %w %s", err, strings.TrimSpace(stderr.String()))
+	}
+	var spec Spec
+	err = json.Unmarshal(stdout.Bytes(), &spec)
+	if err != nil {
+		return Spec{}, fmt.Errorf("describe: %w", err)
+	}
+	if spec.Name == "" || strings.ContainsAny(spec.Name, "|:,= \t\n") {
+		return Spec{}, fmt.Errorf("describe: invalid transformer name %q", spec.Name)
+	}
+	spec.Plugin = true
+	spec.New = func(p Params) (StreamTransformer, error) {
+		params := Params{}
+		for k, v := range p {
+			if k != decodeParam.Name {
+				params[k] = v
+			}
+		}
+		return &PluginTransformer{Path: path, Params: params, Limits: limits}, nil
+	}
+	return spec, nil
+}
+
+func (t *PluginTransformer) Inverse() (StreamTransformer, error) {
+	return &PluginTransformer{Path: t.Path, Params: t.Params, Decode: !t.Decode, Limits: t.Limits}, nil
+}
+
+func (t *PluginTransformer) TransformStream(in io.Reader, out io.Writer) error {
+	timeout := t.Limits.Timeout
+	if timeout <= 0 {
+		timeout = DefaultPluginTime
+	}
+	maxOutput := t.Limits.MaxOutput
+	if maxOutput <= 0 {
+		maxOutput = DefaultPluginOut
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+
+	var stderr bytes.Buffer
+	cmd := exec.CommandContext(ctx, t.Path, "transform")
+	cmd.Stderr = &limitedBuffer{buf: &stderr, limit: maxFramePayload}
+	stdin, err := cmd.StdinPipe()
+	if err != nil {
+		return err
+	}
+	stdout, err := cmd.StdoutPipe()
+	if err != nil {
+		return err
+	}
+	err = cmd.Start()
+	if err != nil {
+		return err
+	}
+
+	sendErr := make(chan error, 1)
+	go func() {
+		err := t.sendInput(stdin, in)
+		stdin.Close()
+		sendErr <- err
+	}()
+
+	err = receiveOutput(bufio.NewReader(stdout), out, maxOutput)
+	if err != nil {
+		cancel()
+	}
+	waitErr := cmd.Wait()
+	if ctx.Err() == context.DeadlineExceeded {
+		return fmt.Errorf("plugin %s: timed out after %s", filepath.Base(t.Path), timeout)
+	}
+	if err != nil {
+		return fmt.Errorf("plugin %s: %w", filepath.Base(t.Path), err)
+	}
+	if waitErr != nil {
+		return fmt.Errorf("plugin %s: %w %s", filepath.Base(t.Path), waitErr, strings.TrimSpace(stderr.String()))
+	}
+	if err := <-sendErr; err != nil {
+		return fmt.Errorf("plugin %s: %w", filepath.Base(t.Path), err)
+	}
+	return nil
+}
+
+func (t *PluginTransformer) sendInput(w io.Writer, in io.Reader) error {
+	params := Params{}
+	for k, v := range t.Params {
+		params[k] = v
+	}
+	if t.Decode {
+		params[decodeParam.Name] = "true"
+	}
+	payload, err := json.Marshal(params)
+	if err != nil {
+		return err
+	}
+	bw := bufio.NewWriter(w)
+	err = writeFrame(bw, frameParams, payload)
+	if err != nil {
+		return err
+	}
+	buf := make([]byte, maxFramePayload)
+	for {
+		n, err := in.Read(buf)
+		if n > 0 {
+			if werr := writeFrame(bw, frameData, buf[:n]); werr != nil {
+				return werr
+			}
+		}
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return err
+		}
+	}
+	err = writeFrame(bw, frameEnd, nil)
+	if err != nil {
+		return err
+	}
+	return bw.Flush()
+}
+
+var errPluginProtocol = errors.New("plugin protocol error")
+
+func receiveOutput(r io.Reader, out io.Writer, maxOutput int64) error {
+	var written int64
+	for {
+		kind, payload, err := readFrame(r)
+		if err != nil {
+			return fmt.Errorf("%w: %s", errPluginProtocol, err)
+		}
+		switch kind {
+		case frameData:
+			written += int64(len(payload))
+			if written > maxOutput {
+				return fmt.Errorf("output exceeds %d bytes", maxOutput)
+			}
+			_, err = out.Write(payload)
+			if err != nil {
+				return err
+			}
+		case frameEnd:
+			return nil
+		case frameError:
+			return errors.New(string(payload))
+		default:
+			return fmt.Errorf("%w: unexpected frame %q", errPluginProtocol, kind)
+		}
+	}
+}
+
+func writeFrame(w io.Writer, kind byte, payload []byte) error {
+	var header [5]byte
+	header[0] = kind
+	binary.BigEndian.PutUint32(header[1:], uint32(len(payload)))
+	_, err := w.Write(header[:])
+	if err != nil {
+		return err
+	}
+	_, err = w.Write(payload)
+	return err
+}
+
+func readFrame(r io.Reader) (byte, []byte, error) {
+	var header [5]byte
+	_, err := io.ReadFull(r, header[:])
+	if err != nil {
+		return 0, nil, err
+	}
+	n := binary.BigEndian.Uint32(header[1:])
+	if n > maxFramePayload {
+		return 0, nil, fmt.Errorf("frame of %d bytes exceeds %d", n, maxFramePayload)
+	}
+	payload := make([]byte, n)
+	_, err = io.ReadFull(r, payload)
+	if err != nil {
+		return 0, nil, err
+	}
+	return header[0], payload, nil
+}
+
+// limitedBuffer keeps at most limit bytes and discards the rest.
+type limitedBuffer struct {
+	buf   *bytes.Buffer
+	limit int
+}
+
+func (b *limitedBuffer) Write(p []byte) (int, error) {
+	if room := b.limit - b.buf.Len(); room > 0 {
+		if len(p) > room {
+			b.buf.Write(p[:room])
+		} else {
+			b.buf.Write(p)
+		}
+	}
+	return len(p), nil
+}
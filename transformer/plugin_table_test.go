@@ -0,0 +1,146 @@
+package transformer
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestMain(m *testing.M) {
+	if os.Getenv("TRANSFORMER_TEST_PLUGIN") != "" {
+		os.Exit(runTestPlugin(os.Args[len(os.Args)-1]))
+	}
+	os.Exit(m.Run())
+}
+
+// runTestPlugin is a plugin that changes the case of its input.
+func runTestPlugin(command string) int {
+	if command == "describe" {
+		fmt.Print(`{"name":"test-shout","description":"Upper-case the input","params":[{"name":"mode","type":"string"}],"invertible":true}`)
+		return 0
+	}
+	r := bufio.NewReader(os.Stdin)
+	w := bufio.NewWriter(os.Stdout)
+	defer w.Flush()
+	_, payload, err := readFrame(r)
+	if err != nil {
+		return 1
+	}
+	var params Params
+	_ = json.Unmarshal(payload, &params)
+	for {
+		kind, payload, err := readFrame(r)
+		if err != nil {
+			return 1
+		}
+		if kind == frameEnd {
+			break
+		}
+		switch {
+		case params["mode"] == "fail":
+			_ = writeFrame(w, frameError, []byte("asked to fail"))
+			return 0
+		case params["mode"] == "sleep":
+			time.Sleep(time.Minute)
+		case params["mode"] == "big":
+			for i := 0; i < 1000; i++ {
+				_ = writeFrame(w, frameData, payload)
+			}
+		case params["decode"] == "true":
+			payload = bytes.ToLower(payload)
+		default:
+			payload = bytes.ToUpper(payload)
+		}
+		_ = writeFrame(w, frameData, payload)
+	}
+	_ = writeFrame(w, frameEnd, nil)
+	return 0
+}
+
+func loadTestPlugin(t *testing.T, limits PluginLimits) {
+	exe, err := os.Executable()
+	if err != nil {
+		t.Fatalf("Error finding test binary: %s", err)
+	}
+	dir := t.TempDir()
+	script := fmt.Sprintf("#!/bin/sh\nTRANSFORMER_TEST_PLUGIN=1 exec '%s' \"$@\"\n", exe)
+	err = os.WriteFile(filepath.Join(dir, "shout"), []byte(script), 0o755)
+	if err != nil {
+		t.Fatalf("Error writing plugin: %s", err)
+	}
+	err = os.WriteFile(filepath.Join(dir, "README"), []byte("not a plugin"), 0o644)
+	if err != nil {
+		t.Fatalf("Error writing file: %s", err)
+	}
+
+	names, errs := LoadPlugins(dir, limits)
+	if len(errs) > 0 || len(names) != 1 || names[0] != "test-shout" {
+		t.Fatalf("Error loading plugins: %v %v", names, errs)
+	}
+	t.Cleanup(func() {
+		registry.Lock()
+		delete(registry.specs, "test-shout")
+		registry.Unlock()
+	})
+}
+
+type TestPlugin struct {
+	spec, input, expected string
+}
+
+var TestArrayPlugin = []TestPlugin{
+	TestPlugin{"test-shout", "hello", "HELLO"},
+	TestPlugin{"test-shout:decode=true", "HELLO", "hello"},
+	TestPlugin{"reverse | test-shout | base64", "abc", "Q0JB"},
+	TestPlugin{"test-shout", strings.Repeat("x", 200000), strings.Repeat("X", 200000)},
+}
+
+func TestTablePlugin(t *testing.T) {
+	loadTestPlugin(t, PluginLimits{Timeout: 10 * time.Second, MaxOutput: 1 << 20})
+
+	for _, test := range TestArrayPlugin {
+
+		p, err := ParsePipeline(test.spec)
+		if err != nil {
+			t.Fatalf("Error parsing %q: %s", test.spec, err)
+		}
+		result := new(strings.Builder)
+		err = p.TransformStream(strings.NewReader(test.input), result)
+		if err != nil {
+			t.Errorf("Error transforming: %s", err)
+		}
+		if result.String() != test.expected {
+			t.Errorf("Error: result = %.20q, expected = %.20q", result.String(), test.expected)
+		}
+
+	}
+}
+
+func TestPluginLimits(t *testing.T) {
+	loadTestPlugin(t, PluginLimits{Timeout: 3 * time.Second, MaxOutput: 1 << 20})
+
+	for mode, expected := range map[string]string{
+		"fail":  "asked to fail",
+		"sleep": "timed out",
+		"big":   "output exceeds",
+		"color": "unknown parameter",
+	} {
+		tr, err := New("test-shout", Params{"mode": mode})
+		if mode == "color" {
+			tr, err = New("test-shout", Params{"color": "red"})
+		}
+		if err == nil {
+			err = tr.TransformStream(strings.NewReader(strings.Repeat("x", 2000)), io.Discard)
+		}
+		if err == nil || !strings.Contains(err.Error(), expected) {
+			t.Errorf("Error: mode %s, expected error containing %q, got %v", mode, expected, err)
+		}
+	}
+}
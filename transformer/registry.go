@@ -0,0 +1,259 @@
+package transformer
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+type ParamType string
+
+const (
+	ParamString ParamType = "string"
+	ParamInt    ParamType = "int"
+	ParamBool   ParamType = "bool"
+)
+
+// Params are the named options of a single transformation step.
+type Params map[string]string
+
+func (p Params) Int(name string) (int, error) {
+	v, ok := p[name]
+	if !ok || v == "" {
+		return 0, nil
+	}
+	n, err := strconv.Atoi(v)
+	if err != nil {
+		return 0, fmt.Errorf("parameter %s: expected integer, got %q", name, v)
+	}
+	return n, nil
+}
+
+func (p Params) Bool(name string) (bool, error) {
+	v, ok := p[name]
+	if !ok || v == "" {
+		return false, nil
+	}
+	b, err := strconv.ParseBool(v)
+	if err != nil {
+		return false, fmt.Errorf("parameter %s: expected true or false, got %q", name, v)
+	}
+	return b, nil
+}
+
+// Param describes one parameter a transformer accepts.
+type Param struct {
+	Name        string    `json:"name"`
+	Type        ParamType `json:"type"`
+	Required    bool      `json:"required,omitempty"`
+	Default     string    `json:"default,omitempty"`
+	Choices     []string  `json:"choices,omitempty"`
+	Description string    `json:"description"`
+}
+
+// Spec describes a transformer that can be created by name.
+type Spec struct {
+	Name        string `json:"name"`
+	Description string `json:"description"`
+	// MainParam is set by a pipeline step argument given without a key.
+	MainParam string  `json:"main_param,omitempty"`
+	Params    []Param `json:"params,omitempty"`
+	// Invertible transformers accept the common "decode" parameter, which
+	// applies their inverse.
+	Invertible bool `json:"invertible"`
+	// Text transformers work on characters. They accept the common "mode"
+	// parameter, which selects between UTF-8 and byte input.
+	Text bool `json:"text"`
+	// BinaryOutput transformers write bytes that are not text, such as
+	// compressed data, unless they decode. Chain them into a codec to get
+	// text.
+	BinaryOutput bool `json:"binary_output,omitempty"`
+	// Legacy transformers are kept for compatibility and should not be used
+	// for new data, such as broken hash functions.
+	Legacy bool `json:"legacy,omitempty"`
+	// Plugin is set for transformers provided by an external executable.
+	Plugin bool                                      `json:"plugin,omitempty"`
+	New    func(p Params) (StreamTransformer, error) `json:"-"`
+}
+
+var decodeParam = Param{Name: "decode", Type: ParamBool, Description: "Apply the inverse transformation"}
+
+type specRegistry struct {
+	sync.RWMutex
+	specs map[string]Spec
+}
+
+var registry = newRegistry(
+	reverseSpec,
+	caesarSpec,
+	base64Spec,
+	vigenereSpec,
+	atbashSpec,
+	substitutionSpec,
+	hexSpec,
+	base32Spec,
+	base58Spec,
+	ascii85Spec,
+	z85Spec,
+	percentSpec,
+	gzipSpec,
+	zlibSpec,
+	aesSpec,
+	sha256Spec,
+	sha512Spec,
+	sha1Spec,
+	md5Spec,
+	blake2bSpec,
+	hmacSHA256Spec,
+	railFenceSpec,
+	columnarSpec,
+	playfairSpec,
+	xorSpec,
+	caseSpec,
+	whitespaceSpec,
+	normalizeSpec,
+)
+
+func newRegistry(specs ...Spec) *specRegistry {
+	r := &specRegistry{specs: map[string]Spec{}}
+	for _, spec := range specs {
+		r.add(spec)
+	}
+	return r
+}
+
+func (r *specRegistry) add(spec Spec) {
+	if _, ok := r.specs[spec.Name]; ok {
+		panic(fmt.Sprintf("transformer %q registered twice", spec.Name))
+	}
+	spec.Params = append([]Param{}, spec.Params...)
+	if spec.Text {
+		spec.Params = append(spec.Params, modeParam)
+	}
+	if spec.Invertible {
+		spec.Params = append(spec.Params, decodeParam)
+	}
+	r.specs[spec.Name] = spec
+}
+
+// Register makes a transformer available by its name. It panics if the name
+// is already taken.
+func Register(spec Spec) {
+	registry.Lock()
+	defer registry.Unlock()
+	registry.add(spec)
+}
+
+func Lookup(name string) (Spec, bool) {
+	registry.RLock()
+	defer registry.RUnlock()
+	spec, ok := registry.specs[name]
+	return spec, ok
+}
+
+// Specs returns all registered transformers sorted by name.
+func Specs() []Spec {
+	registry.RLock()
+	defer registry.RUnlock()
+	specs := make([]Spec, 0, len(registry.specs))
+	for _, spec := range registry.specs {
+		specs = append(specs, spec)
+	}
+	sort.Slice(specs, func(i, j int) bool {
+		return specs[i].Name < specs[j].Name
+	})
+	return specs
+}
+
+func Names() []string {
+	specs := Specs()
+	names := make([]string, len(specs))
+	for i, spec := range specs {
+		names[i] = spec.Name
+	}
+	return names
+}
+
+// New validates the parameters against the schema of the named transformer
+// and creates it.
+func New(name string, p Params) (StreamTransformer, error) {
+	spec, ok := Lookup(name)
+	if !ok {
+		return nil, fmt.Errorf("unknown transformation %q, expected one of %s", name, strings.Join(Names(), "/"))
+	}
+	err := spec.Validate(p)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", name, err)
+	}
+	tr, err := spec.New(p)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", name, err)
+	}
+	if spec.Text {
+		tr, err = WithMode(tr, p[modeParam.Name])
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", name, err)
+		}
+	}
+	decode, _ := p.Bool(decodeParam.Name)
+	if decode {
+		return Inverse(tr)
+	}
+	return tr, nil
+}
+
+func (s Spec) Validate(p Params) error {
+	known := make(map[string]bool, len(s.Params))
+	for _, param := range s.Params {
+		known[param.Name] = true
+		v, ok := p[param.Name]
+		if !ok || v == "" {
+			if param.Required {
+				return fmt.Errorf("expected %s parameter", param.Name)
+			}
+			continue
+		}
+		err := param.check(v)
+		if err != nil {
+			return err
+		}
+	}
+	for name := range p {
+		if !known[name] {
+			return fmt.Errorf("unknown parameter %q", name)
+		}
+	}
+	return nil
+}
+
+func (param Param) check(v string) error {
+	switch param.Type {
+	case ParamInt:
+		if _, err := strconv.Atoi(v); err != nil {
+			return fmt.Errorf("parameter %s: expected integer, got %q", param.Name, v)
+		}
+	case ParamBool:
+		if _, err := strconv.ParseBool(v); err != nil {
+			return fmt.Errorf("parameter %s: expected true or false, got %q", param.Name, v)
+		}
+	case ParamString:
+	}
+	if len(param.Choices) > 0 {
+		for _, c := range param.Choices {
+			if v == c {
+				return nil
+			}
+		}
+		return fmt.Errorf("parameter %s: expected one of %s, got %q", param.Name, strings.Join(param.Choices, "/"), v)
+	}
+	return nil
+}
+
+var alphabetParam = Param{
+	Name:        "alphabet",
+	Type:        ParamString,
+	Default:     DefaultCaesarAlphabet,
+	Description: "Alphabet name (latin, ukrainian) or the letters of a custom alphabet",
+}
@@ -0,0 +1,103 @@
+package transformer
+
+import (
+	"io"
+	"strings"
+	"testing"
+)
+
+type TestRegistry struct {
+	name            string
+	params          Params
+	input, expected string
+}
+
+var TestArrayRegistry = []TestRegistry{
+	TestRegistry{"reverse", nil, "abc", "cba"},
+	TestRegistry{"caesar", Params{"shift": "1"}, "zab", "abc"},
+	TestRegistry{"caesar", Params{"shift": "1", "decode": "true"}, "abc", "zab"},
+	TestRegistry{"base64", Params{"variant": "url"}, "\xfb\xff", "-_8="},
+	TestRegistry{"vigenere", Params{"key": "lemon"}, "attackatdawn", "lxfopvefrnhr"},
+}
+
+func TestTableRegistry(t *testing.T) {
+
+	for _, test := range TestArrayRegistry {
+
+		tr, err := New(test.name, test.params)
+		if err != nil {
+			t.Fatalf("Error creating %s: %s", test.name, err)
+		}
+		result := new(strings.Builder)
+		err = tr.TransformStream(strings.NewReader(test.input), result)
+		if err != nil {
+			t.Errorf("Error transforming: %s", err)
+		}
+
+		if result.String() != test.expected {
+			t.Errorf("Error: result = %q, expected = %q", result.String(), test.expected)
+		}
+
+	}
+}
+
+var TestArrayRegistryInvalid = []TestRegistry{
+	TestRegistry{name: "rot13"},
+	TestRegistry{name: "caesar"},
+	TestRegistry{name: "caesar", params: Params{"shift": "three"}},
+	TestRegistry{name: "caesar", params: Params{"shift": "0"}},
+	TestRegistry{name: "caesar", params: Params{"shift": "1", "key": "x"}},
+	TestRegistry{name: "base64", params: Params{"variant": "base65"}},
+	TestRegistry{name: "reverse", params: Params{"decode": "maybe"}},
+}
+
+func TestRegistryInvalid(t *testing.T) {
+	for _, test := range TestArrayRegistryInvalid {
+		_, err := New(test.name, test.params)
+		if err == nil {
+			t.Errorf("Error: expected error for %s %v", test.name, test.params)
+		}
+	}
+}
+
+func TestRegister(t *testing.T) {
+	Register(Spec{
+		Name: "test-upper",
+		New: func(p Params) (StreamTransformer, error) {
+			return upperTransformer{}, nil
+		},
+	})
+	defer func() {
+		registry.Lock()
+		delete(registry.specs, "test-upper")
+		registry.Unlock()
+	}()
+
+	p, err := ParsePipeline("reverse | test-upper")
+	if err != nil {
+		t.Fatalf("Error parsing pipeline: %s", err)
+	}
+	result := new(strings.Builder)
+	err = p.TransformStream(strings.NewReader("abc"), result)
+	if err != nil || result.String() != "CBA" {
+		t.Errorf("Error: result = %q, err = %v", result.String(), err)
+	}
+
+	defer func() {
+		if recover() == nil {
+			t.Errorf("Error: expected panic on duplicate name")
+		}
+	}()
+	Register(Spec{Name: "test-upper"})
+}
+
+type upperTransformer struct{}
+
+func (upperTransformer) TransformStream(in io.Reader, out io.Writer) error {
+	b, err := io.ReadAll(in)
+	if err != nil {
+		return err
+	}
+	_, err = out.Write([]byte(strings.ToUpper(string(b))))
+	return err
+}
@@ -0,0 +1,288 @@
+package transformer
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/rivo/uniseg"
+)
+
+// Units a ReverseTransformer can reverse the order of.
+const (
+	ReverseRunes     = "runes"
+	ReverseGraphemes = "graphemes"
+	ReverseWords     = "words"
+	ReverseLines     = "lines"
+)
+
+// reverseChunkSize is the longest piece of a word or line that is held in
+// memory. Longer words and lines are read in pieces and collected in a spill
+// buffer, grapheme clusters may not be longer.
+const reverseChunkSize = 1 << 20
+
+var reverseSpec = Spec{
+	Name:        "reverse",
+	Description: "Reverse the order of the characters, grapheme clusters, words or lines",
+	MainParam:   "unit",
+	Params: []Param{
+		{
+			Name:        "unit",
+			Type:        ParamString,
+			Default:     ReverseRunes,
+			Choices:     []string{ReverseRunes, ReverseGraphemes, ReverseWords, ReverseLines},
+			Description: "What to reverse: runes, grapheme clusters (UAX #29), words or lines",
+		},
+	},
+	Text:       true,
+	Invertible: true,
+	New: func(p Params) (StreamTransformer, error) {
+		return NewReverseUnitTransformer(p["unit"])
+	},
+}
+
+type ReverseTransformer struct {
+	Unit string
+}
+
+func NewReverseTransformer() *ReverseTransformer {
+	return &ReverseTransformer{}
+}
+
+func NewReverseUnitTransformer(unit string) (*ReverseTransformer, error) {
+	switch unit {
+	case "", ReverseRunes, ReverseGraphemes, ReverseWords, ReverseLines:
+		return &ReverseTransformer{Unit: unit}, nil
+	}
+	return nil, fmt.Errorf("unknown reverse unit %q", unit)
+}
+
+func (t *ReverseTransformer) Transform(in io.Reader, ioinput bool) (string, error) {
+	return transformToString(t, in, ioinput)
+}
+
+// TransformStream splits the input into units and writes every unit
+// byte-reversed into a spill buffer, then writes the whole buffer back to
+// front. That restores the byte order inside each unit while reversing the
+// order of the units. Word and line separators at the very start and end of
+// the input stay where they are.
+func (t *ReverseTransformer) TransformStream(in io.Reader, out io.Writer) error {
+	split, isSep := t.splitter()
+	sp := newSpillBuffer(spillThreshold)
+	defer sp.Close()
+
+	sc := bufio.NewScanner(in)
+	sc.Buffer(make([]byte, 0, 64<<10), 2*reverseChunkSize)
+	// partial is set while a long token is passed on in pieces,
+	// continued while the pieces of such a token are collected
+	partial, continued := false, false
+	if isSep != nil {
+		sc.Split(splitLong(split, &partial))
+	} else {
+		sc.Split(split)
+	}
+	bw := bufio.NewWriter(sp)
+	writeReversed := func(tok []byte) error {
+		for i := len(tok) - 1; i >= 0; i-- {
+			err := bw.WriteByte(tok[i])
+			if err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+	long := newSpillBuffer(spillThreshold)
+	defer long.Close()
+
+	leading := true
+	var trailing [][]byte
+	for sc.Scan() {
+		tok := sc.Bytes()
+		if isSep != nil && isSep(tok) {
+			if leading {
+				_, err := out.Write(tok)
+				if err != nil {
+					return err
+				}
+				continue
+			}
+			if continued {
+				trailing[len(trailing)-1] = append(trailing[len(trailing)-1], tok...)
+			} else {
+				trailing = append(trailing, append([]byte{}, tok...))
+			}
+			continued = partial
+			continue
+		}
+		leading = false
+		for _, sep := range trailing {
+			err := writeReversed(sep)
+			if err != nil {
+				return err
+			}
+		}
+		trailing = trailing[:0]
+		if !partial && !continued {
+			err := writeReversed(tok)
+			if err != nil {
+				return err
+			}
+			continue
+		}
+		_, err := long.Write(tok)
+		if err != nil {
+			return err
+		}
+		continued = partial
+		if continued {
+			continue
+		}
+		err = long.WriteReversedTo(bw)
+		if err == nil {
+			err = long.Reset()
+		}
+		if err != nil {
+			return err
+		}
+	}
+	if err := sc.Err(); err != nil {
+		if errors.Is(err, bufio.ErrTooLong) {
+			return fmt.Errorf("a grapheme cluster is longer than %d bytes", 2*reverseChunkSize)
+		}
+		return err
+	}
+	if err := bw.Flush(); err != nil {
+		return err
+	}
+	if err := sp.WriteReversedTo(out); err != nil {
+		return err
+	}
+	for _, sep := range trailing {
+		_, err := out.Write(sep)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (t *ReverseTransformer) Inverse() (StreamTransformer, error) {
+	return t, nil
+}
+
+func (t *ReverseTransformer) splitter() (bufio.SplitFunc, func([]byte) bool) {
+	switch t.Unit {
+	case ReverseGraphemes:
+		return scanGraphemes(), nil
+	case ReverseWords:
+		return scanWords, func(tok []byte) bool {
+			r, _ := utf8.DecodeRune(tok)
+			return unicode.IsSpace(r)
+		}
+	case ReverseLines:
+		return scanLines, isLineTerminator
+	default:
+		return bufio.ScanRunes, nil
+	}
+}
+
+// splitLong passes the tokens of split that are longer than reverseChunkSize
+// on in pieces and sets *partial for every piece but the last. A piece ends
+// before the last rune start in the buffer, so that neither a rune nor a CRLF
+// is cut in two.
+func splitLong(split bufio.SplitFunc, partial *bool) bufio.SplitFunc {
+	return func(data []byte, atEOF bool) (int, []byte, error) {
+		advance, tok, err := split(data, atEOF)
+		*partial = false
+		if advance > 0 || tok != nil || err != nil || atEOF || len(data) < reverseChunkSize {
+			return advance, tok, err
+		}
+		n := len(data) - 1
+		for n > len(data)-utf8.UTFMax && !utf8.RuneStart(data[n]) {
+			n--
+		}
+		*partial = true
+		return n, data[:n], nil
+	}
+}
+
+// scanGraphemes splits extended grapheme clusters. A cluster is only emitted
+// once the rune following it is fully buffered, because that rune decides
+// whether the cluster ends.
+func scanGraphemes() bufio.SplitFunc {
+	state := -1
+	return func(data []byte, atEOF bool) (int, []byte, error) {
+		if len(data) == 0 || (!atEOF && !utf8.FullRune(data)) {
+			return 0, nil, nil
+		}
+		cluster, rest, _, newState := uniseg.FirstGraphemeCluster(data, state)
+		if !atEOF && (len(rest) == 0 || !utf8.FullRune(rest)) {
+			return 0, nil, nil
+		}
+		state = newState
+		return len(cluster), cluster, nil
+	}
+}
+
+// scanWords splits runs of white space and runs of other characters.
+func scanWords(data []byte, atEOF bool) (int, []byte, error) {
+	if len(data) == 0 {
+		return 0, nil, nil
+	}
+	first, _ := utf8.DecodeRune(data)
+	space := unicode.IsSpace(first)
+	for i := 0; i < len(data); {
+		if !atEOF && !utf8.FullRune(data[i:]) {
+			return 0, nil, nil
+		}
+		r, size := utf8.DecodeRune(data[i:])
+		if unicode.IsSpace(r) != space {
+			return i, data[:i], nil
+		}
+		i += size
+	}
+	if atEOF {
+		return len(data), data, nil
+	}
+	return 0, nil, nil
+}
+
+// scanLines splits lines and their LF or CRLF terminators into separate
+// tokens.
+func scanLines(data []byte, atEOF bool) (int, []byte, error) {
+	if len(data) == 0 {
+		return 0, nil, nil
+	}
+	if data[0] == '\n' {
+		return 1, data[:1], nil
+	}
+	if data[0] == '\r' {
+		if len(data) > 1 && data[1] == '\n' {
+			return 2, data[:2], nil
+		}
+		if len(data) == 1 && !atEOF {
+			return 0, nil, nil
+		}
+	}
+	for i := 1; i < len(data); i++ {
+		switch {
+		case data[i] == '\n':
+			return i, data[:i], nil
+		case data[i] == '\r' && i+1 < len(data) && data[i+1] == '\n':
+			return i, data[:i], nil
+		case data[i] == '\r' && i+1 == len(data) && !atEOF:
+			return 0, nil, nil
+		}
+	}
+	if atEOF {
+		return len(data), data, nil
+	}
+	return 0, nil, nil
+}
+
+func isLineTerminator(tok []byte) bool {
+	return tok[0] == '\n' || (len(tok) == 2 && tok[0] == '\r' && tok[1] == '\n')
+}
@@ -1,8 +1,10 @@
 package transformer
 
 import (
+	"io"
 	"strings"
 	"testing"
+	"testing/iotest"
 )
 
 type TestReverse struct {
@@ -16,7 +18,7 @@ var TestArrayReverse = []TestReverse{
 	TestReverse{"12345", "54321", false},
 	TestReverse{"1", "1", false},
 	TestReverse{"", "", false},
-	TestReverse{"1230", "321", true},
+	TestReverse{"123\n", "321", true},
 }
 
 func TestTableReverse(t *testing.T) {
@@ -34,3 +36,85 @@ func TestTableReverse(t *testing.T) {
 
 	}
 }
+
+type TestReverseUnit struct {
+	input, expected, unit string
+}
+
+var TestArrayReverseUnit = []TestReverseUnit{
+	TestReverseUnit{"éa", "aé", ReverseGraphemes},
+	TestReverseUnit{"éa", "áe", ReverseRunes},
+	TestReverseUnit{"a👨‍👩‍👧b", "b👨‍👩‍👧a", ReverseGraphemes},
+	TestReverseUnit{"🇺🇦🇵🇱", "🇵🇱🇺🇦", ReverseGraphemes},
+	TestReverseUnit{"각x", "x각", ReverseGraphemes},
+	TestReverseUnit{"", "", ReverseGraphemes},
+	TestReverseUnit{"hello big  world", "world  big hello", ReverseWords},
+	TestReverseUnit{"  one two\n", "  two one\n", ReverseWords},
+	TestReverseUnit{"a\nb\nc", "c\nb\na", ReverseLines},
+	TestReverseUnit{"a\r\nb\r\n", "b\r\na\r\n", ReverseLines},
+	TestReverseUnit{"x\ry\nz\n", "z\nx\ry\n", ReverseLines},
+	TestReverseUnit{"\n\none\ntwo\n\n", "\n\ntwo\none\n\n", ReverseLines},
+}
+
+func TestTableReverseUnit(t *testing.T) {
+
+	for _, test := range TestArrayReverseUnit {
+
+		tr, err := NewReverseUnitTransformer(test.unit)
+		if err != nil {
+			t.Fatalf("Error creating transformer: %s", err)
+		}
+		result := new(strings.Builder)
+		err = tr.TransformStream(iotest.OneByteReader(strings.NewReader(test.input)), result)
+		if err != nil {
+			t.Errorf("Error transforming: %s", err)
+		}
+
+		if result.String() != test.expected {
+			t.Errorf("Error: result = %q, expected = %q", result.String(), test.expected)
+		}
+
+	}
+}
+
+type TestReverseLong struct {
+	input, expected, unit string
+}
+
+// longToken is longer than the pieces long words and lines are read in.
+var longToken = strings.Repeat("ab", reverseChunkSize) + "é" + strings.Repeat("c", reverseChunkSize)
+
+var TestArrayReverseLong = []TestReverseLong{
+	TestReverseLong{longToken + " x", "x " + longToken, ReverseWords},
+	TestReverseLong{"x " + longToken + "  y", "y  " + longToken + " x", ReverseWords},
+	TestReverseLong{"x" + strings.Repeat(" ", 3*reverseChunkSize) + "y ", "y" + strings.Repeat(" ", 3*reverseChunkSize) + "x ", ReverseWords},
+	TestReverseLong{longToken + "\r\nx\r\n", "x\r\n" + longToken + "\r\n", ReverseLines},
+	TestReverseLong{"x\n" + longToken + "\r" + longToken, longToken + "\r" + longToken + "\nx", ReverseLines},
+	TestReverseLong{strings.Repeat("\xff", 3*reverseChunkSize) + "\nx", "x\n" + strings.Repeat("\xff", 3*reverseChunkSize), ReverseLines},
+}
+
+func TestTableReverseLong(t *testing.T) {
+
+	for _, test := range TestArrayReverseLong {
+
+		tr, err := NewReverseUnitTransformer(test.unit)
+		if err != nil {
+			t.Fatalf("Error creating transformer: %s", err)
+		}
+		result := new(strings.Builder)
+		err = tr.TransformStream(strings.NewReader(test.input), result)
+		if err != nil {
+			t.Errorf("Error transforming %d bytes by %s: %s", len(test.input), test.unit, err)
+		}
+
+		if result.String() != test.expected {
+			t.Errorf("Error: reversing %d bytes by %s gives %d bytes, not the expected", len(test.input), test.unit, result.Len())
+		}
+	}
+
+	tr, _ := NewReverseUnitTransformer(ReverseGraphemes)
+	err := tr.TransformStream(strings.NewReader("e"+strings.Repeat("\u0301", reverseChunkSize)), io.Discard)
+	if err == nil || !strings.Contains(err.Error(), "grapheme cluster is longer") {
+		t.Errorf("Error: a longToken grapheme cluster gives %v", err)
+	}
+}
@@ -0,0 +1,123 @@
+package transformer
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// Selector picks values of a JSON or YAML document with a JSONPath subset:
+// $ is the root, .name or ['name'] a key, [n] an array index, * any key or
+// index and .. any depth, as in $.user.name, $.items[*].id or $..password.
+// A selector that does not start with $ matches its keys at any depth, so
+// password is the same as $..password.
+type Selector struct {
+	steps []selectorStep
+}
+
+type selectorStep struct {
+	key        string
+	index      int
+	isIndex    bool
+	wildcard   bool
+	descendant bool
+}
+
+// pathElem is a key or an index on the way from the root to a value.
+type pathElem struct {
+	key     string
+	index   int
+	isIndex bool
+}
+
+func ParseSelector(s string) (*Selector, error) {
+	rest := strings.TrimSpace(s)
+	if rest == "" {
+		return nil, fmt.Errorf("empty selector")
+	}
+	if rest[0] == '$' {
+		rest = rest[1:]
+	} else {
+		rest = ".." + rest
+	}
+	sel := &Selector{}
+	for rest != "" {
+		var step selectorStep
+		switch {
+		case strings.HasPrefix(rest, ".."):
+			step.descendant = true
+			rest = rest[2:]
+			if strings.HasPrefix(rest, "[") {
+				break
+			}
+			fallthrough
+		case strings.HasPrefix(rest, "."):
+			rest = strings.TrimPrefix(rest, ".")
+			end := strings.IndexAny(rest, ".[")
+			if end < 0 {
+				end = len(rest)
+			}
+			if end == 0 {
+				return nil, fmt.Errorf("selector %q: expected key after dot", s)
+			}
+			step.key, rest = rest[:end], rest[end:]
+			step.wildcard = step.key == "*"
+			sel.steps = append(sel.steps, step)
+			continue
+		case !strings.HasPrefix(rest, "["):
+			return nil, fmt.Errorf("selector %q: unexpected %q", s, rest)
+		}
+		end := strings.IndexByte(rest, ']')
+		if end < 0 {
+			return nil, fmt.Errorf("selector %q: missing ]", s)
+		}
+		inner := rest[1:end]
+		rest = rest[end+1:]
+		switch {
+		case inner == "*":
+			step.wildcard = true
+		case len(inner) >= 2 && (inner[0] == '\'' || inner[0] == '"') && inner[len(inner)-1] == inner[0]:
+			step.key = inner[1 : len(inner)-1]
+		default:
+			n, err := strconv.Atoi(inner)
+			if err != nil || n < 0 {
+				return nil, fmt.Errorf("selector %q: invalid index %q", s, inner)
+			}
+			step.index, step.isIndex = n, true
+		}
+		sel.steps = append(sel.steps, step)
+	}
+	return sel, nil
+}
+
+// Match reports whether the value at path, or a value containing it, is
+// selected.
+func (s *Selector) Match(path []pathElem) bool {
+	return matchSteps(s.steps, path)
+}
+
+func matchSteps(steps []selectorStep, path []pathElem) bool {
+	if len(steps) == 0 {
+		return true
+	}
+	step := steps[0]
+	if !step.descendant {
+		return len(path) > 0 && step.matches(path[0]) && matchSteps(steps[1:], path[1:])
+	}
+	for i := range path {
+		if step.matches(path[i]) && matchSteps(steps[1:], path[i+1:]) {
+			return true
+		}
+	}
+	return false
+}
+
+func (s selectorStep) matches(e pathElem) bool {
+	switch {
+	case s.wildcard:
+		return true
+	case s.isIndex:
+		return e.isIndex && e.index == s.index
+	}
+	return !e.isIndex && e.key == s.key
+}
@@ -0,0 +1,101 @@
+package transformer
+
+import (
+	"io"
+	"os"
+)
+
+// spillThreshold is how many bytes a spillBuffer keeps in memory before it
+// moves its content to a temporary file.
+const spillThreshold = 4 << 20
+
+const spillBlockSize = 64 << 10
+
+// spillBuffer collects written bytes in memory and spills them to a temporary
+// file once the threshold is exceeded, so it can hold inputs of any size.
+type spillBuffer struct {
+	limit int
+	buf   []byte
+	file  *os.File
+	size  int64
+}
+
+func newSpillBuffer(limit int) *spillBuffer {
+	return &spillBuffer{limit: limit}
+}
+
+func (s *spillBuffer) Write(p []byte) (int, error) {
+	if s.file == nil && len(s.buf)+len(p) <= s.limit {
+		s.buf = append(s.buf, p...)
+		s.size += int64(len(p))
+		return len(p), nil
+	}
+	if s.file == nil {
+		f, err := os.CreateTemp("", "transformer-spill-*")
+		if err != nil {
+			return 0, err
+		}
+		s.file = f
+		_, err = f.Write(s.buf)
+		if err != nil {
+			return 0, err
+		}
+		s.buf = nil
+	}
+	n, err := s.file.Write(p)
+	s.size += int64(n)
+	return n, err
+}
+
+// WriteReversedTo writes the buffered bytes to w in reverse order.
+func (s *spillBuffer) WriteReversedTo(w io.Writer) error {
+	if s.file == nil {
+		reverseBytes(s.buf)
+		_, err := w.Write(s.buf)
+		return err
+	}
+	block := make([]byte, spillBlockSize)
+	for end := s.size; end > 0; {
+		start := end - spillBlockSize
+		if start < 0 {
+			start = 0
+		}
+		b := block[:end-start]
+		_, err := s.file.ReadAt(b, start)
+		if err != nil {
+			return err
+		}
+		reverseBytes(b)
+		_, err = w.Write(b)
+		if err != nil {
+			return err
+		}
+		end = start
+	}
+	return nil
+}
+
+// Reset empties the buffer so that it can be written again.
+func (s *spillBuffer) Reset() error {
+	s.buf, s.size = s.buf[:0], 0
+	return s.Close()
+}
+
+func (s *spillBuffer) Close() error {
+	if s.file == nil {
+		return nil
+	}
+	name := s.file.Name()
+	err := s.file.Close()
+	s.file = nil
+	if rmErr := os.Remove(name); err == nil {
+		err = rmErr
+	}
+	return err
+}
+
+func reverseBytes(b []byte) {
+	for i, j := 0, len(b)-1; i < j; i, j = i+1, j-1 {
+		b[i], b[j] = b[j], b[i]
+	}
+}
@@ -0,0 +1,96 @@
+package transformer
+
+import (
+	"bytes"
+	"io"
+	"strings"
+	"testing"
+	"testing/iotest"
+)
+
+type TestStream struct {
+	tr              StreamTransformer
+	input, expected string
+}
+
+var TestArrayStream = []TestStream{
+	TestStream{NewCaesarTransformer(1), "zab", "abc"},
+	TestStream{NewReverseTransformer(), "abcd", "dcba"},
+	TestStream{NewReverseTransformer(), "привіт", "тівирп"},
+	TestStream{NewReverseTransformer(), "", ""},
+	TestStream{NewBase64Transformer(), "Man", "TWFu"},
+	TestStream{NewBase64Transformer(), "Ma", "TWE="},
+}
+
+func TestTableStream(t *testing.T) {
+
+	for _, test := range TestArrayStream {
+
+		buf := new(bytes.Buffer)
+		err := test.tr.TransformStream(iotest.OneByteReader(strings.NewReader(test.input)), buf)
+		if err != nil {
+			t.Errorf("Error transforming: %s", err)
+		}
+
+		if buf.String() != test.expected {
+			t.Errorf("Error: result = %q, expected = %q", buf.String(), test.expected)
+		}
+
+	}
+}
+
+func TestSpillBuffer(t *testing.T) {
+	input := strings.Repeat("0123456789", spillBlockSize/5)
+	sp := newSpillBuffer(16)
+	defer sp.Close()
+
+	_, err := io.Copy(sp, strings.NewReader(input))
+	if err != nil {
+		t.Fatalf("Error writing spill buffer: %s", err)
+	}
+	if sp.file == nil {
+		t.Fatalf("Error: spill buffer did not spill to a file")
+	}
+
+	buf := new(bytes.Buffer)
+	err = sp.WriteReversedTo(buf)
+	if err != nil {
+		t.Fatalf("Error reading spill buffer: %s", err)
+	}
+	expected := []byte(input)
+	reverseBytes(expected)
+	if !bytes.Equal(buf.Bytes(), expected) {
+		t.Errorf("Error: spilled content was not reversed")
+	}
+}
+
+type TestTrimNewline struct {
+	input, expected string
+}
+
+var TestArrayTrimNewline = []TestTrimNewline{
+	TestTrimNewline{"", ""},
+	TestTrimNewline{"abc", "abc"},
+	TestTrimNewline{"abc\n", "abc"},
+	TestTrimNewline{"abc\r\n", "abc"},
+	TestTrimNewline{"abc\n\n", "abc\n"},
+	TestTrimNewline{"a\nb\r", "a\nb\r"},
+	TestTrimNewline{"\n", ""},
+}
+
+func TestTableTrimNewline(t *testing.T) {
+
+	for _, test := range TestArrayTrimNewline {
+
+		for _, r := range []io.Reader{strings.NewReader(test.input), iotest.OneByteReader(strings.NewReader(test.input))} {
+			result, err := io.ReadAll(newTrimNewlineReader(r))
+			if err != nil {
+				t.Errorf("Error reading: %s", err)
+			}
+			if string(result) != test.expected {
+				t.Errorf("Error: result = %q, expected = %q", result, test.expected)
+			}
+		}
+
+	}
+}
@@ -0,0 +1,256 @@
+package transformer
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"regexp"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+
+	"gopkg.in/yaml.v3"
+)
+
+const (
+	FormatJSON = "json"
+	FormatYAML = "yaml"
+)
+
+// Select makes tr transform the string values of a JSON or YAML document
+// picked by selector, strings inside a selected object or array included.
+// Keys, other values and the structure stay as they are. JSON keeps its
+// formatting, YAML is written again with its comments.
+func Select(tr StreamTransformer, selector, format string) (StreamTransformer, error) {
+	sel, err := ParseSelector(selector)
+	if err != nil {
+		return nil, err
+	}
+	switch format {
+	case "", FormatJSON:
+		return &jsonSelectTransformer{tr: tr, sel: sel}, nil
+	case FormatYAML:
+		return &yamlSelectTransformer{tr: tr, sel: sel}, nil
+	}
+	return nil, fmt.Errorf("unknown document format %q, expected json or yaml", format)
+}
+
+// transformValue transforms a selected value, which must stay text to be
+// written back into the document.
+func transformValue(tr StreamTransformer, value string, offset int64) (string, error) {
+	result, err := transformString(tr, value, offset)
+	if err != nil {
+		return "", err
+	}
+	if !utf8.ValidString(result) {
+		return "", errors.New("transformed value is not valid UTF-8, chain a codec such as base64")
+	}
+	return result, nil
+}
+
+type jsonSelectTransformer struct {
+	tr  StreamTransformer
+	sel *Selector
+}
+
+// jsonEdit replaces the string literal at data[start:end].
+type jsonEdit struct {
+	start, end int64
+	value      string
+}
+
+func (t *jsonSelectTransformer) TransformStream(in io.Reader, out io.Writer) error {
+	data, err := io.ReadAll(in)
+	if err != nil {
+		return err
+	}
+	w := &jsonWalker{dec: json.NewDecoder(bytes.NewReader(data)), data: data, t: t}
+	w.dec.UseNumber()
+	for w.dec.More() {
+		if err := w.value(nil); err != nil {
+			var syntax *json.SyntaxError
+			if errors.As(err, &syntax) {
+				return &MalformedInputError{Format: "JSON", Offset: syntax.Offset}
+			}
+			if errors.Is(err, io.EOF) || errors.Is(err, io.ErrUnexpectedEOF) {
+				return &MalformedInputError{Format: "JSON", Offset: int64(len(data)), Hint: "input is truncated"}
+			}
+			return err
+		}
+	}
+	// More stops at the first byte that cannot start a value
+	rest := w.dec.InputOffset()
+	if trailing := bytes.TrimLeft(data[rest:], " \t\r\n"); len(trailing) > 0 {
+		return &MalformedInputError{Format: "JSON", Offset: int64(len(data) - len(trailing))}
+	}
+
+	var last int64
+	for _, edit := range w.edits {
+		if _, err := out.Write(data[last:edit.start]); err != nil {
+			return err
+		}
+		var literal bytes.Buffer
+		enc := json.NewEncoder(&literal)
+		enc.SetEscapeHTML(false)
+		if err := enc.Encode(edit.value); err != nil {
+			return err
+		}
+		if _, err := out.Write(bytes.TrimSuffix(literal.Bytes(), []byte("\n"))); err != nil {
+			return err
+		}
+		last = edit.end
+	}
+	_, err = out.Write(data[last:])
+	return err
+}
+
+func (t *jsonSelectTransformer) Inverse() (StreamTransformer, error) {
+	inv, err := Inverse(t.tr)
+	if err != nil {
+		return nil, err
+	}
+	return &jsonSelectTransformer{tr: inv, sel: t.sel}, nil
+}
+
+type jsonWalker struct {
+	dec   *json.Decoder
+	data  []byte
+	t     *jsonSelectTransformer
+	edits []jsonEdit
+}
+
+func (w *jsonWalker) value(path []pathElem) error {
+	before := w.dec.InputOffset()
+	tok, err := w.dec.Token()
+	if err != nil {
+		return err
+	}
+	switch v := tok.(type) {
+	case json.Delim:
+		for i := 0; w.dec.More(); i++ {
+			elem := pathElem{index: i, isIndex: true}
+			if v == '{' {
+				key, err := w.dec.Token()
+				if err != nil {
+					return err
+				}
+				elem = pathElem{key: key.(string)}
+			}
+			if err := w.value(append(path, elem)); err != nil {
+				return err
+			}
+		}
+		_, err = w.dec.Token()
+		return err
+	case string:
+		if !w.t.sel.Match(path) {
+			return nil
+		}
+		// the token read may start with the separators before the literal
+		end := w.dec.InputOffset()
+		start := before + int64(bytes.IndexByte(w.data[before:end], '"'))
+		result, err := transformValue(w.t.tr, v, start+1)
+		if err != nil {
+			return err
+		}
+		w.edits = append(w.edits, jsonEdit{start: start, end: end, value: result})
+	}
+	return nil
+}
+
+type yamlSelectTransformer struct {
+	tr  StreamTransformer
+	sel *Selector
+}
+
+var yamlErrorLine = regexp.MustCompile(`^yaml: line (\d+):`)
+
+func (t *yamlSelectTransformer) TransformStream(in io.Reader, out io.Writer) error {
+	data, err := io.ReadAll(in)
+	if err != nil {
+		return err
+	}
+	lines := []int64{0}
+	for i, b := range data {
+		if b == '\n' {
+			lines = append(lines, int64(i+1))
+		}
+	}
+	offset := func(line, column int) int64 {
+		if line < 1 || line > len(lines) {
+			return 0
+		}
+		return lines[line-1] + int64(column-1)
+	}
+
+	dec := yaml.NewDecoder(bytes.NewReader(data))
+	enc := yaml.NewEncoder(out)
+	enc.SetIndent(2)
+	for {
+		var doc yaml.Node
+		err := dec.Decode(&doc)
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			malformed := &MalformedInputError{Format: "YAML", Hint: strings.TrimPrefix(err.Error(), "yaml: ")}
+			if m := yamlErrorLine.FindStringSubmatch(err.Error()); m != nil {
+				line, _ := strconv.Atoi(m[1])
+				malformed.Offset = offset(line, 1)
+			}
+			return malformed
+		}
+		err = t.walk(&doc, nil, offset)
+		if err != nil {
+			return err
+		}
+		if err := enc.Encode(&doc); err != nil {
+			return err
+		}
+	}
+	return enc.Close()
+}
+
+func (t *yamlSelectTransformer) walk(node *yaml.Node, path []pathElem, offset func(line, column int) int64) error {
+	switch node.Kind {
+	case yaml.DocumentNode:
+		for _, child := range node.Content {
+			if err := t.walk(child, path, offset); err != nil {
+				return err
+			}
+		}
+	case yaml.MappingNode:
+		for i := 0; i+1 < len(node.Content); i += 2 {
+			if err := t.walk(node.Content[i+1], append(path, pathElem{key: node.Content[i].Value}), offset); err != nil {
+				return err
+			}
+		}
+	case yaml.SequenceNode:
+		for i, child := range node.Content {
+			if err := t.walk(child, append(path, pathElem{index: i, isIndex: true}), offset); err != nil {
+				return err
+			}
+		}
+	case yaml.ScalarNode:
+		if node.ShortTag() != "!!str" || !t.sel.Match(path) {
+			return nil
+		}
+		result, err := transformValue(t.tr, node.Value, offset(node.Line, node.Column))
+		if err != nil {
+			return err
+		}
+		// the encoder quotes values that would no longer read as strings
+		node.Value = result
+	}
+	return nil
+}
+
+func (t *yamlSelectTransformer) Inverse() (StreamTransformer, error) {
+	inv, err := Inverse(t.tr)
+	if err != nil {
+		return nil, err
+	}
+	return &yamlSelectTransformer{tr: inv, sel: t.sel}, nil
+}
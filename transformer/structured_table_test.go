@@ -0,0 +1,109 @@
+package transformer
+
+import (
+	"errors"
+	"strings"
+	"testing"
+)
+
+type TestSelect struct {
+	opts            Options
+	input, expected string
+}
+
+var TestArraySelect = []TestSelect{
+	TestSelect{Options{Select: "$..password", Base64: true},
+		`{"user": {"name": "ann", "password": "secret"}, "db": [{"password": "x<y"}], "password": 5}`,
+		`{"user": {"name": "ann", "password": "c2VjcmV0"}, "db": [{"password": "eDx5"}], "password": 5}`},
+	TestSelect{Options{Select: "$.user.name", CaesarShift: 3},
+		"{\n  \"user\" : {\"name\":\"ann\", \"nick\": \"ann\"},\n  \"name\": \"bob\"\n}\n",
+		"{\n  \"user\" : {\"name\":\"dqq\", \"nick\": \"ann\"},\n  \"name\": \"bob\"\n}\n"},
+	TestSelect{Options{Select: "$.items[*]", ReverseUnit: ReverseRunes},
+		`{"items": ["ab", {"c": "de"}, 1, null], "other": "xy"}`,
+		`{"items": ["ba", {"c": "ed"}, 1, null], "other": "xy"}`},
+	TestSelect{Options{Select: "$[1]['a b']"}, `[{"a b": "12"}, {"a b": "\"qé"}]`, `[{"a b": "12"}, {"a b": "éq\""}]`},
+	TestSelect{Options{Select: "name"}, "{\"name\": \"ab\"}\n{\"name\": \"cd\"}", "{\"name\": \"ba\"}\n{\"name\": \"dc\"}"},
+	TestSelect{Options{Select: "$..password", Format: FormatYAML, Base64: true},
+		"# settings\nuser:\n  name: ann\n  password: secret # change me\nport: 8080\n",
+		"# settings\nuser:\n  name: ann\n  password: c2VjcmV0 # change me\nport: 8080\n"},
+	TestSelect{Options{Select: "$.list[0]", Format: FormatYAML, Pipeline: "reverse"},
+		"list:\n  - \"321\"\n  - ab\n---\nlist: [xy]\n",
+		"list:\n  - \"123\"\n  - ab\n---\nlist: [yx]\n"},
+}
+
+func TestTableSelect(t *testing.T) {
+
+	for _, test := range TestArraySelect {
+
+		tr, err := NewTransformer(test.opts)
+		if err != nil {
+			t.Fatalf("Error creating transformer: %s", err)
+		}
+		result := new(strings.Builder)
+		err = tr.TransformStream(strings.NewReader(test.input), result)
+		if err != nil {
+			t.Errorf("Error transforming %q: %s", test.input, err)
+		}
+
+		if result.String() != test.expected {
+			t.Errorf("Error: result = %q, expected = %q", result.String(), test.expected)
+		}
+
+	}
+}
+
+func TestSelectRoundTrip(t *testing.T) {
+	input := `{"a": {"b": ["x", "y"]}, "c": "z"}`
+	tr, err := NewTransformer(Options{Select: "$.a", Pipeline: "caesar:5 | base64"})
+	if err != nil {
+		t.Fatalf("Error creating transformer: %s", err)
+	}
+	encoded := new(strings.Builder)
+	if err := tr.TransformStream(strings.NewReader(input), encoded); err != nil {
+		t.Fatalf("Error transforming: %s", err)
+	}
+	inv, err := Inverse(tr)
+	if err != nil {
+		t.Fatalf("Error inverting: %s", err)
+	}
+	decoded := new(strings.Builder)
+	if err := inv.TransformStream(strings.NewReader(encoded.String()), decoded); err != nil {
+		t.Fatalf("Error transforming back: %s", err)
+	}
+	if decoded.String() != input {
+		t.Errorf("Error: round trip = %q, expected = %q", decoded.String(), input)
+	}
+}
+
+func TestSelectErrors(t *testing.T) {
+	for _, selector := range []string{"$.", "$[x]", "$[-1]", "$['a'", "$a", ""} {
+		if _, err := ParseSelector(selector); err == nil {
+			t.Errorf("Error: expected error for selector %q", selector)
+		}
+	}
+	if _, err := NewTransformer(Options{Select: "a", Lines: true}); err == nil {
+		t.Errorf("Error: expected error for selector in line mode")
+	}
+
+	var malformed *MalformedInputError
+	for _, test := range []struct {
+		format, input string
+		offset        int64
+	}{
+		{FormatJSON, `{"a": "b",}`, 10},
+		{FormatJSON, `{"a": "b"} x`, 12},
+		{FormatJSON, `{"a": "b"`, 9},
+		{FormatYAML, "a: b\n  c: d\n", 5},
+	} {
+		tr, _ := NewTransformer(Options{Select: "a", Format: test.format})
+		err := tr.TransformStream(strings.NewReader(test.input), new(strings.Builder))
+		if !errors.As(err, &malformed) || malformed.Offset != test.offset {
+			t.Errorf("Error: expected malformed %s at offset %d for %q, got %v", test.format, test.offset, test.input, err)
+		}
+	}
+
+	tr, _ := NewTransformer(Options{Select: "a", Compress: "gzip"})
+	if err := tr.TransformStream(strings.NewReader(`{"a": "b"}`), new(strings.Builder)); err == nil {
+		t.Errorf("Error: expected error for binary value")
+	}
+}
@@ -0,0 +1,169 @@
+package transformer
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"unicode"
+)
+
+var atbashSpec = Spec{
+	Name:        "atbash",
+	Description: "Atbash cipher, mirror the alphabet",
+	MainParam:   "alphabet",
+	Params:      []Param{alphabetParam},
+	Text:        true,
+	Invertible:  true,
+	New: func(p Params) (StreamTransformer, error) {
+		return NewAtbashTransformer(p["alphabet"])
+	},
+}
+
+type AtbashTransformer struct {
+	Alphabet []rune
+}
+
+func NewAtbashTransformer(alphabet string) (*AtbashTransformer, error) {
+	letters, err := CaesarAlphabet(alphabet)
+	if err != nil {
+		return nil, err
+	}
+	return &AtbashTransformer{Alphabet: letters}, nil
+}
+
+func (t *AtbashTransformer) TransformStream(in io.Reader, out io.Writer) error {
+	alphabet := orDefaultAlphabet(t.Alphabet)
+	reversed := append([]rune{}, alphabet...)
+	for i, j := 0, len(reversed)-1; i < j; i, j = i+1, j-1 {
+		reversed[i], reversed[j] = reversed[j], reversed[i]
+	}
+	return mapRunes(in, out, substitutionTable(alphabet, reversed))
+}
+
+func (t *AtbashTransformer) Inverse() (StreamTransformer, error) {
+	return t, nil
+}
+
+var substitutionSpec = Spec{
+	Name:        "substitution",
+	Description: "Monoalphabetic substitution with a keyword or a full keyed alphabet",
+	MainParam:   "key",
+	Params: []Param{
+		{Name: "key", Type: ParamString, Required: true, Description: "Keyword or permutation of the alphabet"},
+		alphabetParam,
+	},
+	Text:       true,
+	Invertible: true,
+	New: func(p Params) (StreamTransformer, error) {
+		return NewSubstitutionTransformer(p["key"], p["alphabet"])
+	},
+}
+
+// SubstitutionTransformer replaces every letter of the alphabet with the
+// letter at the same position of the keyed alphabet.
+type SubstitutionTransformer struct {
+	Alphabet []rune
+	Key      []rune
+	Decrypt  bool
+}
+
+// NewSubstitutionTransformer accepts either a full permutation of the
+// alphabet or a keyword, in which case the keyed alphabet is the keyword
+// without repeated letters followed by the rest of the alphabet.
+func NewSubstitutionTransformer(key string, alphabet string) (*SubstitutionTransformer, error) {
+	letters, err := CaesarAlphabet(alphabet)
+	if err != nil {
+		return nil, err
+	}
+	keyed, err := keyedAlphabet(key, letters)
+	if err != nil {
+		return nil, err
+	}
+	return &SubstitutionTransformer{Alphabet: letters, Key: keyed}, nil
+}
+
+func (t *SubstitutionTransformer) TransformStream(in io.Reader, out io.Writer) error {
+	alphabet := orDefaultAlphabet(t.Alphabet)
+	if len(t.Key) != len(alphabet) {
+		return fmt.Errorf("substitution key has %d letters, alphabet has %d", len(t.Key), len(alphabet))
+	}
+	if t.Decrypt {
+		return mapRunes(in, out, substitutionTable(t.Key, alphabet))
+	}
+	return mapRunes(in, out, substitutionTable(alphabet, t.Key))
+}
+
+func (t *SubstitutionTransformer) Inverse() (StreamTransformer, error) {
+	return &SubstitutionTransformer{Alphabet: t.Alphabet, Key: t.Key, Decrypt: !t.Decrypt}, nil
+}
+
+func keyedAlphabet(key string, alphabet []rune) ([]rune, error) {
+	if key == "" {
+		return nil, fmt.Errorf("expected substitution key")
+	}
+	inAlphabet := make(map[rune]bool, len(alphabet))
+	for _, r := range alphabet {
+		inAlphabet[r] = true
+	}
+	used := make(map[rune]bool, len(alphabet))
+	keyed := make([]rune, 0, len(alphabet))
+	for _, r := range key {
+		r = unicode.ToLower(r)
+		if !inAlphabet[r] {
+			return nil, fmt.Errorf("substitution key letter %q is not in the alphabet", r)
+		}
+		if !used[r] {
+			used[r] = true
+			keyed = append(keyed, r)
+		}
+	}
+	for _, r := range alphabet {
+		if !used[r] {
+			keyed = append(keyed, r)
+		}
+	}
+	return keyed, nil
+}
+
+func orDefaultAlphabet(alphabet []rune) []rune {
+	if len(alphabet) == 0 {
+		return []rune(CaesarAlphabets[DefaultCaesarAlphabet])
+	}
+	return alphabet
+}
+
+// substitutionTable maps every letter of from to the letter at the same
+// position of to, for the lower- and upper-case forms alike.
+func substitutionTable(from, to []rune) map[rune]rune {
+	table := make(map[rune]rune, 2*len(from)+10)
+	for i, r := range from {
+		table[r] = to[i]
+		if upper := unicode.ToUpper(r); upper != r {
+			table[upper] = unicode.ToUpper(to[i])
+		}
+	}
+	return table
+}
+
+// mapRunes copies in to out, replacing the runes found in table.
+func mapRunes(in io.Reader, out io.Writer, table map[rune]rune) error {
+	br := bufio.NewReader(in)
+	bw := bufio.NewWriter(out)
+	for {
+		rn, _, err := br.ReadRune()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return err
+		}
+		if mapped, ok := table[rn]; ok {
+			rn = mapped
+		}
+		_, err = bw.WriteRune(rn)
+		if err != nil {
+			return err
+		}
+	}
+	return bw.Flush()
+}
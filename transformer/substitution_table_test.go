@@ -0,0 +1,63 @@
+package transformer
+
+import (
+	"strings"
+	"testing"
+)
+
+type TestSubstitution struct {
+	tr              StreamTransformer
+	input, expected string
+}
+
+func mustAtbash(alphabet string) StreamTransformer {
+	tr, err := NewAtbashTransformer(alphabet)
+	if err != nil {
+		panic(err)
+	}
+	return tr
+}
+
+func mustSubstitution(key string, decrypt bool) StreamTransformer {
+	tr, err := NewSubstitutionTransformer(key, "")
+	if err != nil {
+		panic(err)
+	}
+	tr.Decrypt = decrypt
+	return tr
+}
+
+var TestArraySubstitution = []TestSubstitution{
+	TestSubstitution{mustAtbash(""), "abc xyz", "zyx cba"},
+	TestSubstitution{mustAtbash(""), "Hello, World!", "Svool, Dliow!"},
+	TestSubstitution{mustAtbash("ukrainian"), "Аб", "Яю"},
+	TestSubstitution{mustSubstitution("zebras", false), "flee at once", "siaa zq lkba"},
+	TestSubstitution{mustSubstitution("zebras", true), "siaa zq lkba", "flee at once"},
+	TestSubstitution{mustSubstitution("qwertyuiopasdfghjklzxcvbnm", false), "Abc", "Qwe"},
+}
+
+func TestTableSubstitution(t *testing.T) {
+
+	for _, test := range TestArraySubstitution {
+
+		result := new(strings.Builder)
+		err := test.tr.TransformStream(strings.NewReader(test.input), result)
+		if err != nil {
+			t.Errorf("Error transforming: %s", err)
+		}
+
+		if result.String() != test.expected {
+			t.Errorf("Error: result = %q, expected = %q", result.String(), test.expected)
+		}
+
+	}
+}
+
+func TestSubstitutionInvalidKey(t *testing.T) {
+	for _, key := range []string{"", "key1"} {
+		_, err := NewSubstitutionTransformer(key, "")
+		if err == nil {
+			t.Errorf("Error: expected error for key %q", key)
+		}
+	}
+}
@@ -0,0 +1,231 @@
+package transformer
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"strings"
+	"unicode"
+
+	"golang.org/x/text/cases"
+	"golang.org/x/text/language"
+	"golang.org/x/text/transform"
+	"golang.org/x/text/unicode/norm"
+)
+
+const (
+	CaseUpper = "upper"
+	CaseLower = "lower"
+	CaseTitle = "title"
+	CaseSnake = "snake"
+	CaseCamel = "camel"
+	CaseKebab = "kebab"
+)
+
+var caseSpec = Spec{
+	Name:        "case",
+	Description: "Change letter case, or join the words of every line as snake_case, camelCase or kebab-case",
+	MainParam:   "style",
+	Params: []Param{
+		{Name: "style", Type: ParamString, Required: true, Choices: []string{CaseUpper, CaseLower, CaseTitle, CaseSnake, CaseCamel, CaseKebab}, Description: "Target case"},
+	},
+	Text: true,
+	New: func(p Params) (StreamTransformer, error) {
+		return NewCaseTransformer(p["style"])
+	},
+}
+
+// CaseTransformer converts letter case with the Unicode rules, so that "ß"
+// becomes "SS". The word styles split words at spaces, punctuation and
+// case changes, "HTTPServer" has the words "HTTP" and "Server".
+type CaseTransformer struct {
+	Style string
+}
+
+func NewCaseTransformer(style string) (*CaseTransformer, error) {
+	switch style {
+	case CaseUpper, CaseLower, CaseTitle, CaseSnake, CaseCamel, CaseKebab:
+		return &CaseTransformer{Style: style}, nil
+	}
+	return nil, fmt.Errorf("unknown case style %q", style)
+}
+
+func (t *CaseTransformer) TransformStream(in io.Reader, out io.Writer) error {
+	var caser cases.Caser
+	switch t.Style {
+	case CaseUpper:
+		caser = cases.Upper(language.Und)
+	case CaseLower:
+		caser = cases.Lower(language.Und)
+	case CaseTitle:
+		caser = cases.Title(language.Und)
+	case CaseSnake, CaseCamel, CaseKebab:
+		return forEachLine(in, out, func(line string, _ int64) (string, error) {
+			return t.joinWords(line), nil
+		})
+	default:
+		return fmt.Errorf("unknown case style %q", t.Style)
+	}
+	_, err := io.Copy(out, transform.NewReader(in, caser))
+	return err
+}
+
+func (t *CaseTransformer) joinWords(line string) string {
+	words := splitWords(line)
+	for i, w := range words {
+		w = strings.ToLower(w)
+		if t.Style == CaseCamel && i > 0 {
+			r := []rune(w)
+			r[0] = unicode.ToUpper(r[0])
+			w = string(r)
+		}
+		words[i] = w
+	}
+	switch t.Style {
+	case CaseSnake:
+		return strings.Join(words, "_")
+	case CaseKebab:
+		return strings.Join(words, "-")
+	}
+	return strings.Join(words, "")
+}
+
+func splitWords(s string) []string {
+	var words []string
+	var word []rune
+	flush := func() {
+		if len(word) > 0 {
+			words = append(words, string(word))
+			word = word[:0]
+		}
+	}
+	runes := []rune(s)
+	for i, r := range runes {
+		if !unicode.IsLetter(r) && !unicode.IsDigit(r) {
+			flush()
+			continue
+		}
+		if len(word) > 0 && unicode.IsUpper(r) {
+			prev := word[len(word)-1]
+			nextLower := i+1 < len(runes) && unicode.IsLower(runes[i+1])
+			if unicode.IsLower(prev) || unicode.IsDigit(prev) || unicode.IsUpper(prev) && nextLower {
+				flush()
+			}
+		}
+		word = append(word, r)
+	}
+	flush()
+	return words
+}
+
+const (
+	WhitespaceCollapse  = "collapse"
+	WhitespaceTrim      = "trim"
+	WhitespaceTrimLines = "trim_lines"
+)
+
+var whitespaceSpec = Spec{
+	Name:        "whitespace",
+	Description: "Collapse runs of whitespace into one space, or trim the text or every line",
+	MainParam:   "action",
+	Params: []Param{
+		{Name: "action", Type: ParamString, Default: WhitespaceCollapse, Choices: []string{WhitespaceCollapse, WhitespaceTrim, WhitespaceTrimLines}, Description: "collapse also trims the text"},
+	},
+	Text: true,
+	New: func(p Params) (StreamTransformer, error) {
+		action := p["action"]
+		if action == "" {
+			action = WhitespaceCollapse
+		}
+		return &WhitespaceTransformer{Action: action}, nil
+	},
+}
+
+// WhitespaceTransformer streams the input, holding back only the whitespace
+// that may turn out to be trailing.
+type WhitespaceTransformer struct {
+	Action string
+}
+
+func (t *WhitespaceTransformer) TransformStream(in io.Reader, out io.Writer) error {
+	br := bufio.NewReader(in)
+	bw := bufio.NewWriter(out)
+	var pending []rune
+	// cr is whether the last rune was a carriage return, which pending
+	// misses at the start of a line
+	started, cr := false, false
+	for {
+		r, _, err := br.ReadRune()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return err
+		}
+		switch {
+		case r == '\n' && t.Action == WhitespaceTrimLines:
+			if cr {
+				bw.WriteRune('\r')
+			}
+			bw.WriteRune('\n')
+			pending, started = pending[:0], false
+		case unicode.IsSpace(r):
+			if started {
+				pending = append(pending, r)
+			}
+		default:
+			if len(pending) > 0 {
+				if t.Action == WhitespaceCollapse {
+					bw.WriteRune(' ')
+				} else {
+					bw.WriteString(string(pending))
+				}
+				pending = pending[:0]
+			}
+			bw.WriteRune(r)
+			started = true
+		}
+		cr = r == '\r'
+	}
+	return bw.Flush()
+}
+
+var normalizeSpec = Spec{
+	Name:        "normalize",
+	Description: "Unicode normalization",
+	MainParam:   "form",
+	Params: []Param{
+		{Name: "form", Type: ParamString, Default: "nfc", Choices: []string{"nfc", "nfd", "nfkc", "nfkd"}, Description: "Normalization form, the k forms also replace compatibility characters"},
+	},
+	Text: true,
+	New: func(p Params) (StreamTransformer, error) {
+		return NewNormalizeTransformer(p["form"])
+	},
+}
+
+var normForms = map[string]norm.Form{
+	"nfc":  norm.NFC,
+	"nfd":  norm.NFD,
+	"nfkc": norm.NFKC,
+	"nfkd": norm.NFKD,
+}
+
+type NormalizeTransformer struct {
+	Form norm.Form
+}
+
+func NewNormalizeTransformer(form string) (*NormalizeTransformer, error) {
+	if form == "" {
+		form = "nfc"
+	}
+	f, ok := normForms[form]
+	if !ok {
+		return nil, fmt.Errorf("unknown normalization form %q", form)
+	}
+	return &NormalizeTransformer{Form: f}, nil
+}
+
+func (t *NormalizeTransformer) TransformStream(in io.Reader, out io.Writer) error {
+	_, err := io.Copy(out, transform.NewReader(in, t.Form))
+	return err
+}
@@ -0,0 +1,69 @@
+package transformer
+
+import (
+	"strings"
+	"testing"
+)
+
+type TestText struct {
+	name            string
+	params          Params
+	input, expected string
+}
+
+var TestArrayText = []TestText{
+	TestText{"case", Params{"style": "upper"}, "Straße привіт", "STRASSE ПРИВІТ"},
+	TestText{"case", Params{"style": "lower"}, "HeLLo СВІТ", "hello світ"},
+	TestText{"case", Params{"style": "title"}, "hELLO wide world", "Hello Wide World"},
+	TestText{"case", Params{"style": "snake"}, "HTTPServer error\nuser-id 42", "http_server_error\nuser_id_42"},
+	TestText{"case", Params{"style": "camel"}, "hello big_world\r\n", "helloBigWorld\r\n"},
+	TestText{"case", Params{"style": "kebab"}, "getUserName", "get-user-name"},
+	TestText{"whitespace", Params{}, "  a \t b\n\n c  ", "a b c"},
+	TestText{"whitespace", Params{"action": "trim"}, " \n a  b \n ", "a  b"},
+	TestText{"whitespace", Params{"action": "trim_lines"}, "  a  \r\n\tb c \n", "a\r\nb c\n"},
+	TestText{"whitespace", Params{"action": "trim_lines"}, "a\r\n\r\nb\r\n", "a\r\n\r\nb\r\n"},
+	TestText{"whitespace", Params{"action": "trim_lines"}, " \t\r\n  \r\r\n\n", "\r\n\r\n\n"},
+	TestText{"normalize", Params{}, "e\u0301", "é"},
+	TestText{"normalize", Params{"form": "nfd"}, "é", "e\u0301"},
+	TestText{"normalize", Params{"form": "nfkc"}, "ﬁ ①", "fi 1"},
+	TestText{"normalize", Params{"form": "nfkd"}, "ﬁ é", "fi e\u0301"},
+}
+
+func TestTableText(t *testing.T) {
+
+	for _, test := range TestArrayText {
+
+		tr, err := New(test.name, test.params)
+		if err != nil {
+			t.Fatalf("Error creating %s: %s", test.name, err)
+		}
+		result := new(strings.Builder)
+		err = tr.TransformStream(strings.NewReader(test.input), result)
+		if err != nil {
+			t.Errorf("Error transforming: %s", err)
+		}
+
+		if result.String() != test.expected {
+			t.Errorf("Error: %s result = %q, expected = %q", test.name, result.String(), test.expected)
+		}
+
+	}
+}
+
+func TestTextPipeline(t *testing.T) {
+	p, err := ParsePipeline("whitespace | case:upper | caesar:3")
+	if err != nil {
+		t.Fatalf("Error parsing pipeline: %s", err)
+	}
+	result := new(strings.Builder)
+	err = p.TransformStream(strings.NewReader("  hello   world "), result)
+	if err != nil {
+		t.Fatalf("Error transforming: %s", err)
+	}
+	if result.String() != "KHOOR ZRUOG" {
+		t.Errorf("Error: result = %q, expected = %q", result.String(), "KHOOR ZRUOG")
+	}
+	if _, err := p.Inverse(); err == nil {
+		t.Errorf("Error: expected case conversion not to be invertible")
+	}
+}
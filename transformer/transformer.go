@@ -1,106 +1,410 @@
 package transformer
 
 import (
+	"bufio"
 	"encoding/base64"
+	"errors"
 	"fmt"
 	"io"
+	"strconv"
+	"strings"
 )
 
+const base64ChunkSize = 4 << 10
+
 type Transformer interface {
 	Transform(in io.Reader, ioinput bool) (string, error)
 }
 
-type CaesarTransformer struct {
-	Shift int
+// StreamTransformer reads its input from in and writes the result to out
+// without holding the whole input in memory.
+type StreamTransformer interface {
+	TransformStream(in io.Reader, out io.Writer) error
+}
+
+// Invertible is implemented by transformers whose output can be turned back
+// into their input.
+type Invertible interface {
+	Inverse() (StreamTransformer, error)
+}
+
+// BinaryReader is implemented by transformers that may read binary input,
+// such as decryption, which must reach them unchanged.
+type BinaryReader interface {
+	ReadsBinary() bool
+}
+
+// ReadsBinary reports whether tr, or the first step of a pipeline, reads
+// binary input.
+func ReadsBinary(tr StreamTransformer) bool {
+	if p, ok := tr.(Pipeline); ok && len(p) > 0 {
+		tr = p[0]
+	}
+	b, ok := tr.(BinaryReader)
+	return ok && b.ReadsBinary()
+}
+
+var ErrNotInvertible = errors.New("transformation is not invertible")
+
+func Inverse(t StreamTransformer) (StreamTransformer, error) {
+	inv, ok := t.(Invertible)
+	if !ok {
+		return nil, ErrNotInvertible
+	}
+	return inv.Inverse()
+}
+
+// MalformedInputError reports input that cannot be decoded, pointing at the
+// offending byte.
+type MalformedInputError struct {
+	Format string
+	Offset int64
+	Hint   string
 }
 
-func NewCaesarTransformer(shift int) *CaesarTransformer {
-	return &CaesarTransformer{Shift: shift}
+func (e *MalformedInputError) Error() string {
+	msg := fmt.Sprintf("malformed %s input at byte offset %d", e.Format, e.Offset)
+	if e.Hint != "" {
+		msg += ", " + e.Hint
+	}
+	return msg
 }
 
-func (t *CaesarTransformer) Transform(in io.Reader, ioinput bool) (string, error) {
-	f, err := io.ReadAll(in)
+func transformToString(t StreamTransformer, in io.Reader, ioinput bool) (string, error) {
+	if ioinput {
+		in = newTrimNewlineReader(in)
+	}
+	var sb strings.Builder
+	err := t.TransformStream(in, &sb)
 	if err != nil {
 		return "", err
 	}
-	if ioinput {
-		f = f[:len(f)-1]
+	return sb.String(), nil
+}
+
+var base64Spec = Spec{
+	Name:        "base64",
+	Description: "Base64 encoding",
+	MainParam:   "variant",
+	Params: []Param{
+		{Name: "variant", Type: ParamString, Default: "std", Choices: []string{"std", "url", "rawstd", "rawurl"}, Description: "Alphabet and padding, raw variants are unpadded"},
+	},
+	Invertible: true,
+	New: func(p Params) (StreamTransformer, error) {
+		return NewBase64VariantTransformer(p["variant"], false)
+	},
+}
+
+type Base64Transformer struct {
+	Encoding *base64.Encoding
+	Decode   bool
+}
+
+var Base64Variants = map[string]*base64.Encoding{
+	"std":    base64.StdEncoding,
+	"url":    base64.URLEncoding,
+	"rawstd": base64.RawStdEncoding,
+	"rawurl": base64.RawURLEncoding,
+}
+
+func NewBase64Transformer() *Base64Transformer {
+	return &Base64Transformer{Encoding: base64.StdEncoding}
+}
+
+func NewBase64VariantTransformer(variant string, decode bool) (*Base64Transformer, error) {
+	if variant == "" {
+		variant = "std"
 	}
-	rns := []rune(string(f))
-	for i := 0; i < len(rns); i++ {
-		r := int(rns[i]) + t.Shift
-		switch {
-		case r > 'z':
-			rns[i] = rune(r - 26)
-		case r < 'a':
-			rns[i] = rune(r + 26)
-		default:
-			rns[i] = rune(r)
-		}
+	enc, ok := Base64Variants[variant]
+	if !ok {
+		return nil, fmt.Errorf("unknown base64 variant %q", variant)
 	}
-	result := string(rns)
+	return &Base64Transformer{Encoding: enc, Decode: decode}, nil
+}
+
+func (t *Base64Transformer) Transform(in io.Reader, ioinput bool) (string, error) {
+	return transformToString(t, in, ioinput)
+}
 
-	return result, nil
+func (t *Base64Transformer) TransformStream(in io.Reader, out io.Writer) error {
+	if t.Decode {
+		return t.decodeStream(in, out)
+	}
+	enc := base64.NewEncoder(t.encoding(), out)
+	_, err := io.Copy(enc, in)
+	if err != nil {
+		return err
+	}
+	return enc.Close()
 }
 
-type ReverseTransformer struct{}
+func (t *Base64Transformer) Inverse() (StreamTransformer, error) {
+	return &Base64Transformer{Encoding: t.Encoding, Decode: !t.Decode}, nil
+}
 
-func NewReverseTransformer() *ReverseTransformer {
-	return &ReverseTransformer{}
+func (t *Base64Transformer) encoding() *base64.Encoding {
+	if t.Encoding == nil {
+		return base64.StdEncoding
+	}
+	return t.Encoding
 }
 
-func (t *ReverseTransformer) Transform(in io.Reader, ioinput bool) (string, error) {
-	f, err := io.ReadAll(in)
+// decodeStream decodes the input in chunks of whole base64 groups. Line breaks
+// are skipped, and the offset of every kept byte is remembered so that an
+// error can point at the offending byte of the original input.
+func (t *Base64Transformer) decodeStream(in io.Reader, out io.Writer) error {
+	enc := t.encoding()
+	br := bufio.NewReader(in)
+	chunk := make([]byte, 0, base64ChunkSize)
+	offsets := make([]int64, 0, base64ChunkSize)
+	dst := make([]byte, enc.DecodedLen(base64ChunkSize)+3)
+	var pos int64
+	padded := false
+
+	flush := func() error {
+		if len(chunk) == 0 {
+			return nil
+		}
+		if padded {
+			return &MalformedInputError{Format: "base64", Offset: offsets[0]}
+		}
+		n, err := enc.Decode(dst, chunk)
+		if _, werr := out.Write(dst[:n]); werr != nil {
+			return werr
+		}
+		var corrupt base64.CorruptInputError
+		if errors.As(err, &corrupt) {
+			offset := pos
+			if int(corrupt) < len(offsets) {
+				offset = offsets[corrupt]
+			}
+			return &MalformedInputError{Format: "base64", Offset: offset}
+		}
+		if err != nil {
+			return err
+		}
+		padded = chunk[len(chunk)-1] == '='
+		chunk, offsets = chunk[:0], offsets[:0]
+		return nil
+	}
+
+	for {
+		b, err := br.ReadByte()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return err
+		}
+		pos++
+		if b == '\r' || b == '\n' {
+			continue
+		}
+		chunk = append(chunk, b)
+		offsets = append(offsets, pos-1)
+		if len(chunk) == base64ChunkSize {
+			err = flush()
+			if err != nil {
+				return err
+			}
+		}
+	}
+	return flush()
+}
+
+type Options struct {
+	Pipeline      string
+	Codec         string
+	Compress      string
+	Encrypt       bool
+	Hash          string
+	CaesarShift   int
+	CaesarOptions CaesarOptions
+	Vigenere      string
+	Atbash        bool
+	Substitution  string
+	RailFence     int
+	Columnar      string
+	Playfair      string
+	Xor           string
+	Case          string
+	Whitespace    string
+	Normalize     string
+	Base64        bool
+	Base64Decode  bool
+	Base64Variant string
+	ReverseUnit   string
+	Mode          string
+	// Lines transforms every line on its own, Field only the given field,
+	// counted from 1, of every line split at Delimiter.
+	Lines     bool
+	Field     int
+	Delimiter string
+	// Select transforms only the values picked by a selector such as
+	// $.user.name in a document of the given Format, json or yaml.
+	Select string
+	Format string
+}
+
+// NewTransformer builds the transformation selected by opts. Selecting more
+// than one is an error, several transformations are combined with a pipeline.
+func NewTransformer(opts Options) (StreamTransformer, error) {
+	tr, err := newTransformer(opts)
 	if err != nil {
-		return "", err
+		return nil, err
 	}
-	if ioinput {
-		f = f[:len(f)-1]
+	if opts.Select != "" {
+		if opts.Lines || opts.Field != 0 {
+			return nil, errors.New("a selector cannot be combined with line or field mode")
+		}
+		return Select(tr, opts.Select, opts.Format)
+	}
+	if opts.Field != 0 {
+		delimiter, err := ParseDelimiter(opts.Delimiter)
+		if err != nil {
+			return nil, err
+		}
+		return Field(tr, opts.Field, delimiter)
 	}
-	rns := []rune(string(f))
-	for i, j := 0, len(rns)-1; i < j; i, j = i+1, j-1 {
-		rns[i], rns[j] = rns[j], rns[i]
+	if opts.Lines {
+		return Lines(tr), nil
 	}
-	result := string(rns)
-	return result, nil
+	return tr, nil
 }
 
-type Base64Transformer struct{}
+func newTransformer(opts Options) (StreamTransformer, error) {
+	selected := 0
+	for _, set := range []bool{opts.Pipeline != "", opts.Codec != "", opts.Compress != "", opts.Encrypt, opts.Hash != "", opts.Base64, opts.CaesarShift != 0, opts.Vigenere != "", opts.Atbash, opts.Substitution != "",
+		opts.RailFence != 0, opts.Columnar != "", opts.Playfair != "", opts.Xor != "",
+		opts.Case != "", opts.Whitespace != "", opts.Normalize != ""} {
+		if set {
+			selected++
+		}
+	}
+	if selected > 1 {
+		return nil, errors.New("several transformations selected, combine them with a pipeline instead")
+	}
 
-func NewBase64Transformer() *Base64Transformer {
-	return &Base64Transformer{}
+	if opts.Codec != "" {
+		return newStepOf("codec", opts.Codec, Codecs)
+	}
+	if opts.Compress != "" {
+		return newStepOf("compression", opts.Compress, Compressions)
+	}
+	if opts.Hash != "" {
+		return NewHash(opts.Hash)
+	}
+	name, p := opts.step()
+	if name == "" {
+		steps, err := ParseSteps(opts.Pipeline)
+		if err != nil {
+			return nil, err
+		}
+		for i := range steps {
+			steps[i].Params = withMode(steps[i].Name, steps[i].Params, opts.Mode)
+		}
+		return NewPipeline(steps)
+	}
+	return New(name, withMode(name, p, opts.Mode))
 }
 
-func (t *Base64Transformer) Transform(in io.Reader, ioinput bool) (string, error) {
-	f, err := io.ReadAll(in)
+// step maps opts to a registered transformer, name is empty for a pipeline.
+func (opts Options) step() (string, Params) {
+	switch {
+	case opts.Pipeline != "":
+		return "", nil
+	case opts.Encrypt:
+		return "aes", Params{}
+	case opts.Base64:
+		return "base64", Params{"variant": opts.Base64Variant, "decode": strconv.FormatBool(opts.Base64Decode)}
+	case opts.CaesarShift != 0:
+		return "caesar", Params{
+			"shift":         strconv.Itoa(opts.CaesarShift),
+			"alphabet":      opts.CaesarOptions.Alphabet,
+			"rotate_digits": strconv.FormatBool(opts.CaesarOptions.RotateDigits),
+		}
+	case opts.Vigenere != "":
+		return "vigenere", Params{"key": opts.Vigenere, "alphabet": opts.CaesarOptions.Alphabet}
+	case opts.Atbash:
+		return "atbash", Params{"alphabet": opts.CaesarOptions.Alphabet}
+	case opts.Substitution != "":
+		return "substitution", Params{"key": opts.Substitution, "alphabet": opts.CaesarOptions.Alphabet}
+	case opts.RailFence != 0:
+		return "railfence", Params{"rails": strconv.Itoa(opts.RailFence)}
+	case opts.Columnar != "":
+		return "columnar", Params{"key": opts.Columnar}
+	case opts.Playfair != "":
+		return "playfair", Params{"key": opts.Playfair}
+	case opts.Xor != "":
+		return "xor", Params{"key": opts.Xor}
+	case opts.Case != "":
+		return "case", Params{"style": opts.Case}
+	case opts.Whitespace != "":
+		return "whitespace", Params{"action": opts.Whitespace}
+	case opts.Normalize != "":
+		return "normalize", Params{"form": opts.Normalize}
+	default:
+		return "reverse", Params{"unit": opts.ReverseUnit}
+	}
+}
+
+// newStepOf builds a step such as "hex:upper" whose transformer must be
+// one of names.
+func newStepOf(kind, spec string, names []string) (StreamTransformer, error) {
+	step, err := ParseStep(spec)
 	if err != nil {
-		return "", err
+		return nil, err
 	}
-	if ioinput {
-		f = f[:len(f)-1]
+	for _, name := range names {
+		if step.Name == name {
+			return NewStep(step)
+		}
 	}
-	result := base64.StdEncoding.EncodeToString(f)
-	return result, nil
+	return nil, fmt.Errorf("unknown %s %q, expected one of %s", kind, step.Name, strings.Join(names, "/"))
+}
+
+// NewHash builds one of the Hashes from a step such as "sha256:base64".
+func NewHash(spec string) (*HashTransformer, error) {
+	tr, err := newStepOf("hash", spec, Hashes)
+	if err != nil {
+		return nil, err
+	}
+	return tr.(*HashTransformer), nil
+}
+
+// withMode sets the input mode of a text transformer unless the step
+// already chose one. Binary transformers have no mode and are left alone.
+func withMode(name string, p Params, mode string) Params {
+	spec, ok := Lookup(name)
+	if mode == "" || !ok || !spec.Text || p["mode"] != "" {
+		return p
+	}
+	out := Params{"mode": mode}
+	for k, v := range p {
+		out[k] = v
+	}
+	return out
 }
 
 func BasicTransform(in io.Reader, out io.Writer, caesaarShift int, base64Use bool, ioinput bool) error {
-	var tr Transformer
-	switch {
-	case base64Use:
-		tr = NewBase64Transformer()
-	case caesaarShift != 0:
-		tr = NewCaesarTransformer(caesaarShift)
-	default:
-		tr = NewReverseTransformer()
+	opts := Options{CaesarShift: caesaarShift}
+	if base64Use {
+		opts = Options{Base64: true}
 	}
-	result, err := tr.Transform(in, ioinput)
+	tr, err := NewTransformer(opts)
 	if err != nil {
-		return fmt.Errorf("TRANSFORMER error: %w", err)
+		return err
 	}
+	return RunTransform(in, out, tr, ioinput)
+}
 
-	_, err = out.Write([]byte(result))
+func RunTransform(in io.Reader, out io.Writer, tr StreamTransformer, ioinput bool) error {
+	if ioinput && !ReadsBinary(tr) {
+		in = newTrimNewlineReader(in)
+	}
+	err := tr.TransformStream(in, out)
 	if err != nil {
-		return fmt.Errorf("write output error: %w", err)
+		return fmt.Errorf("TRANSFORMER error: %w", err)
 	}
 	return nil
 }
@@ -0,0 +1,176 @@
+package transformer
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"sort"
+	"unicode"
+)
+
+var railFenceSpec = Spec{
+	Name:        "railfence",
+	Description: "Rail fence transposition, writes the text in a zigzag over the rails",
+	MainParam:   "rails",
+	Params: []Param{
+		{Name: "rails", Type: ParamInt, Required: true, Description: "Number of rails, at least 2"},
+	},
+	Text:       true,
+	Invertible: true,
+	New: func(p Params) (StreamTransformer, error) {
+		rails, _ := p.Int("rails")
+		return NewRailFenceTransformer(rails)
+	},
+}
+
+// RailFenceTransformer moves every character, spaces and punctuation
+// included, so the whole input is held in memory.
+type RailFenceTransformer struct {
+	Rails   int
+	Decrypt bool
+}
+
+func NewRailFenceTransformer(rails int) (*RailFenceTransformer, error) {
+	if rails < 2 {
+		return nil, fmt.Errorf("expected at least 2 rails, got %d", rails)
+	}
+	return &RailFenceTransformer{Rails: rails}, nil
+}
+
+func (t *RailFenceTransformer) TransformStream(in io.Reader, out io.Writer) error {
+	if t.Rails < 2 {
+		return fmt.Errorf("expected at least 2 rails, got %d", t.Rails)
+	}
+	text, err := readRunes(in)
+	if err != nil {
+		return err
+	}
+	// rails beyond the length of the text stay empty, and a text no longer
+	// than the rails keeps its order
+	rails := t.Rails
+	if rails > len(text) {
+		rails = len(text)
+	}
+	if rails < 2 {
+		return writeTransposed(out, text, identityOrder(len(text)), t.Decrypt)
+	}
+	// order lists the positions of the plain text rail by rail, it is
+	// sorted by counting the positions on every rail first
+	cycle := 2 * (rails - 1)
+	railOf := func(i int) int {
+		pos := i % cycle
+		if pos >= rails {
+			return cycle - pos
+		}
+		return pos
+	}
+	start := make([]int, rails+1)
+	for i := range text {
+		start[railOf(i)+1]++
+	}
+	for rail := 1; rail <= rails; rail++ {
+		start[rail] += start[rail-1]
+	}
+	order := make([]int, len(text))
+	for i := range text {
+		rail := railOf(i)
+		order[start[rail]] = i
+		start[rail]++
+	}
+	return writeTransposed(out, text, order, t.Decrypt)
+}
+
+func (t *RailFenceTransformer) Inverse() (StreamTransformer, error) {
+	return &RailFenceTransformer{Rails: t.Rails, Decrypt: !t.Decrypt}, nil
+}
+
+var columnarSpec = Spec{
+	Name:        "columnar",
+	Description: "Columnar transposition, reads the columns in the alphabetical order of the key letters",
+	MainParam:   "key",
+	Params: []Param{
+		{Name: "key", Type: ParamString, Required: true, Description: "Keyword, one column per letter"},
+	},
+	Text:       true,
+	Invertible: true,
+	New: func(p Params) (StreamTransformer, error) {
+		return NewColumnarTransformer(p["key"])
+	},
+}
+
+// ColumnarTransformer writes the text in rows as wide as the key and reads
+// it column by column. The last row is not padded, so decryption recovers
+// the text exactly. The whole input is held in memory.
+type ColumnarTransformer struct {
+	// Columns is the order in which the columns are read.
+	Columns []int
+	Decrypt bool
+}
+
+func NewColumnarTransformer(key string) (*ColumnarTransformer, error) {
+	letters := []rune(key)
+	if len(letters) == 0 {
+		return nil, errors.New("expected columnar key")
+	}
+	columns := make([]int, len(letters))
+	for i := range columns {
+		columns[i] = i
+	}
+	sort.SliceStable(columns, func(i, j int) bool {
+		return unicode.ToLower(letters[columns[i]]) < unicode.ToLower(letters[columns[j]])
+	})
+	return &ColumnarTransformer{Columns: columns}, nil
+}
+
+func (t *ColumnarTransformer) TransformStream(in io.Reader, out io.Writer) error {
+	if len(t.Columns) == 0 {
+		return errors.New("expected columnar key")
+	}
+	text, err := readRunes(in)
+	if err != nil {
+		return err
+	}
+	width := len(t.Columns)
+	order := make([]int, 0, len(text))
+	for _, column := range t.Columns {
+		for i := column; i < len(text); i += width {
+			order = append(order, i)
+		}
+	}
+	return writeTransposed(out, text, order, t.Decrypt)
+}
+
+func (t *ColumnarTransformer) Inverse() (StreamTransformer, error) {
+	return &ColumnarTransformer{Columns: t.Columns, Decrypt: !t.Decrypt}, nil
+}
+
+func identityOrder(n int) []int {
+	order := make([]int, n)
+	for i := range order {
+		order[i] = i
+	}
+	return order
+}
+
+func readRunes(in io.Reader) ([]rune, error) {
+	data, err := io.ReadAll(in)
+	if err != nil {
+		return nil, err
+	}
+	return []rune(string(data)), nil
+}
+
+// writeTransposed writes text in the given order of positions, or puts the
+// characters back at those positions when decrypting.
+func writeTransposed(out io.Writer, text []rune, order []int, decrypt bool) error {
+	result := make([]rune, len(text))
+	for i, pos := range order {
+		if decrypt {
+			result[pos] = text[i]
+		} else {
+			result[i] = text[pos]
+		}
+	}
+	_, err := io.WriteString(out, string(result))
+	return err
+}
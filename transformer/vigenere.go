@@ -0,0 +1,102 @@
+package transformer
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"unicode"
+)
+
+var vigenereSpec = Spec{
+	Name:        "vigenere",
+	Description: "Vigenere cipher with a keyword",
+	MainParam:   "key",
+	Params: []Param{
+		{Name: "key", Type: ParamString, Required: true, Description: "Keyword made of alphabet letters"},
+		alphabetParam,
+	},
+	Text:       true,
+	Invertible: true,
+	New: func(p Params) (StreamTransformer, error) {
+		return NewVigenereTransformer(p["key"], p["alphabet"])
+	},
+}
+
+// VigenereTransformer shifts every letter of the alphabet by the position of
+// the next keyword letter. Characters outside the alphabet are copied as they
+// are and do not advance the keyword.
+type VigenereTransformer struct {
+	Key      []int
+	Alphabet []rune
+	Decrypt  bool
+}
+
+func NewVigenereTransformer(key string, alphabet string) (*VigenereTransformer, error) {
+	letters, err := CaesarAlphabet(alphabet)
+	if err != nil {
+		return nil, err
+	}
+	index := alphabetIndex(letters)
+	shifts := make([]int, 0, len(key))
+	for _, r := range key {
+		i, ok := index[unicode.ToLower(r)]
+		if !ok {
+			return nil, fmt.Errorf("vigenere key letter %q is not in the alphabet", r)
+		}
+		shifts = append(shifts, i)
+	}
+	if len(shifts) == 0 {
+		return nil, fmt.Errorf("expected vigenere key")
+	}
+	return &VigenereTransformer{Key: shifts, Alphabet: letters}, nil
+}
+
+func (t *VigenereTransformer) TransformStream(in io.Reader, out io.Writer) error {
+	if len(t.Key) == 0 {
+		return fmt.Errorf("expected vigenere key")
+	}
+	alphabet := orDefaultAlphabet(t.Alphabet)
+	index := alphabetIndex(alphabet)
+	n := len(alphabet)
+	br := bufio.NewReader(in)
+	bw := bufio.NewWriter(out)
+	for k := 0; ; {
+		rn, _, err := br.ReadRune()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return err
+		}
+		lower := unicode.ToLower(rn)
+		if i, ok := index[lower]; ok {
+			shift := t.Key[k%len(t.Key)]
+			if t.Decrypt {
+				shift = -shift
+			}
+			shifted := alphabet[mod(i+shift, n)]
+			if lower != rn {
+				shifted = unicode.ToUpper(shifted)
+			}
+			rn = shifted
+			k++
+		}
+		_, err = bw.WriteRune(rn)
+		if err != nil {
+			return err
+		}
+	}
+	return bw.Flush()
+}
+
+func (t *VigenereTransformer) Inverse() (StreamTransformer, error) {
+	return &VigenereTransformer{Key: t.Key, Alphabet: t.Alphabet, Decrypt: !t.Decrypt}, nil
+}
+
+func alphabetIndex(alphabet []rune) map[rune]int {
+	index := make(map[rune]int, len(alphabet))
+	for i, r := range alphabet {
+		index[r] = i
+	}
+	return index
+}
@@ -0,0 +1,50 @@
+package transformer
+
+import (
+	"strings"
+	"testing"
+)
+
+type TestVigenere struct {
+	input, expected, key, alphabet string
+	decrypt                        bool
+}
+
+var TestArrayVigenere = []TestVigenere{
+	TestVigenere{"attackatdawn", "lxfopvefrnhr", "lemon", "", false},
+	TestVigenere{"lxfopvefrnhr", "attackatdawn", "lemon", "", true},
+	TestVigenere{"Attack at Dawn!", "Lxfopv ef Rnhr!", "LEMON", "", false},
+	TestVigenere{"Lxfopv ef Rnhr!", "Attack at Dawn!", "lemon", "", true},
+	TestVigenere{"абв", "бвг", "б", "ukrainian", false},
+}
+
+func TestTableVigenere(t *testing.T) {
+
+	for _, test := range TestArrayVigenere {
+
+		tr, err := NewVigenereTransformer(test.key, test.alphabet)
+		if err != nil {
+			t.Fatalf("Error creating transformer: %s", err)
+		}
+		tr.Decrypt = test.decrypt
+		result := new(strings.Builder)
+		err = tr.TransformStream(strings.NewReader(test.input), result)
+		if err != nil {
+			t.Errorf("Error transforming: %s", err)
+		}
+
+		if result.String() != test.expected {
+			t.Errorf("Error: result = %q, expected = %q", result.String(), test.expected)
+		}
+
+	}
+}
+
+func TestVigenereInvalidKey(t *testing.T) {
+	for _, key := range []string{"", "key1"} {
+		_, err := NewVigenereTransformer(key, "")
+		if err == nil {
+			t.Errorf("Error: expected error for key %q", key)
+		}
+	}
+}
@@ -0,0 +1,78 @@
+package transformer
+
+import (
+	"errors"
+	"io"
+)
+
+var xorSpec = Spec{
+	Name:        "xor",
+	Description: "Repeating-key XOR with hex output",
+	MainParam:   "key",
+	Params: []Param{
+		{Name: "key", Type: ParamString, Required: true, Description: "Key whose bytes are repeated over the input"},
+	},
+	Invertible: true,
+	New: func(p Params) (StreamTransformer, error) {
+		return NewXorTransformer(p["key"])
+	},
+}
+
+// XorTransformer XORs the input with the repeated key and writes the result
+// as lower-case hex. Decryption reads that hex.
+type XorTransformer struct {
+	Key     []byte
+	Decrypt bool
+}
+
+func NewXorTransformer(key string) (*XorTransformer, error) {
+	if key == "" {
+		return nil, errors.New("expected xor key")
+	}
+	return &XorTransformer{Key: []byte(key)}, nil
+}
+
+func (t *XorTransformer) TransformStream(in io.Reader, out io.Writer) error {
+	if len(t.Key) == 0 {
+		return errors.New("expected xor key")
+	}
+	if t.Decrypt {
+		return (&HexTransformer{Decode: true}).TransformStream(in, &xorWriter{w: out, key: t.Key})
+	}
+	return (&HexTransformer{}).TransformStream(&xorReader{r: in, key: t.Key}, out)
+}
+
+func (t *XorTransformer) Inverse() (StreamTransformer, error) {
+	return &XorTransformer{Key: t.Key, Decrypt: !t.Decrypt}, nil
+}
+
+type xorReader struct {
+	r   io.Reader
+	key []byte
+	pos int
+}
+
+func (r *xorReader) Read(p []byte) (int, error) {
+	n, err := r.r.Read(p)
+	for i := range p[:n] {
+		p[i] ^= r.key[r.pos%len(r.key)]
+		r.pos++
+	}
+	return n, err
+}
+
+type xorWriter struct {
+	w   io.Writer
+	key []byte
+	pos int
+}
+
+func (w *xorWriter) Write(p []byte) (int, error) {
+	buf := make([]byte, len(p))
+	for i, b := range p {
+		buf[i] = b ^ w.key[(w.pos+i)%len(w.key)]
+	}
+	n, err := w.w.Write(buf)
+	w.pos += n
+	return n, err
+}
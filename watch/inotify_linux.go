@@ -0,0 +1,140 @@
+package watch
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+	"syscall"
+	"unsafe"
+)
+
+const inotifyMask = syscall.IN_CREATE | syscall.IN_MODIFY | syscall.IN_CLOSE_WRITE | syscall.IN_ATTRIB |
+	syscall.IN_DELETE | syscall.IN_MOVED_FROM | syscall.IN_MOVED_TO | syscall.IN_DELETE_SELF
+
+type inotify struct {
+	file *os.File
+	fd   int
+	root string
+	// dirs are the watched directories by watch descriptor
+	dirs map[int32]string
+	// single is set when root is a file, whose directory is watched so
+	// that editors replacing the file are seen
+	single bool
+}
+
+func startInotify(root string, w *Watcher) (func() error, error) {
+	fd, err := syscall.InotifyInit1(syscall.IN_CLOEXEC | syscall.IN_NONBLOCK)
+	if err != nil {
+		return nil, fmt.Errorf("inotify: %w", err)
+	}
+	n := &inotify{fd: fd, root: root, dirs: map[int32]string{}}
+	// a non-blocking descriptor is read through the runtime poller, so
+	// closing the file ends a pending read
+	n.file = os.NewFile(uintptr(fd), "inotify")
+
+	info, err := os.Stat(root)
+	if err == nil && !info.IsDir() {
+		n.single = true
+		err = n.add(filepath.Dir(root))
+	} else if err == nil {
+		err = n.addTree(root, nil)
+	}
+	if err != nil {
+		n.file.Close()
+		return nil, err
+	}
+	go n.read(w)
+	return n.file.Close, nil
+}
+
+func (n *inotify) add(dir string) error {
+	wd, err := syscall.InotifyAddWatch(n.fd, dir, inotifyMask)
+	if err != nil {
+		return &os.PathError{Op: "inotify_add_watch", Path: dir, Err: err}
+	}
+	n.dirs[int32(wd)] = dir
+	return nil
+}
+
+// addTree watches dir and its subdirectories. The files found are sent when
+// w is set, they may have been written before the watch was added.
+func (n *inotify) addTree(dir string, w *Watcher) error {
+	return filepath.WalkDir(dir, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			// removed in the meantime
+			if errors.Is(err, fs.ErrNotExist) {
+				return nil
+			}
+			return err
+		}
+		if d.IsDir() {
+			return n.add(path)
+		}
+		if w != nil && !w.send(path) {
+			return errClosed
+		}
+		return nil
+	})
+}
+
+func (n *inotify) read(w *Watcher) {
+	buf := make([]byte, 64*(syscall.SizeofInotifyEvent+syscall.NAME_MAX+1))
+	for {
+		size, err := n.file.Read(buf)
+		if err != nil {
+			if !errors.Is(err, os.ErrClosed) {
+				w.fail(fmt.Errorf("inotify: %w", err))
+			}
+			return
+		}
+		for offset := 0; offset+syscall.SizeofInotifyEvent <= size; {
+			event := (*syscall.InotifyEvent)(unsafe.Pointer(&buf[offset]))
+			nameStart := offset + syscall.SizeofInotifyEvent
+			offset = nameStart + int(event.Len)
+			name := strings.TrimRight(string(buf[nameStart:offset]), "\x00")
+			if !n.handle(w, event, name) {
+				return
+			}
+		}
+	}
+}
+
+// handle sends the path of an event, it returns false once the watcher is
+// closed.
+func (n *inotify) handle(w *Watcher, event *syscall.InotifyEvent, name string) bool {
+	if event.Mask&syscall.IN_Q_OVERFLOW != 0 {
+		// events were lost, everything may have changed
+		return w.send(n.root)
+	}
+	dir, ok := n.dirs[event.Wd]
+	if !ok {
+		return true
+	}
+	if event.Mask&syscall.IN_IGNORED != 0 {
+		delete(n.dirs, event.Wd)
+		return true
+	}
+	path := dir
+	if name != "" {
+		path = filepath.Join(dir, name)
+	}
+	if n.single {
+		if path != n.root {
+			return true
+		}
+		return w.send(path)
+	}
+	if event.Mask&syscall.IN_ISDIR != 0 && event.Mask&(syscall.IN_CREATE|syscall.IN_MOVED_TO) != 0 {
+		err := n.addTree(path, w)
+		if errors.Is(err, errClosed) {
+			return false
+		}
+		if err != nil {
+			w.fail(err)
+		}
+	}
+	return w.send(path)
+}
@@ -0,0 +1,7 @@
+//go:build !linux
+
+package watch
+
+func startInotify(root string, w *Watcher) (func() error, error) {
+	return nil, errUnsupported
+}
@@ -0,0 +1,69 @@
+package watch
+
+import (
+	"io/fs"
+	"path/filepath"
+	"time"
+)
+
+type fileState struct {
+	modTime time.Time
+	size    int64
+	mode    fs.FileMode
+}
+
+// startPoll compares the state of the files below root every interval.
+func startPoll(root string, interval time.Duration, w *Watcher) (func() error, error) {
+	last := snapshot(root)
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-w.done:
+				return
+			case <-ticker.C:
+			}
+			next := snapshot(root)
+			for path, state := range next {
+				if old, ok := last[path]; !ok || old != state {
+					if !w.send(path) {
+						return
+					}
+				}
+			}
+			for path := range last {
+				if _, ok := next[path]; !ok {
+					if !w.send(path) {
+						return
+					}
+				}
+			}
+			last = next
+		}
+	}()
+	return func() error { return nil }, nil
+}
+
+// snapshot records root and every file below it, a missing root is empty.
+func snapshot(root string) map[string]fileState {
+	states := map[string]fileState{}
+	filepath.WalkDir(root, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return nil
+		}
+		info, err := d.Info()
+		if err != nil {
+			return nil
+		}
+		state := fileState{mode: info.Mode()}
+		// the times of directories change with their entries, which are
+		// reported themselves
+		if !d.IsDir() {
+			state.modTime, state.size = info.ModTime(), info.Size()
+		}
+		states[path] = state
+		return nil
+	})
+	return states
+}
@@ -0,0 +1,161 @@
+// Package watch reports the files below a path that changed, with inotify on
+// Linux and by polling elsewhere.
+package watch
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+	"time"
+)
+
+const (
+	DefaultDebounce = 200 * time.Millisecond
+	DefaultInterval = 500 * time.Millisecond
+)
+
+var errUnsupported = errors.New("inotify is not supported on this system")
+
+var errClosed = errors.New("watcher closed")
+
+// Backends of a Watcher.
+const (
+	BackendInotify = "inotify"
+	BackendPoll    = "poll"
+)
+
+type Options struct {
+	// Debounce is how long the path has to stay unchanged before the changes
+	// are reported, so a burst of writes is reported once.
+	Debounce time.Duration
+	// Interval is how often polling looks for changes.
+	Interval time.Duration
+	// Poll skips inotify, which does not see changes made over some network
+	// file systems.
+	Poll bool
+	// Ignore drops the changes of some paths, such as outputs written next
+	// to the watched files.
+	Ignore func(path string) bool
+}
+
+// Watcher watches a file, or a directory with its subdirectories.
+type Watcher struct {
+	// Backend is BackendInotify or BackendPoll.
+	Backend string
+
+	changes chan []string
+	errors  chan error
+	raw     chan string
+	done    chan struct{}
+	once    sync.Once
+	stop    func() error
+}
+
+// New starts watching path, which has to exist. It falls back to polling when
+// inotify is not available or out of watches.
+func New(path string, opts Options) (*Watcher, error) {
+	if opts.Debounce <= 0 {
+		opts.Debounce = DefaultDebounce
+	}
+	if opts.Interval <= 0 {
+		opts.Interval = DefaultInterval
+	}
+	path = filepath.Clean(path)
+	if _, err := os.Stat(path); err != nil {
+		return nil, err
+	}
+	w := &Watcher{
+		changes: make(chan []string),
+		errors:  make(chan error, 1),
+		raw:     make(chan string, 64),
+		done:    make(chan struct{}),
+	}
+	var err error
+	if !opts.Poll {
+		w.stop, err = startInotify(path, w)
+		w.Backend = BackendInotify
+	}
+	if opts.Poll || err != nil {
+		w.stop, err = startPoll(path, opts.Interval, w)
+		w.Backend = BackendPoll
+	}
+	if err != nil {
+		return nil, err
+	}
+	go w.debounce(opts)
+	return w, nil
+}
+
+// Changes receives the paths that changed, created and removed files
+// included, sorted and without duplicates. A directory stands for everything
+// below it.
+func (w *Watcher) Changes() <-chan []string {
+	return w.changes
+}
+
+// Errors receives the errors of the backend, the watcher keeps running.
+func (w *Watcher) Errors() <-chan error {
+	return w.errors
+}
+
+func (w *Watcher) Close() error {
+	var err error
+	w.once.Do(func() {
+		close(w.done)
+		err = w.stop()
+	})
+	return err
+}
+
+// send passes a changed path from the backend, it returns false once the
+// watcher is closed.
+func (w *Watcher) send(path string) bool {
+	select {
+	case w.raw <- path:
+		return true
+	case <-w.done:
+		return false
+	}
+}
+
+// fail reports an error of the backend, dropping it when the previous one
+// has not been received yet.
+func (w *Watcher) fail(err error) {
+	select {
+	case w.errors <- err:
+	default:
+	}
+}
+
+func (w *Watcher) debounce(opts Options) {
+	pending := map[string]bool{}
+	timer := time.NewTimer(opts.Debounce)
+	timer.Stop()
+	for {
+		select {
+		case <-w.done:
+			timer.Stop()
+			return
+		case path := <-w.raw:
+			if opts.Ignore != nil && opts.Ignore(path) {
+				continue
+			}
+			pending[path] = true
+			timer.Reset(opts.Debounce)
+		case <-timer.C:
+			changed := make([]string, 0, len(pending))
+			for path := range pending {
+				changed = append(changed, path)
+			}
+			sort.Strings(changed)
+			pending = map[string]bool{}
+			select {
+			case w.changes <- changed:
+			case <-w.done:
+				return
+			}
+		}
+	}
+}
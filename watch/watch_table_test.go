@@ -0,0 +1,90 @@
+package watch
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+type TestWatch struct {
+	name string
+	poll bool
+	// watchFile watches a.txt instead of the directory
+	watchFile bool
+	writes    []string
+	expected  []string
+}
+
+var TestArrayWatch = []TestWatch{
+	TestWatch{"inotify directory", false, false, []string{"a.txt", "sub/b.txt", "c.out"}, []string{"a.txt", "sub/b.txt"}},
+	TestWatch{"poll directory", true, false, []string{"a.txt", "sub/b.txt", "c.out"}, []string{"a.txt", "sub/b.txt"}},
+	TestWatch{"inotify file", false, true, []string{"d.txt", "a.txt"}, []string{"a.txt"}},
+	TestWatch{"poll file", true, true, []string{"d.txt", "a.txt"}, []string{"a.txt"}},
+}
+
+func TestTableWatch(t *testing.T) {
+
+	for _, test := range TestArrayWatch {
+
+		dir := t.TempDir()
+		err := os.MkdirAll(filepath.Join(dir, "sub"), 0o755)
+		if err != nil {
+			t.Fatalf("Error creating the tree: %s", err)
+		}
+		err = os.WriteFile(filepath.Join(dir, "a.txt"), []byte("old"), 0o644)
+		if err != nil {
+			t.Fatalf("Error creating the tree: %s", err)
+		}
+		root := dir
+		if test.watchFile {
+			root = filepath.Join(dir, "a.txt")
+		}
+		w, err := New(root, Options{
+			Debounce: 50 * time.Millisecond,
+			Interval: 20 * time.Millisecond,
+			Poll:     test.poll,
+			Ignore:   func(path string) bool { return strings.HasSuffix(path, ".out") },
+		})
+		if err != nil {
+			t.Fatalf("Error in %s: %s", test.name, err)
+		}
+		expectedBackend := BackendInotify
+		if test.poll {
+			expectedBackend = BackendPoll
+		}
+		if w.Backend != expectedBackend {
+			t.Errorf("Error in %s: backend %s, expected %s", test.name, w.Backend, expectedBackend)
+		}
+
+		for _, name := range test.writes {
+			err = os.WriteFile(filepath.Join(dir, name), []byte("new content"), 0o644)
+			if err != nil {
+				t.Fatalf("Error writing %s: %s", name, err)
+			}
+		}
+
+		var changed []string
+		select {
+		case changed = <-w.Changes():
+		case <-time.After(5 * time.Second):
+			t.Errorf("Error in %s: no changes reported", test.name)
+		}
+		// the directory itself may be reported along with its files
+		var files []string
+		for _, path := range changed {
+			if path != filepath.Join(dir, "sub") {
+				rel, _ := filepath.Rel(dir, path)
+				files = append(files, filepath.ToSlash(rel))
+			}
+		}
+		if strings.Join(files, " ") != strings.Join(test.expected, " ") {
+			t.Errorf("Error in %s: changed %q, expected %q", test.name, files, test.expected)
+		}
+		err = w.Close()
+		if err != nil {
+			t.Errorf("Error closing %s: %s", test.name, err)
+		}
+	}
+}